@@ -0,0 +1,234 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// errCheckFailed is returned by the transaction of an atomic operation
+// to roll it back when one of its checks fails.
+var errCheckFailed = errors.New("atomic operation check failed")
+
+// AtomicOperation is a set of checks and mutations of the store's keys,
+// built with KV.Atomic, which are committed all at once, or not at all.
+//
+// Checks compare the current version of keys with the expected one, so that
+// the operation is only committed if none of them changed in the meantime.
+type AtomicOperation struct {
+	kv *KV
+
+	checks    []atomicCheck
+	mutations []mutation
+
+	// ttls holds the TTL of each of the mutations, if any.
+	ttls []time.Duration
+
+	// err is the first error met while building the operation, which
+	// rejects its commit.
+	err error
+}
+
+// atomicCheck is a check of the version of a key.
+type atomicCheck struct {
+	key []byte
+
+	// version is the expected version of the key,
+	// or nil if the key is expected not to exist.
+	version *uint64
+}
+
+// AtomicResult is the result of committing an AtomicOperation.
+type AtomicResult struct {
+	// OK is true when the operation was committed, and false
+	// when one of its checks failed.
+	OK bool `json:"ok" js:"ok"`
+
+	// Version is the version of the keys set by the operation, if any.
+	Version *uint64 `json:"version" js:"version"`
+}
+
+// Atomic returns a new, empty, atomic operation.
+func (k *KV) Atomic() *AtomicOperation {
+	return &AtomicOperation{kv: k}
+}
+
+// Check adds a check that the key has the given version, as resolved by
+// AtomicOperation.Commit, or does not exist when the version is null.
+func (a *AtomicOperation) Check(key sobek.Value, version sobek.Value) *AtomicOperation {
+	keyBytes, err := a.importKey(key)
+	if err != nil {
+		return a
+	}
+
+	check := atomicCheck{key: keyBytes}
+	if !common.IsNullish(version) {
+		expected := uint64(version.ToInteger())
+		check.version = &expected
+	}
+
+	a.checks = append(a.checks, check)
+
+	return a
+}
+
+// Set adds the setting of the value of a key. It accepts the same options as KV.Set.
+func (a *AtomicOperation) Set(key sobek.Value, value sobek.Value, options sobek.Value) *AtomicOperation {
+	keyBytes, err := a.importKey(key)
+	if err != nil {
+		return a
+	}
+
+	jsonValue, err := a.kv.marshal(keyBytes, value)
+	if err != nil {
+		a.fail(err)
+		return a
+	}
+
+	setOptions, err := ImportSetOptions(a.kv.vu.Runtime(), options)
+	if err != nil {
+		a.fail(err)
+		return a
+	}
+
+	a.mutations = append(a.mutations, mutation{op: "atomic.set", key: keyBytes, value: jsonValue})
+	a.ttls = append(a.ttls, setOptions.TTL)
+
+	return a
+}
+
+// Delete adds the deletion of a key.
+func (a *AtomicOperation) Delete(key sobek.Value) *AtomicOperation {
+	keyBytes, err := a.importKey(key)
+	if err != nil {
+		return a
+	}
+
+	a.mutations = append(a.mutations, mutation{op: "atomic.delete", key: keyBytes})
+	a.ttls = append(a.ttls, 0)
+
+	return a
+}
+
+// Commit applies the operation's mutations within a single transaction, if
+// all of its checks pass, and resolves with an AtomicResult telling whether
+// it did.
+func (a *AtomicOperation) Commit() *sobek.Promise {
+	k := a.kv
+	promise, resolve, reject := promises.New(k.vu)
+
+	if a.err != nil {
+		reject(a.err)
+		return promise
+	}
+
+	checks, mutations, ttls := a.checks, a.mutations, a.ttls
+
+	go func() {
+		var result AtomicResult
+
+		err := k.mutateMany(mutations, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			if !k.checkVersions(tx, bucket, checks) {
+				return errCheckFailed
+			}
+
+			for i, m := range mutations {
+				if err := undelay(tx, k.bucket, m.key); err != nil {
+					return err
+				}
+
+				if m.value == nil {
+					if err := k.removeValue(tx, bucket, m.key); err != nil {
+						return err
+					}
+
+					continue
+				}
+
+				if err := k.storeValue(tx, bucket, m.key, m.value); err != nil {
+					return err
+				}
+
+				if ttls[i] > 0 {
+					if err := k.expireKey(tx, m.key, time.Now().Add(ttls[i])); err != nil {
+						return err
+					}
+				}
+
+				version := versionOf(tx, k.bucket, m.key)
+				result.Version = &version
+			}
+
+			result.OK = true
+
+			return nil
+		})
+		if err != nil && !errors.Is(err, errCheckFailed) {
+			reject(err)
+			return
+		}
+
+		resolve(result)
+	}()
+
+	return promise
+}
+
+// checkVersions reports whether the keys have the versions the checks expect.
+func (k *KV) checkVersions(tx *bolt.Tx, bucket *bolt.Bucket, checks []atomicCheck) bool {
+	visible := newVisibility(tx, k.bucket)
+
+	for _, check := range checks {
+		exists := bucket.Get(check.key) != nil && !visible.hidden(check.key)
+
+		switch {
+		case check.version == nil && exists:
+			return false
+		case check.version == nil:
+			continue
+		case !exists || versionOf(tx, k.bucket, check.key) != *check.version:
+			return false
+		}
+	}
+
+	return true
+}
+
+// importKey converts and validates a key of the operation, recording
+// the error which rejects its commit if the key is invalid.
+func (a *AtomicOperation) importKey(key sobek.Value) ([]byte, error) {
+	if common.IsNullish(key) {
+		err := NewError(KeyRequiredError, "key must not be empty")
+		a.fail(err)
+		return nil, err
+	}
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err == nil {
+		err = a.kv.validateKey(keyBytes)
+	}
+
+	if err != nil {
+		a.fail(fmt.Errorf("atomic operation: %w", err))
+		return nil, err
+	}
+
+	return keyBytes, nil
+}
+
+// fail records the first error met while building the operation.
+func (a *AtomicOperation) fail(err error) {
+	if a.err == nil {
+		a.err = err
+	}
+}
@@ -0,0 +1,41 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVCheckVersions(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+	expect := func(version uint64) *uint64 { return &version }
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("a"), []byte(`1`)))
+		first := versionOf(tx, kv.bucket, []byte("a"))
+
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("a"), []byte(`2`)))
+		second := versionOf(tx, kv.bucket, []byte("a"))
+		assert.Greater(t, second, first)
+
+		assert.True(t, kv.checkVersions(tx, bucket, []atomicCheck{
+			{key: []byte("a"), version: expect(second)},
+			{key: []byte("missing")},
+		}))
+		assert.False(t, kv.checkVersions(tx, bucket, []atomicCheck{{key: []byte("a"), version: expect(first)}}))
+		assert.False(t, kv.checkVersions(tx, bucket, []atomicCheck{{key: []byte("a")}}))
+		assert.False(t, kv.checkVersions(tx, bucket, []atomicCheck{{key: []byte("missing"), version: expect(0)}}))
+
+		require.NoError(t, kv.removeValue(tx, bucket, []byte("a")))
+		assert.Nil(t, tx.Bucket(versionsBucket(kv.bucket)).Get([]byte("a")))
+
+		return nil
+	}))
+}
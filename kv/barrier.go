@@ -0,0 +1,122 @@
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// BarriersBucket is the name of the internal bucket holding the number of
+// callers which arrived at each barrier, by name.
+const BarriersBucket = "k6/barriers"
+
+// Barrier records the caller's arrival at the named barrier, shared by all
+// VUs, and resolves with its arrival number, starting at 1, once count
+// callers have arrived.
+//
+// A barrier opens once, for good: callers arriving after it opened resolve
+// right away. If the timeout option is set, either as a number of
+// milliseconds or as a duration string such as "30s", the returned promise
+// is rejected with a TimeoutError if the barrier has not opened by then.
+//
+// Arrivals are recorded in dry-run mode as well, so that barriers open.
+func (k *KV) Barrier(name sobek.Value, count sobek.Value, options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(name) || name.String() == "" {
+		reject(NewError(KeyRequiredError, "barrier name is required"))
+		return promise
+	}
+
+	if common.IsNullish(count) || count.ToInteger() <= 0 {
+		reject(fmt.Errorf("barrier count must be a positive integer, got %v", count))
+		return promise
+	}
+
+	var timeout sobek.Value
+	if !common.IsNullish(options) {
+		timeout = options.ToObject(k.vu.Runtime()).Get("timeout")
+	}
+
+	waitTimeout, err := toDuration(timeout)
+	if err != nil {
+		reject(fmt.Errorf("invalid timeout: %w", err))
+		return promise
+	}
+
+	nameBytes := []byte(name.String())
+	expected := count.ToInteger()
+
+	go func() {
+		var arrival int64
+
+		err := k.coordinate(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(BarriersBucket))
+			if err != nil {
+				return err
+			}
+
+			if arrival, err = loadArrivals(bucket, nameBytes); err != nil {
+				return err
+			}
+
+			arrival++
+
+			raw, err := json.Marshal(arrival)
+			if err != nil {
+				return err
+			}
+
+			return bucket.Put(nameBytes, raw)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		err = pollUntil(k.vu.Context(), waitTimeout, func() (bool, error) {
+			var arrivals int64
+
+			err := k.view(func(tx *bolt.Tx) error {
+				var err error
+				arrivals, err = loadArrivals(tx.Bucket([]byte(BarriersBucket)), nameBytes)
+
+				return err
+			})
+
+			return arrivals >= expected, err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(arrival)
+	}()
+
+	return promise
+}
+
+// loadArrivals reads the number of callers which arrived at the named
+// barrier from the bucket, if it exists.
+func loadArrivals(bucket *bolt.Bucket, name []byte) (int64, error) {
+	if bucket == nil {
+		return 0, nil
+	}
+
+	raw := bucket.Get(name)
+	if raw == nil {
+		return 0, nil
+	}
+
+	var arrivals int64
+	if err := json.Unmarshal(raw, &arrivals); err != nil {
+		return 0, fmt.Errorf("failed to decode barrier %s: %w", name, err)
+	}
+
+	return arrivals, nil
+}
@@ -0,0 +1,72 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVBarrier(t *testing.T) {
+	t.Parallel()
+
+	t.Run("barriers open once enough callers arrived", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			Promise.all([store.barrier("start", 2), store.barrier("start", 2)])
+				.then((arrivals) => {
+					arrivals.sort();
+					if (arrivals[0] !== 1 || arrivals[1] !== 2) {
+						throw new Error("expected arrivals 1 and 2, got " + arrivals);
+					}
+
+					// Callers arriving after the barrier opened resolve right away.
+					return store.barrier("start", 2);
+				})
+				.then((arrival) => {
+					if (arrival !== 3) {
+						throw new Error("expected arrival 3, got " + arrival);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("barriers open in dry-run mode", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv({ dryRun: true });
+
+			Promise.all([store.barrier("start", 2, { timeout: "5s" }), store.barrier("start", 2, { timeout: "5s" })]);
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("callers time out when the barrier does not open", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.barrier("start", 2, { timeout: 50 })
+				.then(
+					() => { throw new Error("expected the barrier not to open"); },
+					(err) => {
+						if (String(err.name) !== "TimeoutError") {
+							throw new Error("expected a TimeoutError, got " + JSON.stringify(err));
+						}
+					},
+				);
+		`)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,225 @@
+package kv
+
+import (
+	"fmt"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// SetMany sets the values of several keys in the store, given as the
+// properties of an object, within a single transaction, and resolves with
+// the number of keys set.
+//
+// Either all the keys are set, or none is.
+func (k *KV) SetMany(entries sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(entries) {
+		reject(fmt.Errorf("setMany expects an object of key-value pairs, got %v", entries))
+		return promise
+	}
+
+	entriesObj := entries.ToObject(k.vu.Runtime())
+	keys := entriesObj.Keys()
+
+	mutations := make([]mutation, 0, len(keys))
+	for _, key := range keys {
+		keyBytes := []byte(key)
+		if err := k.validateKey(keyBytes); err != nil {
+			reject(err)
+			return promise
+		}
+
+		jsonValue, err := k.marshal(keyBytes, entriesObj.Get(key))
+		if err != nil {
+			reject(err)
+			return promise
+		}
+
+		mutations = append(mutations, mutation{op: "setMany", key: keyBytes, value: jsonValue})
+	}
+
+	go func() {
+		err := k.mutateMany(mutations, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			for _, m := range mutations {
+				if err := undelay(tx, k.bucket, m.key); err != nil {
+					return err
+				}
+
+				if err := k.storeValue(tx, bucket, m.key, m.value); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(len(mutations))
+	}()
+
+	return promise
+}
+
+// GetMany resolves with the values of several keys in the store, read within
+// a single transaction, in the order the keys are given. Keys which do not
+// exist have a null value.
+//
+// The options accept the same fields projection as KV.List.
+func (k *KV) GetMany(keys sobek.Value, options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyList, err := exportKeys(keys)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	listOptions, err := ImportListOptions(k.vu.Runtime(), options)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	fields := listOptions.Fields
+	settle := k.reviveLater(resolve, reject)
+
+	go func() {
+		values := make([]any, len(keyList))
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			visible := newVisibility(tx, k.bucket)
+
+			for i, key := range keyList {
+				jsonValue, err := loadValue(tx, bucket.Get(key))
+				if err != nil {
+					return err
+				}
+
+				if jsonValue == nil || visible.hidden(key) {
+					k.db.stats.lookup(nil)
+					continue
+				}
+
+				k.db.stats.lookup(jsonValue)
+
+				if values[i], err = decodeFields(jsonValue, fields); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		settle(func() (any, error) {
+			if err != nil {
+				return nil, err
+			}
+
+			for i, value := range values {
+				if value == nil {
+					continue
+				}
+
+				revived, err := k.revive(keyList[i], value)
+				if err != nil {
+					return nil, err
+				}
+
+				values[i] = revived
+			}
+
+			return values, nil
+		})
+	}()
+
+	return promise
+}
+
+// DeleteMany deletes several keys from the store within a single
+// transaction, and resolves with the number of keys which existed.
+func (k *KV) DeleteMany(keys sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyList, err := exportKeys(keys)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	mutations := make([]mutation, 0, len(keyList))
+	for _, key := range keyList {
+		mutations = append(mutations, mutation{op: "deleteMany", key: key})
+	}
+
+	go func() {
+		var deleted int64
+
+		err := k.mutateMany(mutations, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			for _, key := range keyList {
+				if bucket.Get(key) != nil {
+					deleted++
+				}
+
+				if err := undelay(tx, k.bucket, key); err != nil {
+					return err
+				}
+
+				if err := k.removeValue(tx, bucket, key); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(deleted)
+	}()
+
+	return promise
+}
+
+// exportKeys converts an array of keys to byte slices.
+func exportKeys(keys sobek.Value) ([][]byte, error) {
+	exported, isArray := keys.Export().([]any)
+	if common.IsNullish(keys) || !isArray {
+		return nil, fmt.Errorf("expected an array of keys, got %v", keys)
+	}
+
+	keyList := make([][]byte, 0, len(exported))
+	for _, key := range exported {
+		keyBytes, err := common.ToBytes(key)
+		if err != nil {
+			return nil, err
+		}
+
+		keyList = append(keyList, keyBytes)
+	}
+
+	return keyList, nil
+}
@@ -0,0 +1,108 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVBatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("getMany resolves with null for the missing keys", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			Promise.all([store.set("a", 1), store.set("c", { n: 3 })])
+				.then(() => store.getMany(["a", "b", "c"]))
+				.then((values) => {
+					if (JSON.stringify(values) !== '[1,null,{"n":3}]') {
+						throw new Error("expected [1,null,{\"n\":3}], got " + JSON.stringify(values));
+					}
+
+					return store.stats();
+				})
+				.then((stats) => {
+					if (stats.hits !== 2 || stats.misses !== 1) {
+						throw new Error("expected 2 hits and 1 miss, got " + stats.hits + " and " + stats.misses);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("setMany and deleteMany each commit a single transaction", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.setMany({ a: 1, b: 2, c: 3 })
+				.then((set) => {
+					if (set !== 3) {
+						throw new Error("expected 3 keys to be set, got " + set);
+					}
+
+					return store.stats();
+				})
+				.then((stats) => {
+					if (stats.writes !== 1) {
+						throw new Error("expected setMany to commit once, got " + stats.writes + " writes");
+					}
+
+					return store.deleteMany(["a", "b", "missing"]);
+				})
+				.then((deleted) => {
+					if (deleted !== 2) {
+						throw new Error("expected 2 keys to be deleted, got " + deleted);
+					}
+
+					return Promise.all([store.stats(), store.getMany(["a", "b", "c"])]);
+				})
+				.then(([stats, values]) => {
+					if (stats.writes !== 2) {
+						throw new Error("expected deleteMany to commit once, got " + (stats.writes - 1) + " writes");
+					}
+
+					if (JSON.stringify(values) !== "[null,null,3]") {
+						throw new Error("expected [null,null,3], got " + JSON.stringify(values));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("setMany sets no key if one of them is invalid", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv({ maxValueSize: 16 });
+
+			store.setMany({ a: 1, b: "a value longer than the maximum" })
+				.then(
+					() => { throw new Error("expected setMany to reject"); },
+					(err) => {
+						if (String(err.name) !== "ValueTooLargeError") {
+							throw err;
+						}
+
+						return store.exists("a");
+					},
+				)
+				.then((exists) => {
+					if (exists) {
+						throw new Error("expected no key to be set");
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+}
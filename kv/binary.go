@@ -0,0 +1,65 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/grafana/sobek"
+)
+
+const (
+	// SerializationJSON stores every value as JSON.
+	SerializationJSON = "json"
+
+	// SerializationBinary stores ArrayBuffer and typed array values as their
+	// raw bytes, and reads them back as ArrayBuffers. Other values are still
+	// stored as JSON.
+	SerializationBinary = "binary"
+)
+
+// binaryMarker prefixes the stored form of binary values, followed by their
+// raw bytes. No JSON value starts with it.
+const binaryMarker = 0x01
+
+// isBinary reports whether a stored value is a binary value.
+func isBinary(value []byte) bool {
+	return len(value) > 0 && value[0] == binaryMarker
+}
+
+// encodeBinary returns the stored form of a binary value.
+func encodeBinary(data []byte) []byte {
+	return append([]byte{binaryMarker}, data...)
+}
+
+// binaryValue returns the bytes of an exported ArrayBuffer or typed array
+// value, if the value is one.
+func binaryValue(exported any) ([]byte, bool) {
+	switch exported := exported.(type) {
+	case sobek.ArrayBuffer:
+		return exported.Bytes(), true
+	case []byte:
+		return exported, true
+	default:
+		return nil, false
+	}
+}
+
+// decodeValue decodes a stored value, like json.Unmarshal, but for binary
+// values, which are decoded to a copy of their bytes, so that they remain
+// valid once the transaction they were read in ends, and values encoded by
+// a registered Serializer, which are decoded by it.
+func decodeValue(data []byte, value *any) error {
+	if isBinary(data) {
+		*value = bytes.Clone(data[1:])
+		return nil
+	}
+
+	if isSerialized(data) {
+		decoded, err := decodeSerialized(data)
+		*value = decoded
+
+		return err
+	}
+
+	return json.Unmarshal(data, value)
+}
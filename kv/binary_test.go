@@ -0,0 +1,59 @@
+package kv
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestDecodeValue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("binary values are decoded to a copy of their bytes", func(t *testing.T) {
+		t.Parallel()
+
+		stored := encodeBinary([]byte{0x00, 0xff, '"'})
+
+		var value any
+		require.NoError(t, decodeValue(stored, &value))
+		assert.Equal(t, []byte{0x00, 0xff, '"'}, value)
+
+		stored[1] = 0x42
+		assert.Equal(t, []byte{0x00, 0xff, '"'}, value)
+	})
+
+	t.Run("other values are decoded from JSON", func(t *testing.T) {
+		t.Parallel()
+
+		var value any
+		require.NoError(t, decodeValue([]byte(`{"foo":"bar"}`), &value))
+		assert.Equal(t, map[string]any{"foo": "bar"}, value)
+	})
+}
+
+func TestExportBinaryEntries(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	dbInstance := openTestDB(t, Options{})
+	path := filepath.Join(tmpDir, "binary.json")
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(DefaultKvBucket))
+		if err := bucket.Put([]byte("payload"), encodeBinary([]byte("hello"))); err != nil {
+			return err
+		}
+
+		_, err := exportEntries(tx, []byte(DefaultKvBucket), nil, path)
+
+		return err
+	}))
+
+	entries, err := readSeed(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.JSONEq(t, `"aGVsbG8="`, string(entries[0].Value))
+}
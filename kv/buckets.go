@@ -0,0 +1,192 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// validateBucketName checks that a bucket name can be used for an
+// isolated keyspace of the store.
+//
+// Names containing a "/" are reserved for the internal buckets, such as
+// the ones holding the TTLs of a bucket's keys.
+func validateBucketName(name string) error {
+	if name == "" {
+		return errors.New("bucket name is required")
+	}
+
+	if strings.ContainsRune(name, '/') {
+		return fmt.Errorf("bucket name %q must not contain a '/', which is reserved for internal buckets", name)
+	}
+
+	return nil
+}
+
+// ensureBucket creates the bucket if it does not exist yet.
+//
+// In the sharedReadOnly mode, the bucket is left as is, and operations
+// on it are rejected with a BucketNotFoundError if it does not exist.
+func (db *db) ensureBucket(name []byte) error {
+	if db.readOnly {
+		return nil
+	}
+
+	return db.handle.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(name)
+		return err
+	})
+}
+
+// Bucket returns a KV instance reading and writing the named bucket of
+// the store, an isolated keyspace which is created if it does not exist.
+//
+// It shares the store and the options of the instance it is called on,
+// and should be closed as well.
+func (k *KV) Bucket(name sobek.Value) *sobek.Object {
+	rt := k.vu.Runtime()
+
+	if common.IsNullish(name) {
+		common.Throw(rt, errors.New("bucket name is required"))
+		return nil
+	}
+
+	if err := validateBucketName(name.String()); err != nil {
+		common.Throw(rt, err)
+		return nil
+	}
+
+	if err := k.db.open(k.options); err != nil {
+		common.Throw(rt, err)
+		return nil
+	}
+
+	if err := k.db.ensureBucket([]byte(name.String())); err != nil {
+		_ = k.db.close()
+		common.Throw(rt, err)
+		return nil
+	}
+
+	scoped := NewKV(k.vu, k.db)
+	scoped.bucket = []byte(name.String())
+	scoped.options = k.options
+	scoped.limiter = k.limiter
+	scoped.codecs = k.codecs
+	scoped.churnMetrics = k.churnMetrics
+
+	return rt.ToValue(scoped).ToObject(rt)
+}
+
+// ListBuckets resolves with the names of the buckets of the store, in
+// lexicographical order, leaving out the internal ones.
+func (k *KV) ListBuckets() *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	go func() {
+		names := []string{}
+
+		err := k.view(func(tx *bolt.Tx) error {
+			return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+				if validateBucketName(string(name)) == nil {
+					names = append(names, string(name))
+				}
+
+				return nil
+			})
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(names)
+	}()
+
+	return promise
+}
+
+// DeleteBucket deletes the named bucket of the store, along with its keys.
+//
+// The default bucket cannot be deleted, but can be cleared with KV.Clear.
+func (k *KV) DeleteBucket(name sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(name) {
+		reject(errors.New("bucket name is required"))
+		return promise
+	}
+
+	if err := validateBucketName(name.String()); err != nil {
+		reject(err)
+		return promise
+	}
+
+	if name.String() == DefaultKvBucket {
+		reject(fmt.Errorf("the default bucket %q cannot be deleted, clear it instead", DefaultKvBucket))
+		return promise
+	}
+
+	bucketName := []byte(name.String())
+
+	go func() {
+		err := k.mutate(mutation{op: "deleteBucket", key: bucketName, internal: true}, func(tx *bolt.Tx) error {
+			return k.dropBucket(tx, bucketName, OpLogDeleteBucket)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(true)
+	}()
+
+	return promise
+}
+
+// dropBucket deletes the named bucket, along with its internal buckets,
+// releasing the content its values reference, and records it to the
+// operation log as op, either OpLogClear or OpLogDeleteBucket.
+func (k *KV) dropBucket(tx *bolt.Tx, name []byte, op string) error {
+	if bucket := tx.Bucket(name); bucket != nil {
+		k.trackClear(tx, bucket)
+		k.notifyClear(tx, name)
+		k.invalidateIndex(tx, name)
+
+		if err := k.logOp(tx, op, name, nil, nil); err != nil {
+			return err
+		}
+
+		if err := releaseValues(tx, bucket); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.DeleteBucket(name); err != nil {
+		if errors.Is(err, bolt.ErrBucketNotFound) {
+			return NewError(BucketNotFoundError, "bucket "+string(name)+" not found")
+		}
+
+		return err
+	}
+
+	if err := dropIndexes(tx, name); err != nil {
+		return err
+	}
+
+	for _, internal := range [][]byte{
+		delayedBucket(name), expiryBucket(name), undoBucket(name), versionsBucket(name),
+	} {
+		if tx.Bucket(internal) != nil {
+			if err := tx.DeleteBucket(internal); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
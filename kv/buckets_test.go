@@ -0,0 +1,55 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestValidateBucketName(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, validateBucketName("emails"))
+	assert.NoError(t, validateBucketName(DefaultKvBucket))
+	assert.Error(t, validateBucketName(""))
+	assert.Error(t, validateBucketName("emails/delayed"))
+	assert.Error(t, validateBucketName(ProgressBucket))
+}
+
+func TestKVDropBucket(t *testing.T) {
+	t.Parallel()
+
+	dbInstance := openTestDB(t, Options{})
+	emails := []byte("emails")
+	require.NoError(t, dbInstance.ensureBucket(emails))
+
+	kv := &KV{bucket: emails, db: dbInstance}
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		if err := kv.storeValue(tx, tx.Bucket(emails), []byte("foo"), []byte(`"bar"`)); err != nil {
+			return err
+		}
+
+		return expire(tx, emails, []byte("foo"), time.Hour)
+	}))
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		return kv.dropBucket(tx, emails, OpLogDeleteBucket)
+	}))
+
+	assert.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+		assert.Nil(t, tx.Bucket(emails))
+		assert.Nil(t, tx.Bucket(expiryBucket(emails)))
+		assert.NotNil(t, tx.Bucket([]byte(DefaultKvBucket)))
+		return nil
+	}))
+
+	err := dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		return kv.dropBucket(tx, emails, OpLogDeleteBucket)
+	})
+	var kvErr *Error
+	require.ErrorAs(t, err, &kvErr)
+	assert.Equal(t, ErrorName(BucketNotFoundError), kvErr.Name)
+}
@@ -0,0 +1,120 @@
+package kv
+
+import (
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// CompareAndSwap atomically sets the value of a key in the store, only if
+// its current value is equal to the expected one, and resolves with whether
+// it did.
+//
+// Values are compared once JSON-serialized, so that objects are equal when
+// their properties are. An expected value of null matches absent keys.
+func (k *KV) CompareAndSwap(key sobek.Value, expected sobek.Value, value sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	var expectedValue any
+	if !common.IsNullish(expected) {
+		jsonExpected, err := k.marshal(keyBytes, expected)
+		if err != nil {
+			reject(err)
+			return promise
+		}
+
+		if err := decodeValue(jsonExpected, &expectedValue); err != nil {
+			reject(err)
+			return promise
+		}
+	}
+
+	jsonValue, err := k.marshal(keyBytes, value)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		// Compare the values in a read transaction first, so that mismatches
+		// neither wait for, nor hold up, the writers of the store.
+		var swapped bool
+
+		err := k.view(func(tx *bolt.Tx) error {
+			var err error
+			swapped, err = k.matchesValue(tx, keyBytes, expectedValue)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		if !swapped {
+			resolve(false)
+			return
+		}
+
+		err = k.mutate(mutation{op: "compareAndSwap", key: keyBytes, value: jsonValue}, func(tx *bolt.Tx) error {
+			// The value may have changed since it was compared.
+			var err error
+			if swapped, err = k.matchesValue(tx, keyBytes, expectedValue); err != nil {
+				return err
+			}
+
+			if !swapped {
+				return errUnchanged
+			}
+
+			if err := undelay(tx, k.bucket, keyBytes); err != nil {
+				return err
+			}
+
+			return k.storeValue(tx, tx.Bucket(k.bucket), keyBytes, jsonValue)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(swapped)
+	}()
+
+	return promise
+}
+
+// matchesValue reports whether the current value of the key, or null if it
+// does not exist, is equal to the expected one, once JSON-serialized.
+func (k *KV) matchesValue(tx *bolt.Tx, key []byte, expected any) (bool, error) {
+	bucket := tx.Bucket(k.bucket)
+	if bucket == nil {
+		return false, NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+	}
+
+	jsonCurrent, err := loadValue(tx, bucket.Get(key))
+	if err != nil {
+		return false, err
+	}
+
+	var current any
+	if jsonCurrent != nil && !newVisibility(tx, k.bucket).hidden(key) {
+		if err := decodeValue(jsonCurrent, &current); err != nil {
+			return false, err
+		}
+	}
+
+	return len(diffValues("", expected, current)) == 0, nil
+}
@@ -0,0 +1,110 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVCompareAndSwap(t *testing.T) {
+	t.Parallel()
+
+	t.Run("matching values are swapped", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.set("config", { version: 1 })
+				.then(() => store.compareAndSwap("config", { version: 1 }, { version: 2 }))
+				.then((swapped) => {
+					if (!swapped) {
+						throw new Error("expected the value to be swapped");
+					}
+
+					return store.get("config");
+				})
+				.then((value) => {
+					if (value.version !== 2) {
+						throw new Error("expected the swapped value, got " + JSON.stringify(value));
+					}
+
+					return store.compareAndSwap("missing", null, "created");
+				})
+				.then((swapped) => {
+					if (!swapped) {
+						throw new Error("expected an absent key to match null");
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("mismatching values are left unchanged, without writing", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			let writes;
+
+			store.set("config", { version: 1 })
+				.then(() => store.stats())
+				.then((stats) => {
+					writes = stats.writes;
+					return store.compareAndSwap("config", { version: 3 }, { version: 4 });
+				})
+				.then((swapped) => {
+					if (swapped) {
+						throw new Error("expected the value not to be swapped");
+					}
+
+					return Promise.all([store.get("config"), store.stats()]);
+				})
+				.then(([value, stats]) => {
+					if (value.version !== 1) {
+						throw new Error("expected the value to be unchanged, got " + JSON.stringify(value));
+					}
+
+					if (stats.writes !== writes) {
+						throw new Error("expected the mismatch not to write");
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("only swapped values are recorded in dry-run mode", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv({ dryRun: true });
+
+			store.compareAndSwap("config", { version: 1 }, { version: 2 })
+				.then((swapped) => {
+					if (swapped) {
+						throw new Error("expected the value not to be swapped");
+					}
+
+					return store.compareAndSwap("config", null, { version: 1 });
+				})
+				.then((swapped) => {
+					if (!swapped) {
+						throw new Error("expected an absent key to match null");
+					}
+
+					const report = store.dryRunReport();
+					if (report.length !== 1 || report[0].op !== "compareAndSwap") {
+						throw new Error("expected the swap alone to be recorded, got " + JSON.stringify(report));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+}
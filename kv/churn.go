@@ -0,0 +1,240 @@
+package kv
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/metrics"
+)
+
+// churnKind is the kind of change a mutation made to a key's existence.
+type churnKind int
+
+const (
+	// churnCreated is a write of a key which did not exist.
+	churnCreated churnKind = iota
+
+	// churnOverwritten is a write of a key which already existed.
+	churnOverwritten
+
+	// churnDeleted is a deletion of a key which existed.
+	churnDeleted
+)
+
+// ChurnStats counts the keys created, overwritten and deleted, as returned
+// by KV.ChurnStats().
+type ChurnStats struct {
+	// Created is the number of writes of keys which did not exist.
+	Created int64 `json:"created" js:"created"`
+
+	// Overwritten is the number of writes of keys which already existed.
+	Overwritten int64 `json:"overwritten" js:"overwritten"`
+
+	// Deleted is the number of keys deleted, including by KV.Clear.
+	Deleted int64 `json:"deleted" js:"deleted"`
+
+	// ByPrefix breaks the counts down by the churnPrefixes option's
+	// prefixes, each key counting towards the longest prefix it starts with.
+	ByPrefix map[string]*ChurnStats `json:"byPrefix,omitempty" js:"byPrefix"`
+}
+
+// add adds n changes of the given kind to the counts.
+func (s *ChurnStats) add(kind churnKind, n int64) {
+	switch kind {
+	case churnCreated:
+		s.Created += n
+	case churnOverwritten:
+		s.Overwritten += n
+	case churnDeleted:
+		s.Deleted += n
+	}
+}
+
+// churnLog counts the changes made to the keys of the store, shared by all VUs.
+type churnLog struct {
+	lock  sync.Mutex
+	stats ChurnStats
+}
+
+// record adds n changes of the given kind to the counts, and to the prefix's
+// counts if it is not empty.
+func (l *churnLog) record(prefix string, kind churnKind, n int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.stats.add(kind, n)
+
+	if prefix == "" {
+		return
+	}
+
+	if l.stats.ByPrefix == nil {
+		l.stats.ByPrefix = make(map[string]*ChurnStats)
+	}
+
+	if l.stats.ByPrefix[prefix] == nil {
+		l.stats.ByPrefix[prefix] = &ChurnStats{}
+	}
+
+	l.stats.ByPrefix[prefix].add(kind, n)
+}
+
+// snapshot returns a copy of the counts, and resets them if reset is true.
+func (l *churnLog) snapshot(reset bool) ChurnStats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	stats := ChurnStats{
+		Created:     l.stats.Created,
+		Overwritten: l.stats.Overwritten,
+		Deleted:     l.stats.Deleted,
+		ByPrefix:    make(map[string]*ChurnStats, len(l.stats.ByPrefix)),
+	}
+
+	for prefix, counts := range l.stats.ByPrefix {
+		stats.ByPrefix[prefix] = &ChurnStats{
+			Created:     counts.Created,
+			Overwritten: counts.Overwritten,
+			Deleted:     counts.Deleted,
+		}
+	}
+
+	if reset {
+		l.stats = ChurnStats{}
+	}
+
+	return stats
+}
+
+// churnMetrics are the k6 metrics the changes made to the keys of the store
+// are reported to.
+type churnMetrics struct {
+	created     *metrics.Metric
+	overwritten *metrics.Metric
+	deleted     *metrics.Metric
+}
+
+// registerChurnMetrics registers the kv_keys_created, kv_keys_overwritten
+// and kv_keys_deleted counters.
+func registerChurnMetrics(registry *metrics.Registry) (*churnMetrics, error) {
+	var (
+		churn churnMetrics
+		err   error
+	)
+
+	if churn.created, err = registry.NewMetric("kv_keys_created", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if churn.overwritten, err = registry.NewMetric("kv_keys_overwritten", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	if churn.deleted, err = registry.NewMetric("kv_keys_deleted", metrics.Counter); err != nil {
+		return nil, err
+	}
+
+	return &churn, nil
+}
+
+// ChurnStats returns the number of keys created, overwritten and deleted by
+// all VUs since the store was opened. Keys purged as their TTL elapses count
+// as deleted.
+//
+// When called with { reset: true }, the counts are reset once returned, so
+// that successive calls return the changes made in between.
+func (k *KV) ChurnStats(options sobek.Value) ChurnStats {
+	reset := false
+	if !common.IsNullish(options) {
+		reset = options.ToObject(k.vu.Runtime()).Get("reset").ToBoolean()
+	}
+
+	return k.db.churn.snapshot(reset)
+}
+
+// trackChurn counts n changes of the given kind to the key, once the
+// transaction is committed.
+func (k *KV) trackChurn(tx *bolt.Tx, key []byte, kind churnKind, n int64) {
+	prefix := k.churnPrefix(key)
+
+	tx.OnCommit(func() {
+		k.db.churn.record(prefix, kind, n)
+		k.emitChurn(prefix, kind, n)
+	})
+}
+
+// trackClear counts the deletions of all the keys of the bucket, once the
+// transaction is committed.
+func (k *KV) trackClear(tx *bolt.Tx, bucket *bolt.Bucket) {
+	if len(k.options.ChurnPrefixes) == 0 {
+		k.trackChurn(tx, nil, churnDeleted, int64(bucket.Stats().KeyN))
+		return
+	}
+
+	deleted := make(map[string]int64)
+	_ = bucket.ForEach(func(key, _ []byte) error {
+		deleted[k.churnPrefix(key)]++
+		return nil
+	})
+
+	for prefix, n := range deleted {
+		k.trackChurn(tx, []byte(prefix), churnDeleted, n)
+	}
+}
+
+// churnPrefix returns the longest of the churnPrefixes option's prefixes
+// the key starts with, or an empty string if there is none.
+func (k *KV) churnPrefix(key []byte) string {
+	return longestPrefix(k.options.ChurnPrefixes, key)
+}
+
+// longestPrefix returns the longest of the prefixes the key starts with, or
+// an empty string if there is none.
+func longestPrefix(prefixes []string, key []byte) string {
+	var longest string
+
+	for _, prefix := range prefixes {
+		if len(prefix) > len(longest) && strings.HasPrefix(string(key), prefix) {
+			longest = prefix
+		}
+	}
+
+	return longest
+}
+
+// emitChurn pushes a sample of n changes of the given kind to the churn metrics.
+func (k *KV) emitChurn(prefix string, kind churnKind, n int64) {
+	if k.churnMetrics == nil {
+		return
+	}
+
+	state := k.vu.State()
+	if state == nil {
+		return
+	}
+
+	metric := k.churnMetrics.created
+	switch kind {
+	case churnOverwritten:
+		metric = k.churnMetrics.overwritten
+	case churnDeleted:
+		metric = k.churnMetrics.deleted
+	}
+
+	ctm := state.Tags.GetCurrentValues()
+	tags := ctm.Tags
+	if prefix != "" {
+		tags = tags.With("prefix", prefix)
+	}
+
+	metrics.PushIfNotDone(k.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{Metric: metric, Tags: tags},
+		Time:       time.Now(),
+		Metadata:   ctm.Metadata,
+		Value:      float64(n),
+	})
+}
@@ -0,0 +1,86 @@
+package kv
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestChurnTracking(t *testing.T) {
+	t.Parallel()
+
+	dbInstance := openTestDB(t, Options{})
+	kv := &KV{
+		bucket:  []byte(DefaultKvBucket),
+		db:      dbInstance,
+		options: Options{ChurnPrefixes: []string{"users:", "users:admin:"}},
+	}
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("users:1"), []byte(`1`)))
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("users:1"), []byte(`2`)))
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("users:admin:1"), []byte(`1`)))
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("other"), []byte(`1`)))
+		require.NoError(t, kv.removeValue(tx, bucket, []byte("other")))
+		require.NoError(t, kv.removeValue(tx, bucket, []byte("missing")))
+
+		return nil
+	}))
+
+	// Changes made by transactions which are rolled back are not counted.
+	rollback := errors.New("rollback")
+	require.ErrorIs(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		require.NoError(t, kv.storeValue(tx, tx.Bucket(kv.bucket), []byte("users:2"), []byte(`1`)))
+		return rollback
+	}), rollback)
+
+	assert.Equal(t, ChurnStats{
+		Created:     3,
+		Overwritten: 1,
+		Deleted:     1,
+		ByPrefix: map[string]*ChurnStats{
+			"users:":       {Created: 1, Overwritten: 1},
+			"users:admin:": {Created: 1},
+		},
+	}, dbInstance.churn.snapshot(true))
+
+	assert.Equal(t, ChurnStats{ByPrefix: map[string]*ChurnStats{}}, dbInstance.churn.snapshot(false))
+}
+
+func TestChurnTrackingPurges(t *testing.T) {
+	t.Parallel()
+
+	options := Options{ChurnPrefixes: []string{"sessions:"}}
+	dbInstance := openTestDB(t, options)
+	kv := &KV{bucket: []byte(DefaultKvBucket), db: dbInstance, options: options}
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		for _, key := range []string{"sessions:1", "sessions:2", "other"} {
+			require.NoError(t, kv.storeValue(tx, bucket, []byte(key), []byte(`1`)))
+			require.NoError(t, expire(tx, kv.bucket, []byte(key), time.Nanosecond))
+		}
+
+		return nil
+	}))
+
+	dbInstance.churn.snapshot(true)
+
+	// Keys purged as their TTL elapses are counted as deleted
+	time.Sleep(time.Millisecond)
+	require.NoError(t, dbInstance.handle.Update(dbInstance.purgeExpired))
+
+	assert.Equal(t, ChurnStats{
+		Deleted: 3,
+		ByPrefix: map[string]*ChurnStats{
+			"sessions:": {Deleted: 2},
+		},
+	}, dbInstance.churn.snapshot(false))
+}
@@ -0,0 +1,79 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVClear(t *testing.T) {
+	t.Parallel()
+
+	t.Run("clearing the store deletes its keys, along with their state", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+			const other = kv.openKv({ bucket: "other" });
+
+			Promise.all([
+				store.set("session", "abc", { ttl: 60000 }),
+				store.setDelayed("job", "pending", "1h"),
+				store.set("counter", 1),
+				other.set("counter", 2),
+			])
+				.then(() => store.clear())
+				.then((cleared) => {
+					if (cleared !== true) {
+						throw new Error("expected clear to resolve with true, got " + cleared);
+					}
+
+					return Promise.all([store.size(), store.list(), other.get("counter")]);
+				})
+				.then(([size, entries, counter]) => {
+					if (size !== 0 || entries.length !== 0) {
+						throw new Error("expected the store to be empty, got " + JSON.stringify(entries));
+					}
+
+					if (counter !== 2) {
+						throw new Error("expected the other bucket to be left alone, got " + counter);
+					}
+
+					return Promise.all([store.set("session", "def"), store.set("job", "done")]);
+				})
+				.then(() => Promise.all([store.ttl("session"), store.get("job")]))
+				.then(([ttl, job]) => {
+					if (ttl !== null) {
+						throw new Error("expected the previous TTL to be cleared, got " + ttl);
+					}
+
+					if (job !== "done") {
+						throw new Error("expected the previous delay to be cleared, got " + job);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("clearing a prefix deletes its keys alone", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			Promise.all([store.set("users:1", "alice"), store.set("users:2", "bob"), store.set("orders:1", "book")])
+				.then(() => store.clear({ prefix: "users:" }))
+				.then(() => store.list())
+				.then((entries) => {
+					if (entries.length !== 1 || entries[0].key !== "orders:1") {
+						throw new Error("expected the other keys to be left alone, got " + JSON.stringify(entries));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+}
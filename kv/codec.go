@@ -0,0 +1,174 @@
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+// codec holds the hooks converting the values of the keys starting with
+// a prefix to and from their JSON-serializable form.
+type codec struct {
+	// prefix is the prefix of the keys the codec applies to.
+	prefix string
+
+	// serialize converts a value to its JSON-serializable form, if set.
+	serialize sobek.Callable
+
+	// revive converts a decoded JSON value back to its original form, if set.
+	revive sobek.Callable
+}
+
+// RegisterCodec registers hooks converting the values of the keys starting
+// with the given prefix to and from their JSON-serializable form, so that
+// class instances come back as such, rather than as plain objects.
+//
+// The hooks are passed as { serialize(value), revive(raw) }, and both are
+// optional. When several prefixes match a key, the longest one's hooks are
+// used. Codecs are registered per VU, and are usually registered in the init
+// context.
+func (k *KV) RegisterCodec(prefix sobek.Value, hooks sobek.Value) {
+	rt := k.vu.Runtime()
+
+	if common.IsNullish(hooks) {
+		common.Throw(rt, errors.New("codec hooks are required"))
+		return
+	}
+
+	registered := codec{prefix: prefix.String()}
+	hooksObj := hooks.ToObject(rt)
+
+	for name, hook := range map[string]*sobek.Callable{"serialize": &registered.serialize, "revive": &registered.revive} {
+		value := hooksObj.Get(name)
+		if common.IsNullish(value) {
+			continue
+		}
+
+		fn, isFunction := sobek.AssertFunction(value)
+		if !isFunction {
+			common.Throw(rt, fmt.Errorf("codec %s: %s must be a function, got %v", prefix, name, value))
+			return
+		}
+
+		*hook = fn
+	}
+
+	for i, existing := range k.codecs {
+		if existing.prefix == registered.prefix {
+			k.codecs[i] = registered
+			return
+		}
+	}
+
+	k.codecs = append(k.codecs, registered)
+}
+
+// codecFor returns the codec registered for the longest prefix of the key, if any.
+func (k *KV) codecFor(key []byte) (codec, bool) {
+	var (
+		found codec
+		ok    bool
+	)
+
+	for _, c := range k.codecs {
+		if strings.HasPrefix(string(key), c.prefix) && (!ok || len(c.prefix) > len(found.prefix)) {
+			found, ok = c, true
+		}
+	}
+
+	return found, ok
+}
+
+// marshal encodes the value of the key to JSON, once converted by the
+// serialize hook registered for it, if any. In the binary serialization
+// mode, ArrayBuffer and typed array values are encoded as binary values.
+//
+// Values larger than the MaxValueSize option are rejected with a
+// ValueTooLargeError.
+//
+// It must be called from the event loop.
+func (k *KV) marshal(key []byte, value sobek.Value) ([]byte, error) {
+	if c, ok := k.codecFor(key); ok && c.serialize != nil {
+		serialized, err := c.serialize(sobek.Undefined(), value)
+		if err != nil {
+			return nil, err
+		}
+
+		value = serialized
+	}
+
+	var (
+		exported = value.Export()
+		encoded  []byte
+		err      error
+	)
+
+	if data, isBinary := binaryValue(exported); isBinary && k.options.Serialization == SerializationBinary {
+		encoded = encodeBinary(data)
+	} else if s, found := lookupSerializer(k.options.Serialization); found {
+		if encoded, err = s.Marshal(exported); err != nil {
+			return nil, err
+		}
+
+		encoded = encodeSerialized(k.options.Serialization, encoded)
+	} else if encoded, err = json.Marshal(exported); err != nil {
+		return nil, err
+	}
+
+	return encoded, k.validateValue(key, encoded)
+}
+
+// revive converts the decoded value of the key with the revive hook
+// registered for it, if any. In the binary serialization mode, binary
+// values are converted to ArrayBuffers.
+//
+// It must be called from the event loop if any codec is registered, or in
+// the binary serialization mode.
+func (k *KV) revive(key []byte, value any) (any, error) {
+	if data, isBinary := value.([]byte); isBinary && k.options.Serialization == SerializationBinary {
+		value = k.vu.Runtime().NewArrayBuffer(data)
+	}
+
+	c, ok := k.codecFor(key)
+	if !ok || c.revive == nil {
+		return value, nil
+	}
+
+	return c.revive(sobek.Undefined(), k.vu.Runtime().ToValue(value))
+}
+
+// reviveLater returns a function settling a promise, from any goroutine,
+// with the value returned by fn, run on the event loop if any codec is
+// registered, or in the binary serialization mode, so that it can revive
+// values.
+//
+// It must be called from the event loop, and the returned function must be
+// called exactly once.
+func (k *KV) reviveLater(resolve func(any), reject func(any)) func(fn func() (any, error)) {
+	settle := func(fn func() (any, error)) {
+		value, err := fn()
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(value)
+	}
+
+	if len(k.codecs) == 0 && k.options.Serialization != SerializationBinary {
+		return settle
+	}
+
+	callback := k.vu.RegisterCallback()
+
+	return func(fn func() (any, error)) {
+		callback(func() error {
+			settle(fn)
+			return nil
+		})
+	}
+}
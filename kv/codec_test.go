@@ -0,0 +1,24 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecFor(t *testing.T) {
+	t.Parallel()
+
+	kv := &KV{codecs: []codec{{prefix: "users:"}, {prefix: "users:admin:"}, {prefix: "orders:"}}}
+
+	found, ok := kv.codecFor([]byte("users:admin:1"))
+	assert.True(t, ok)
+	assert.Equal(t, "users:admin:", found.prefix)
+
+	found, ok = kv.codecFor([]byte("users:1"))
+	assert.True(t, ok)
+	assert.Equal(t, "users:", found.prefix)
+
+	_, ok = kv.codecFor([]byte("products:1"))
+	assert.False(t, ok)
+}
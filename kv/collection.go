@@ -0,0 +1,456 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// CollectionsBucket is the name of the internal bucket holding the
+// sequences used to generate the IDs of collections' documents.
+const CollectionsBucket = "k6/collections"
+
+// Collection is a handle on a set of JSON object documents, stored in the KV
+// store under keys made of the collection's name and the documents' IDs, such
+// as "users:42".
+//
+// Documents are validated against the collection's schema, if any, whenever
+// they are written.
+type Collection struct {
+	// name is the name of the collection.
+	name string
+
+	// schema maps the documents' fields to their expected types.
+	schema collectionSchema
+
+	// kv is the KV instance the collection is stored in.
+	kv *KV
+}
+
+// collectionSchema maps fields to their expected types: "string", "number",
+// "boolean", "array" or "object", suffixed with "?" when the field is optional.
+type collectionSchema map[string]string
+
+// Collection returns a handle on the named collection of documents.
+//
+// The options can hold a schema, mapping fields to their expected types,
+// which are one of "string", "number", "boolean", "array" or "object",
+// suffixed with a "?" when the field is optional:
+//
+//	kv.collection("users", { schema: { email: "string", age: "number?" } })
+func (k *KV) Collection(name sobek.Value, options sobek.Value) *sobek.Object {
+	rt := k.vu.Runtime()
+
+	if common.IsNullish(name) || name.String() == "" {
+		common.Throw(rt, NewError(KeyRequiredError, "collection name is required"))
+		return nil
+	}
+
+	collection := &Collection{name: name.String(), kv: k}
+
+	if !common.IsNullish(options) {
+		if schema := options.ToObject(rt).Get("schema"); !common.IsNullish(schema) {
+			collection.schema = make(collectionSchema)
+
+			schemaObj := schema.ToObject(rt)
+			for _, field := range schemaObj.Keys() {
+				fieldType := schemaObj.Get(field).String()
+				if !isSchemaType(strings.TrimSuffix(fieldType, "?")) {
+					common.Throw(rt, fmt.Errorf("collection %s: unsupported type %q for field %q", name, fieldType, field))
+					return nil
+				}
+
+				collection.schema[field] = fieldType
+			}
+		}
+	}
+
+	return rt.ToValue(collection).ToObject(rt)
+}
+
+// Insert validates and stores a new document, and resolves with it.
+//
+// The document's "id" field is used as its ID if set. Otherwise, a sequential
+// ID is generated and set on the stored document. Inserting a document whose
+// ID is already in use is rejected.
+func (c *Collection) Insert(document sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(c.kv.vu)
+
+	doc, err := c.importDocument(document)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var key []byte
+
+		err := c.kv.mutate(mutation{op: "collection.insert", key: []byte(c.name)}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(c.kv.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(c.kv.bucket)+" not found")
+			}
+
+			if _, hasID := doc["id"]; !hasID {
+				sequences, err := tx.CreateBucketIfNotExists([]byte(CollectionsBucket))
+				if err != nil {
+					return err
+				}
+
+				seq, err := sequences.CreateBucketIfNotExists([]byte(c.name))
+				if err != nil {
+					return err
+				}
+
+				id, err := seq.NextSequence()
+				if err != nil {
+					return err
+				}
+
+				doc["id"] = strconv.FormatUint(id, 10)
+			}
+
+			key = c.key(doc["id"])
+			if err := c.kv.validateKey(key); err != nil {
+				return err
+			}
+
+			if bucket.Get(key) != nil {
+				return NewError(KeyExistsError, "document "+string(key)+" already exists")
+			}
+
+			jsonValue, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+
+			return c.kv.storeValue(tx, bucket, key, jsonValue)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(doc)
+	}()
+
+	return promise
+}
+
+// Get resolves with the document with the given ID, or rejects
+// with a KeyNotFoundError if it does not exist.
+func (c *Collection) Get(id sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(c.kv.vu)
+
+	key := c.key(id.Export())
+
+	go func() {
+		var doc map[string]any
+
+		err := c.kv.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(c.kv.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(c.kv.bucket)+" not found")
+			}
+
+			jsonValue, err := loadValue(tx, bucket.Get(key))
+			if err != nil {
+				return err
+			}
+
+			if jsonValue == nil || newVisibility(tx, c.kv.bucket).hidden(key) {
+				return NewError(KeyNotFoundError, "document "+string(key)+" not found")
+			}
+
+			return json.Unmarshal(jsonValue, &doc)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(doc)
+	}()
+
+	return promise
+}
+
+// Update merges the given fields into the document with the given ID,
+// validates the result, stores it, and resolves with it.
+//
+// The update is applied atomically. It is rejected with a KeyNotFoundError
+// if the document does not exist.
+func (c *Collection) Update(id sobek.Value, fields sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(c.kv.vu)
+
+	key := c.key(id.Export())
+
+	patch, isObject := fields.Export().(map[string]any)
+	if !isObject {
+		reject(NewError(ValidationError, "the fields to update must be an object"))
+		return promise
+	}
+
+	go func() {
+		var doc map[string]any
+
+		err := c.kv.mutate(mutation{op: "collection.update", key: key}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(c.kv.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(c.kv.bucket)+" not found")
+			}
+
+			jsonValue, err := loadValue(tx, bucket.Get(key))
+			if err != nil {
+				return err
+			}
+
+			if jsonValue == nil || newVisibility(tx, c.kv.bucket).hidden(key) {
+				return NewError(KeyNotFoundError, "document "+string(key)+" not found")
+			}
+
+			if err := json.Unmarshal(jsonValue, &doc); err != nil {
+				return err
+			}
+
+			for field, value := range patch {
+				if field == "id" {
+					continue
+				}
+
+				doc[field] = value
+			}
+
+			// Round-trip the document through JSON, so that the patched
+			// fields are validated the way they are stored.
+			jsonValue, err = json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+
+			doc = nil
+			if err := json.Unmarshal(jsonValue, &doc); err != nil {
+				return err
+			}
+
+			if err := c.schema.validate(doc); err != nil {
+				return err
+			}
+
+			return c.kv.storeValue(tx, bucket, key, jsonValue)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(doc)
+	}()
+
+	return promise
+}
+
+// Remove deletes the document with the given ID.
+func (c *Collection) Remove(id sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(c.kv.vu)
+
+	key := c.key(id.Export())
+
+	go func() {
+		err := c.kv.mutate(mutation{op: "collection.remove", key: key}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(c.kv.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(c.kv.bucket)+" not found")
+			}
+
+			if err := undelay(tx, c.kv.bucket, key); err != nil {
+				return err
+			}
+
+			return c.kv.removeValue(tx, bucket, key)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(true)
+	}()
+
+	return promise
+}
+
+// Find resolves with the documents of the collection whose fields are equal
+// to all the fields of the given filter, ordered by key. Without a filter,
+// it resolves with all the documents of the collection.
+func (c *Collection) Find(filter sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(c.kv.vu)
+
+	var criteria map[string]any
+	if !common.IsNullish(filter) {
+		if err := normalizeJSON(filter.Export(), &criteria); err != nil {
+			reject(fmt.Errorf("invalid filter: %w", err))
+			return promise
+		}
+	}
+
+	prefix := []byte(c.name + ":")
+
+	go func() {
+		docs := make([]map[string]any, 0)
+
+		err := c.kv.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(c.kv.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(c.kv.bucket)+" not found")
+			}
+
+			visible := newVisibility(tx, c.kv.bucket)
+
+			cursor := bucket.Cursor()
+			for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+				if visible.hidden(k) {
+					continue
+				}
+
+				v, err := loadValue(tx, v)
+				if err != nil {
+					return err
+				}
+
+				var doc map[string]any
+				if err := json.Unmarshal(v, &doc); err != nil {
+					return err
+				}
+
+				if matches(doc, criteria) {
+					docs = append(docs, doc)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(docs)
+	}()
+
+	return promise
+}
+
+// key returns the key the document with the given ID is stored under.
+func (c *Collection) key(id any) []byte {
+	return []byte(c.name + ":" + fmt.Sprint(id))
+}
+
+// importDocument converts a document to its JSON-decoded form, and validates it.
+func (c *Collection) importDocument(document sobek.Value) (map[string]any, error) {
+	var doc map[string]any
+	if common.IsNullish(document) {
+		return nil, NewError(ValidationError, "document must be an object")
+	}
+
+	if err := normalizeJSON(document.Export(), &doc); err != nil || doc == nil {
+		return nil, NewError(ValidationError, "document must be a JSON-serializable object")
+	}
+
+	if err := c.schema.validate(doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// validate checks that the JSON-decoded document complies with the schema.
+func (s collectionSchema) validate(doc map[string]any) error {
+	fields := make([]string, 0, len(s))
+	for field := range s {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var violations []string
+	for _, field := range fields {
+		fieldType, optional := strings.CutSuffix(s[field], "?")
+
+		value, present := doc[field]
+		if !present || value == nil {
+			if !optional {
+				violations = append(violations, "field "+strconv.Quote(field)+" is required")
+			}
+
+			continue
+		}
+
+		if actual := schemaTypeOf(value); actual != fieldType {
+			violations = append(violations, "field "+strconv.Quote(field)+" must be a "+fieldType+", got a "+actual)
+		}
+	}
+
+	if len(violations) > 0 {
+		return NewError(ValidationError, strings.Join(violations, "; "))
+	}
+
+	return nil
+}
+
+// isSchemaType reports whether the type name is supported in collection schemas.
+func isSchemaType(name string) bool {
+	switch name {
+	case "string", "number", "boolean", "array", "object":
+		return true
+	default:
+		return false
+	}
+}
+
+// schemaTypeOf returns the schema type name of a JSON-decoded value.
+func schemaTypeOf(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+// matches reports whether each of the criteria's fields is equal to the document's.
+func matches(doc map[string]any, criteria map[string]any) bool {
+	for field, expected := range criteria {
+		if len(diffValues(field, expected, doc[field])) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeJSON round-trips a value exported from the JS runtime through
+// JSON, so that it takes the same form as the values decoded from the store.
+func normalizeJSON(value any, target any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, target)
+}
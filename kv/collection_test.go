@@ -0,0 +1,66 @@
+package kv
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionSchemaValidate(t *testing.T) {
+	t.Parallel()
+
+	schema := collectionSchema{"email": "string", "age": "number?", "tags": "array"}
+
+	t.Run("complying document", func(t *testing.T) {
+		t.Parallel()
+
+		doc := map[string]any{"email": "a@b.c", "tags": []any{"x"}, "extra": true}
+
+		assert.NoError(t, schema.validate(doc))
+	})
+
+	t.Run("optional field set to null", func(t *testing.T) {
+		t.Parallel()
+
+		doc := map[string]any{"email": "a@b.c", "age": nil, "tags": []any{}}
+
+		assert.NoError(t, schema.validate(doc))
+	})
+
+	t.Run("missing and mistyped fields", func(t *testing.T) {
+		t.Parallel()
+
+		err := schema.validate(map[string]any{"age": "42"})
+
+		var kvErr *Error
+		require.True(t, errors.As(err, &kvErr))
+		assert.Equal(t, ErrorName(ValidationError), kvErr.Name)
+		assert.Equal(
+			t,
+			`field "age" must be a number, got a string; field "email" is required; field "tags" is required`,
+			kvErr.Message,
+		)
+	})
+
+	t.Run("no schema", func(t *testing.T) {
+		t.Parallel()
+
+		var noSchema collectionSchema
+
+		assert.NoError(t, noSchema.validate(map[string]any{"anything": 1.0}))
+	})
+}
+
+func TestMatches(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]any{"name": "alice", "age": 42.0, "address": map[string]any{"city": "Paris"}}
+
+	assert.True(t, matches(doc, nil))
+	assert.True(t, matches(doc, map[string]any{"name": "alice", "age": 42.0}))
+	assert.True(t, matches(doc, map[string]any{"address": map[string]any{"city": "Paris"}}))
+	assert.False(t, matches(doc, map[string]any{"name": "bob"}))
+	assert.False(t, matches(doc, map[string]any{"missing": "field"}))
+}
@@ -0,0 +1,144 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/promises"
+)
+
+// compactTxMaxSize is the number of bytes copied within each transaction
+// when compacting the store, so that large stores are not copied within a
+// single, memory-hungry, transaction.
+const compactTxMaxSize = 64 << 20
+
+// Compact rewrites the store's file, without the free pages left behind by
+// deleted and overwritten values, and resolves with the number of bytes
+// reclaimed.
+//
+// The file only ever grows otherwise, as the pages freed by writes are
+// reused, but never returned to the file system. The store is unavailable
+// while it is compacted: the operations of other VUs wait for it to
+// complete.
+func (k *KV) Compact() *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	go func() {
+		reclaimed, err := k.db.compact()
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(reclaimed)
+	}()
+
+	return promise
+}
+
+// compact copies the store to a new file, which then replaces the store's
+// file, and the handle on it, and returns the number of bytes reclaimed.
+//
+//nolint:forbidigo
+func (db *db) compact() (int64, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.swap.Lock()
+	defer db.swap.Unlock()
+
+	if !db.opened.Load() {
+		return 0, NewError(DatabaseNotOpenError, "the store is closed")
+	}
+
+	if db.readOnly {
+		return 0, NewError(ReadOnlyError, "the store is opened in the "+ModeSharedReadOnly+" mode, and cannot be compacted")
+	}
+
+	db.writes.flush(db.handle)
+
+	before, err := os.Stat(db.path)
+	if err != nil {
+		return 0, err
+	}
+
+	compactedPath := db.path + ".compacted"
+	_ = os.Remove(compactedPath)
+
+	compacted, err := bolt.Open(compactedPath, 0o600, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	err = bolt.Compact(compacted, db.handle, compactTxMaxSize)
+	if closeErr := compacted.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(compactedPath)
+		return 0, fmt.Errorf("failed to compact store %s: %w", db.path, err)
+	}
+
+	// Stop the background tasks, which hold the current handle.
+	close(db.done)
+	db.done = nil
+
+	// The store's file is kept aside until the compacted file replaces it,
+	// so that it can be restored, and opened again, should that fail.
+	backupPath := db.path + ".uncompacted"
+	backedUp := false
+
+	restore := func(err error) (int64, error) {
+		_ = os.Remove(compactedPath)
+		if backedUp {
+			if renameErr := os.Rename(backupPath, db.path); renameErr != nil {
+				err = errors.Join(err, renameErr)
+			}
+		}
+
+		handle, openErr := openStore(db.path, db.options)
+		if openErr != nil {
+			// The store's operations fail from then on, as it is closed.
+			return 0, errors.Join(fmt.Errorf("failed to compact store %s: %w", db.path, err), openErr)
+		}
+
+		db.handle = handle
+		db.start()
+
+		return 0, fmt.Errorf("failed to compact store %s: %w", db.path, err)
+	}
+
+	if err := db.handle.Close(); err != nil {
+		return restore(err)
+	}
+
+	if err := os.Rename(db.path, backupPath); err != nil {
+		return restore(err)
+	}
+
+	backedUp = true
+
+	if err := os.Rename(compactedPath, db.path); err != nil {
+		return restore(err)
+	}
+
+	handle, err := openStore(db.path, db.options)
+	if err != nil {
+		return restore(err)
+	}
+
+	db.handle = handle
+	db.start()
+
+	_ = os.Remove(backupPath)
+
+	after, err := os.Stat(db.path)
+	if err != nil {
+		return 0, err
+	}
+
+	return before.Size() - after.Size(), nil
+}
@@ -0,0 +1,87 @@
+package kv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestDbCompact(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+	value := make([]byte, 1024)
+
+	// Churn leaves free pages behind
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		for i := 0; i < 5000; i++ {
+			if err := tx.Bucket(kv.bucket).Put([]byte(fmt.Sprintf("key%d", i)), value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		for i := 1; i < 5000; i++ {
+			if err := tx.Bucket(kv.bucket).Delete([]byte(fmt.Sprintf("key%d", i))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	reclaimed, err := dbInstance.compact()
+	require.NoError(t, err)
+	assert.Positive(t, reclaimed)
+
+	// The compacted store is used from then on
+	assert.NoError(t, kv.view(func(tx *bolt.Tx) error {
+		assert.Equal(t, value, tx.Bucket(kv.bucket).Get([]byte("key0")))
+		assert.Nil(t, tx.Bucket(kv.bucket).Get([]byte("key1")))
+		return nil
+	}))
+
+	assert.NoError(t, kv.mutate(mutation{op: "set", key: []byte("foo")}, func(tx *bolt.Tx) error {
+		return tx.Bucket(kv.bucket).Put([]byte("foo"), []byte(`"bar"`))
+	}))
+}
+
+//nolint:forbidigo
+func TestDbCompactFailure(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+
+	require.NoError(t, kv.mutate(mutation{op: "set", key: []byte("foo")}, func(tx *bolt.Tx) error {
+		return tx.Bucket(kv.bucket).Put([]byte("foo"), []byte(`"bar"`))
+	}))
+
+	// Keeping the store's file aside fails, once its handle is closed, as
+	// a directory is in the way.
+	require.NoError(t, os.MkdirAll(filepath.Join(dbInstance.path+".uncompacted", "in-the-way"), 0o700))
+
+	_, err := dbInstance.compact()
+	require.Error(t, err)
+
+	// The store's file is opened again, and used from then on
+	assert.NoError(t, kv.view(func(tx *bolt.Tx) error {
+		assert.Equal(t, []byte(`"bar"`), tx.Bucket(kv.bucket).Get([]byte("foo")))
+		return nil
+	}))
+
+	assert.NoError(t, kv.mutate(mutation{op: "set", key: []byte("baz")}, func(tx *bolt.Tx) error {
+		return tx.Bucket(kv.bucket).Put([]byte("baz"), []byte(`1`))
+	}))
+
+	assert.NoFileExists(t, dbInstance.path+".compacted")
+	assert.NotNil(t, dbInstance.done)
+}
@@ -0,0 +1,73 @@
+package kv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// CompressionGzip compresses the values written to the store with gzip.
+const CompressionGzip = "gzip"
+
+// gzipMagic prefixes gzip-compressed values. No JSON value starts with it,
+// which tells compressed values apart from the others when they are read,
+// whatever the compression option the store is opened with.
+const gzipMagic = "\x1f\x8b"
+
+// validateCompression checks that the compression option names a
+// supported algorithm.
+func validateCompression(compression string) error {
+	switch compression {
+	case "", CompressionGzip:
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression %q, only %q is supported", compression, CompressionGzip)
+	}
+}
+
+// compressValue compresses a JSON value with the given algorithm. The value
+// is returned as is when no algorithm is given, or when compressing it does
+// not make it smaller, as is the case for most small values.
+func compressValue(compression string, value []byte) ([]byte, error) {
+	if compression != CompressionGzip {
+		return value, nil
+	}
+
+	var compressed bytes.Buffer
+
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(value); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	if compressed.Len() >= len(value) {
+		return value, nil
+	}
+
+	return compressed.Bytes(), nil
+}
+
+// decompressValue returns the JSON value a stored value stands for,
+// decompressing it if it was compressed.
+func decompressValue(stored []byte) ([]byte, error) {
+	if !bytes.HasPrefix(stored, []byte(gzipMagic)) {
+		return stored, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress value: %w", err)
+	}
+
+	value, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress value: %w", err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,45 @@
+package kv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestValueCompression(t *testing.T) {
+	t.Parallel()
+
+	compressed := openTestKV(t, Options{Compression: CompressionGzip})
+	dbInstance := compressed.db
+	deduplicated := &KV{
+		bucket:  []byte(DefaultKvBucket),
+		db:      dbInstance,
+		options: Options{Compression: CompressionGzip, Deduplicate: true},
+	}
+	payload := []byte(`"` + strings.Repeat("<html></html>", 100) + `"`)
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(DefaultKvBucket))
+
+		require.NoError(t, compressed.storeValue(tx, bucket, []byte("large"), payload))
+		require.NoError(t, compressed.storeValue(tx, bucket, []byte("small"), []byte(`1`)))
+		require.NoError(t, deduplicated.storeValue(tx, bucket, []byte("shared"), payload))
+
+		assert.Less(t, len(bucket.Get([]byte("large"))), len(payload))
+		assert.Equal(t, []byte(`1`), bucket.Get([]byte("small")))
+		assert.True(t, isContentRef(bucket.Get([]byte("shared"))))
+
+		for _, key := range []string{"large", "shared"} {
+			value, err := loadValue(tx, bucket.Get([]byte(key)))
+			require.NoError(t, err)
+			assert.Equal(t, payload, value)
+		}
+
+		return nil
+	}))
+
+	assert.Error(t, validateCompression("lz4"))
+}
@@ -0,0 +1,101 @@
+package kv
+
+import (
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// SetIfAbsent atomically sets the value of a key in the store, only if it
+// does not exist, and resolves with whether it did.
+//
+// As only one of the VUs racing to set a key succeeds, it is suited to
+// electing a leader, or to deduplicating work.
+func (k *KV) SetIfAbsent(key sobek.Value, value sobek.Value, options sobek.Value) *sobek.Promise {
+	return k.setIfLater("setIfAbsent", key, value, options, false)
+}
+
+// SetIfPresent atomically sets the value of a key in the store, only if it
+// exists, and resolves with whether it did.
+func (k *KV) SetIfPresent(key sobek.Value, value sobek.Value, options sobek.Value) *sobek.Promise {
+	return k.setIfLater("setIfPresent", key, value, options, true)
+}
+
+// setIfLater sets the value of a key in the store, only if its existence is
+// the expected one, and resolves with whether it did.
+func (k *KV) setIfLater(op string, key, value, options sobek.Value, present bool) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonValue, err := k.marshal(keyBytes, value)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	setOptions, err := ImportSetOptions(k.vu.Runtime(), options)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	k.db.workers.Load().run(func() {
+		var set bool
+
+		err := k.mutate(mutation{op: op, key: keyBytes, value: jsonValue}, func(tx *bolt.Tx) error {
+			var err error
+			set, err = k.setIf(tx, keyBytes, jsonValue, setOptions, present)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(set)
+	})
+
+	return promise
+}
+
+// setIf sets the value of a key of the bucket, only if whether it exists
+// matches present, and reports whether it did.
+func (k *KV) setIf(tx *bolt.Tx, key, jsonValue []byte, setOptions SetOptions, present bool) (bool, error) {
+	bucket := tx.Bucket(k.bucket)
+	if bucket == nil {
+		return false, NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+	}
+
+	exists := bucket.Get(key) != nil && !newVisibility(tx, k.bucket).hidden(key)
+	if exists != present {
+		return false, nil
+	}
+
+	if err := undelay(tx, k.bucket, key); err != nil {
+		return false, err
+	}
+
+	if err := k.storeValue(tx, bucket, key, jsonValue); err != nil {
+		return false, err
+	}
+
+	if setOptions.TTL > 0 {
+		return true, k.expireKey(tx, key, time.Now().Add(setOptions.TTL))
+	}
+
+	return true, nil
+}
@@ -0,0 +1,46 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVSetIf(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+	key := []byte("leader")
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		set, err := kv.setIf(tx, key, []byte(`"vu-2"`), SetOptions{}, true)
+		require.NoError(t, err)
+		assert.False(t, set, "absent keys are not set if present")
+		assert.Nil(t, bucket.Get(key))
+
+		set, err = kv.setIf(tx, key, []byte(`"vu-1"`), SetOptions{}, false)
+		require.NoError(t, err)
+		assert.True(t, set)
+
+		set, err = kv.setIf(tx, key, []byte(`"vu-3"`), SetOptions{}, false)
+		require.NoError(t, err)
+		assert.False(t, set, "present keys are not set if absent")
+		assert.Equal(t, []byte(`"vu-1"`), bucket.Get(key))
+
+		set, err = kv.setIf(tx, key, []byte(`"vu-4"`), SetOptions{TTL: time.Hour}, true)
+		require.NoError(t, err)
+		assert.True(t, set)
+		assert.Equal(t, []byte(`"vu-4"`), bucket.Get(key))
+
+		_, expires := expiryOf(tx.Bucket(expiryBucket(kv.bucket)), key)
+		assert.True(t, expires)
+
+		return nil
+	}))
+}
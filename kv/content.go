@@ -0,0 +1,200 @@
+package kv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ContentBucket is the name of the internal bucket holding the values
+// stored once for all the keys they are set to, when the store is opened
+// with the Deduplicate option.
+//
+// Its entries are keyed by the SHA-256 hash of the value they hold, prefixed
+// by the 8-byte big-endian count of the keys referencing them.
+const ContentBucket = "k6/content"
+
+// contentRefMarker prefixes the values of keys referencing an entry of the
+// ContentBucket, followed by the entry's hash. No JSON value starts with it.
+const contentRefMarker = 0x00
+
+// contentRefSize is the size of a reference to an entry of the ContentBucket.
+const contentRefSize = 1 + sha256.Size
+
+// isContentRef reports whether a raw value is a reference to an entry of the ContentBucket.
+func isContentRef(raw []byte) bool {
+	return len(raw) == contentRefSize && raw[0] == contentRefMarker
+}
+
+// loadValue returns the value a raw value read from a bucket stands for,
+// resolving references to the ContentBucket, and decompressing it.
+//
+// The returned value is only valid for the life of the transaction.
+func loadValue(tx *bolt.Tx, raw []byte) ([]byte, error) {
+	if !isContentRef(raw) {
+		return decompressValue(raw)
+	}
+
+	content := tx.Bucket([]byte(ContentBucket))
+	if content == nil {
+		return nil, fmt.Errorf("value references missing content %x", raw[1:])
+	}
+
+	entry := content.Get(raw[1:])
+	if entry == nil {
+		return nil, fmt.Errorf("value references missing content %x", raw[1:])
+	}
+
+	return decompressValue(entry[8:])
+}
+
+// storeValue sets the value of a key of the bucket, releasing the value it
+// previously held, removing any TTL it had, and giving it a new version.
+//
+// When the store is opened with the Compression option, the value is
+// compressed first. When it is opened with the Deduplicate option, values
+// larger than a reference are stored once in the ContentBucket, and
+// referenced by the key.
+func (k *KV) storeValue(tx *bolt.Tx, bucket *bolt.Bucket, key, value []byte) error {
+	if err := unexpire(tx, k.bucket, key); err != nil {
+		return err
+	}
+
+	k.notifyChange(tx, key, value)
+	k.trackWrite(tx, value)
+	k.updateIndex(tx, key)
+
+	if err := k.reindex(tx, key, value); err != nil {
+		return err
+	}
+
+	if err := k.logOp(tx, OpLogSet, k.bucket, key, value); err != nil {
+		return err
+	}
+
+	value, err := compressValue(k.options.Compression, value)
+	if err != nil {
+		return err
+	}
+
+	previous := bucket.Get(key)
+	if previous == nil {
+		k.trackChurn(tx, key, churnCreated, 1)
+	} else {
+		k.trackChurn(tx, key, churnOverwritten, 1)
+	}
+
+	if err := releaseValue(tx, previous); err != nil {
+		return err
+	}
+
+	if _, err := bumpVersion(tx, k.bucket, key); err != nil {
+		return err
+	}
+
+	if !k.options.Deduplicate || len(value) <= contentRefSize {
+		return bucket.Put(key, value)
+	}
+
+	content, err := tx.CreateBucketIfNotExists([]byte(ContentBucket))
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(value)
+
+	var refs uint64
+	if entry := content.Get(hash[:]); entry != nil {
+		refs = binary.BigEndian.Uint64(entry)
+	}
+
+	entry := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(entry, refs+1)
+	copy(entry[8:], value)
+
+	if err := content.Put(hash[:], entry); err != nil {
+		return err
+	}
+
+	return bucket.Put(key, append([]byte{contentRefMarker}, hash[:]...))
+}
+
+// removeValue deletes a key of the bucket, releasing the value it held.
+func (k *KV) removeValue(tx *bolt.Tx, bucket *bolt.Bucket, key []byte) error {
+	if err := unexpire(tx, k.bucket, key); err != nil {
+		return err
+	}
+
+	previous := bucket.Get(key)
+	if previous == nil {
+		return nil
+	}
+
+	k.trackChurn(tx, key, churnDeleted, 1)
+	k.notifyChange(tx, key, nil)
+	k.updateIndex(tx, key)
+
+	if err := k.reindex(tx, key, nil); err != nil {
+		return err
+	}
+
+	if err := k.logOp(tx, OpLogDelete, k.bucket, key, nil); err != nil {
+		return err
+	}
+
+	if err := releaseValue(tx, previous); err != nil {
+		return err
+	}
+
+	if err := dropVersion(tx, k.bucket, key); err != nil {
+		return err
+	}
+
+	return bucket.Delete(key)
+}
+
+// releaseValues releases the values held by all the keys of the bucket,
+// before it is dropped.
+func releaseValues(tx *bolt.Tx, bucket *bolt.Bucket) error {
+	if tx.Bucket([]byte(ContentBucket)) == nil {
+		return nil
+	}
+
+	return bucket.ForEach(func(_, v []byte) error {
+		return releaseValue(tx, v)
+	})
+}
+
+// releaseValue drops a reference to an entry of the ContentBucket, deleting
+// the entry once it is no longer referenced. Raw values which are not
+// references are ignored.
+func releaseValue(tx *bolt.Tx, raw []byte) error {
+	if !isContentRef(raw) {
+		return nil
+	}
+
+	content := tx.Bucket([]byte(ContentBucket))
+	if content == nil {
+		return nil
+	}
+
+	hash := bytes.Clone(raw[1:])
+
+	entry := content.Get(hash)
+	if entry == nil {
+		return nil
+	}
+
+	refs := binary.BigEndian.Uint64(entry)
+	if refs <= 1 {
+		return content.Delete(hash)
+	}
+
+	updated := bytes.Clone(entry)
+	binary.BigEndian.PutUint64(updated, refs-1)
+
+	return content.Put(hash, updated)
+}
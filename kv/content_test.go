@@ -0,0 +1,81 @@
+package kv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestContentDeduplication(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{Deduplicate: true})
+	dbInstance := kv.db
+	payload := []byte(`"` + strings.Repeat("x", 100) + `"`)
+
+	contentEntries := func(tx *bolt.Tx) int {
+		content := tx.Bucket([]byte(ContentBucket))
+		if content == nil {
+			return 0
+		}
+
+		entries := 0
+		_ = content.ForEach(func(_, _ []byte) error {
+			entries++
+			return nil
+		})
+
+		return entries
+	}
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("a"), payload))
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("b"), payload))
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("small"), []byte(`1`)))
+
+		assert.True(t, isContentRef(bucket.Get([]byte("a"))))
+		assert.Equal(t, []byte(`1`), bucket.Get([]byte("small")))
+		assert.Equal(t, 1, contentEntries(tx))
+
+		for _, key := range []string{"a", "b", "small"} {
+			value, err := loadValue(tx, bucket.Get([]byte(key)))
+			require.NoError(t, err)
+			assert.NotNil(t, value)
+		}
+
+		value, err := loadValue(tx, bucket.Get([]byte("b")))
+		require.NoError(t, err)
+		assert.Equal(t, payload, value)
+
+		return nil
+	}))
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		// Overwriting and deleting keys releases the content they referenced.
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("a"), []byte(`2`)))
+		assert.Equal(t, 1, contentEntries(tx))
+
+		require.NoError(t, kv.removeValue(tx, bucket, []byte("b")))
+		assert.Equal(t, 0, contentEntries(tx))
+
+		return nil
+	}))
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("c"), payload))
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("d"), payload))
+		require.NoError(t, releaseValues(tx, bucket))
+		assert.Equal(t, 0, contentEntries(tx))
+
+		return nil
+	}))
+}
@@ -0,0 +1,130 @@
+package kv
+
+import (
+	"bytes"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// Copy atomically copies the value of a key to another key, along with its
+// TTL, if any, and resolves with true, or with false if the destination
+// exists and the overwrite option is not set. It rejects with a
+// KeyNotFoundError if the source does not exist.
+func (k *KV) Copy(src sobek.Value, dst sobek.Value, options sobek.Value) *sobek.Promise {
+	overwrite := false
+	if !common.IsNullish(options) {
+		overwrite = options.ToObject(k.vu.Runtime()).Get("overwrite").ToBoolean()
+	}
+
+	return k.copyLater("copy", src, dst, overwrite, false)
+}
+
+// Rename atomically moves the value of a key, along with its TTL, if any,
+// to another key, overwriting it, and resolves with true. It rejects with a
+// KeyNotFoundError if the source does not exist.
+func (k *KV) Rename(src sobek.Value, dst sobek.Value) *sobek.Promise {
+	return k.copyLater("rename", src, dst, true, true)
+}
+
+// copyLater copies the value of the src key to the dst key, deleting the
+// src key if move is true, and resolves with whether it was copied.
+func (k *KV) copyLater(op string, src, dst sobek.Value, overwrite, move bool) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	srcBytes, err := common.ToBytes(src.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	dstBytes, err := common.ToBytes(dst.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(dstBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var copied bool
+
+		ms := []mutation{{op: op, key: dstBytes}}
+		if move {
+			ms = append(ms, mutation{op: op, key: srcBytes})
+		}
+
+		err := k.mutateMany(ms, func(tx *bolt.Tx) error {
+			var err error
+			if copied, err = k.copyKey(tx, srcBytes, dstBytes, overwrite); err != nil || !copied || !move {
+				return err
+			}
+
+			if bytes.Equal(srcBytes, dstBytes) {
+				return nil
+			}
+
+			return k.removeValue(tx, tx.Bucket(k.bucket), srcBytes)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(copied)
+	}()
+
+	return promise
+}
+
+// copyKey copies the value of the src key of the bucket, and its TTL, to
+// the dst key, unless it exists and overwrite is false, and reports whether
+// it did.
+func (k *KV) copyKey(tx *bolt.Tx, src, dst []byte, overwrite bool) (bool, error) {
+	bucket := tx.Bucket(k.bucket)
+	if bucket == nil {
+		return false, NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+	}
+
+	visible := newVisibility(tx, k.bucket)
+
+	value, err := loadValue(tx, bucket.Get(src))
+	if err != nil {
+		return false, err
+	}
+
+	if value == nil || visible.hidden(src) {
+		return false, NewError(KeyNotFoundError, "key "+string(src)+" not found")
+	}
+
+	if bytes.Equal(src, dst) {
+		return true, nil
+	}
+
+	if !overwrite && bucket.Get(dst) != nil && !visible.hidden(dst) {
+		return false, nil
+	}
+
+	deadline, expires := expiryOf(visible.expiry, src)
+
+	if err := undelay(tx, k.bucket, dst); err != nil {
+		return false, err
+	}
+
+	// Copy the value, as the memory it points to is only valid
+	// until the transaction writes to the bucket.
+	if err := k.storeValue(tx, bucket, dst, bytes.Clone(value)); err != nil {
+		return false, err
+	}
+
+	if expires {
+		return true, k.expireKey(tx, dst, deadline)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,51 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVCopyKey(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+	staging, active := []byte("staging:token"), []byte("active:token")
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		_, err := kv.copyKey(tx, staging, active, false)
+
+		var kvErr *Error
+		require.ErrorAs(t, err, &kvErr)
+		assert.Equal(t, ErrorName(KeyNotFoundError), kvErr.Name)
+
+		require.NoError(t, kv.storeValue(tx, bucket, staging, []byte(`"new"`)))
+		require.NoError(t, expire(tx, kv.bucket, staging, time.Hour))
+		require.NoError(t, kv.storeValue(tx, bucket, active, []byte(`"old"`)))
+
+		// Existing keys are kept, unless overwritten
+		copied, err := kv.copyKey(tx, staging, active, false)
+		require.NoError(t, err)
+		assert.False(t, copied)
+		assert.Equal(t, []byte(`"old"`), bucket.Get(active))
+
+		copied, err = kv.copyKey(tx, staging, active, true)
+		require.NoError(t, err)
+		assert.True(t, copied)
+		assert.Equal(t, []byte(`"new"`), bucket.Get(active))
+		assert.Equal(t, []byte(`"new"`), bucket.Get(staging))
+
+		// The TTL is copied along
+		deadline, expires := expiryOf(tx.Bucket(expiryBucket(kv.bucket)), active)
+		assert.True(t, expires)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+
+		return nil
+	}))
+}
@@ -1,6 +1,8 @@
 package kv
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -16,24 +18,83 @@ type db struct {
 	opened   atomic.Bool
 	refCount atomic.Int64
 	lock     sync.Mutex
+
+	// mutations holds the mutations recorded by KV instances in dry-run mode.
+	mutations mutationLog
+
+	// done is closed when the store is closed, to stop purging expired keys.
+	done chan struct{}
+
+	// readOnly is true when the store was opened in the sharedReadOnly mode.
+	readOnly bool
+
+	// limiter limits the number of operations all VUs run concurrently.
+	limiter *opLimiter
+
+	// exportOnClose is the path of the file the entries of exportBucket
+	// are written to when the store is closed, if any.
+	exportOnClose string
+	exportBucket  []byte
+
+	// watchers are the watchers registered with KV.Watch.
+	watchers watcherRegistry
+
+	// writes holds the writes buffered until the next flush, when the store
+	// is opened with the FlushInterval option, and is nil otherwise.
+	writes *writeBuffer
+
+	// writers is the number of read-write transactions in flight.
+	writers atomic.Int64
+
+	// keyLocks are the locks KV.Update takes on keys.
+	keyLocks keyLocks
+
+	// index holds the keys of the store's buckets in memory, when the store
+	// is opened with the KeyIndex option, and is nil otherwise.
+	index *keyIndex
+
+	// workers runs the operations of the KV instances, when the store is
+	// opened with the Workers option, and is nil otherwise. It is read from
+	// the event loop, without holding swap, while the store is closed.
+	workers atomic.Pointer[workerPool]
+
+	// stats counts the operations run on the store by all KV instances.
+	stats statsLog
+
+	// churn counts the changes made to the keys of the store by all KV instances.
+	churn churnLog
+
+	// opLog records the operations applied to the store, when it is opened
+	// with the OpLog option, and is nil otherwise.
+	opLog *opLog
+
+	// options are the options the store was opened with.
+	options Options
+
+	// swap is held for reading by the operations using the handle, and for
+	// writing while KV.Compact replaces it.
+	swap sync.RWMutex
 }
 
 // newDB returns a new db instance.
 func newDB() *db {
 	return &db{
-		path:     DefaultKvPath,
-		handle:   new(bolt.DB),
-		opened:   atomic.Bool{},
-		refCount: atomic.Int64{},
-		lock:     sync.Mutex{},
+		path:      DefaultKvPath,
+		handle:    new(bolt.DB),
+		opened:    atomic.Bool{},
+		refCount:  atomic.Int64{},
+		lock:      sync.Mutex{},
+		mutations: mutationLog{},
+		churn:     churnLog{},
 	}
 }
 
 // open opens the database if it is not already open.
 //
 // It is safe to call this method multiple times.
-// The database will only be opened once.
-func (db *db) open() error {
+// The database will only be opened once, and only the options
+// passed to the first call are taken into account.
+func (db *db) open(options Options) error {
 	if db.opened.Load() {
 		db.refCount.Add(1)
 		return nil
@@ -46,39 +107,217 @@ func (db *db) open() error {
 		return nil
 	}
 
-	handler, err := bolt.Open(db.path, 0o600, nil)
+	handler, err := openStore(db.path, options)
 	if err != nil {
 		return err
 	}
 
-	err = handler.Update(func(tx *bolt.Tx) error {
+	if options.Mode == ModeSharedReadOnly {
+		err = handler.View(checkFormat)
+	} else {
+		err = handler.Update(prepare(options))
+	}
+	if err != nil {
+		// Release the file lock, so that the store can be opened again.
+		_ = handler.Close()
+		return err
+	}
+
+	db.handle = handler
+	db.readOnly = options.Mode == ModeSharedReadOnly
+
+	if options.Seed != "" {
+		if err := db.seed(options); err != nil {
+			_ = handler.Close()
+			db.handle = nil
+			return err
+		}
+	}
+
+	if !db.readOnly {
+		if err := handler.Update(buildIndexes(options.Indexes)); err != nil {
+			_ = handler.Close()
+			db.handle = nil
+			return err
+		}
+	}
+
+	if options.OpLog != "" {
+		if db.opLog, err = openOpLog(options.OpLog); err != nil {
+			_ = handler.Close()
+			db.handle = nil
+			return err
+		}
+	}
+
+	db.options = options
+	db.start()
+
+	db.limiter = newOpLimiter(options.MaxInFlightOps, options.MaxQueuedOps)
+	db.workers.Store(newWorkerPool(options.Workers))
+	if options.KeyIndex {
+		db.index = newKeyIndex()
+	}
+	db.exportOnClose = options.ExportOnClose
+	db.exportBucket = []byte(options.Bucket)
+	if options.Bucket == "" {
+		db.exportBucket = []byte(DefaultKvBucket)
+	}
+	db.opened.Store(true)
+	db.refCount.Add(1)
+
+	return nil
+}
+
+// start starts the background tasks of a store opened in read-write mode,
+// which run until done is closed.
+func (db *db) start() {
+	if db.readOnly {
+		return
+	}
+
+	db.done = make(chan struct{})
+	go db.sweepExpired(db.handle, db.done)
+
+	if db.options.SnapshotInterval > 0 {
+		go db.snapshotEvery(db.handle, db.done)
+	}
+
+	if db.options.FlushInterval > 0 {
+		if db.writes == nil {
+			db.writes = &writeBuffer{}
+		}
+
+		go db.writes.run(db.handle, db.options.FlushInterval, db.done)
+	}
+}
+
+// update runs fn within a read-write transaction.
+//
+// When batch is true, and other writes are in flight, fn is batched with
+// the concurrent writes, within a shared transaction, rather than waiting
+// for each of their transactions to commit. fn is then run once more, on
+// its own, should one of the writes it is batched with fail. A lone write
+// is never batched, as batching delays it.
+func (db *db) update(fn func(tx *bolt.Tx) error, batch bool) error {
+	defer db.writers.Add(-1)
+	if db.writers.Add(1) > 1 && batch {
+		return db.handle.Batch(fn)
+	}
+
+	return db.handle.Update(fn)
+}
+
+// prepare returns a function preparing the store for use by a test run,
+// within a read-write transaction.
+func prepare(options Options) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
 		_, bucketErr := tx.CreateBucketIfNotExists([]byte(DefaultKvBucket))
 		if bucketErr != nil {
 			return fmt.Errorf("failed to create internal bucket: %w", bucketErr)
 		}
 
+		if formatErr := ensureFormat(tx); formatErr != nil {
+			return formatErr
+		}
+
+		if options.ClearOnStart {
+			if err := clearStore(tx, options.Resume); err != nil {
+				return fmt.Errorf("failed to clear the store: %w", err)
+			}
+		}
+
+		// Unless resuming, start over from the state left by previous runs.
+		if !options.Resume {
+			for _, name := range runBuckets {
+				if tx.Bucket([]byte(name)) == nil {
+					continue
+				}
+
+				if bucketErr := tx.DeleteBucket([]byte(name)); bucketErr != nil {
+					return fmt.Errorf("failed to reset %s bucket: %w", name, bucketErr)
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+// runBuckets are the internal buckets holding the state of a test run,
+// such as its progress, which are reset when the store is opened, unless
+// resuming.
+var runBuckets = []string{ProgressBucket, OnceBucket, MemoizeBucket, BarriersBucket, LatchesBucket}
+
+// clearStore deletes every bucket of the store, along with their keys, but
+// the metadata bucket, and the progress bucket when resuming, and recreates
+// the default bucket.
+func clearStore(tx *bolt.Tx, resume bool) error {
+	var names [][]byte
+	err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		if string(name) == MetaBucket || (resume && string(name) == ProgressBucket) {
+			return nil
+		}
+
+		names = append(names, bytes.Clone(name))
+
 		return nil
 	})
 	if err != nil {
 		return err
 	}
 
-	db.handle = handler
-	db.opened.Store(true)
-	db.refCount.Add(1)
+	for _, name := range names {
+		if err := tx.DeleteBucket(name); err != nil {
+			return err
+		}
+	}
 
-	return nil
+	_, err = tx.CreateBucket([]byte(DefaultKvBucket))
+
+	return err
 }
 
 // close closes the database if there are no more references to it.
 func (db *db) close() error {
 	if db.refCount.Add(-1) == 0 {
+		if db.done != nil {
+			close(db.done)
+			db.done = nil
+		}
+
+		// Wait for the operations in flight, which hold swap, to complete,
+		// and keep new ones from buffering writes, or logging operations,
+		// once they are flushed.
+		db.swap.Lock()
+		defer db.swap.Unlock()
+
+		db.writes.flush(db.handle)
+		flushErr := db.writes.errors()
+		db.writes = nil
+
+		var exportErr error
+		if db.exportOnClose != "" {
+			exportErr = db.handle.View(func(tx *bolt.Tx) error {
+				_, err := exportEntries(tx, db.exportBucket, nil, db.exportOnClose)
+				return err
+			})
+		}
+
+		opLogErr := db.opLog.close()
+		db.opLog = nil
+
 		if err := db.handle.Close(); err != nil {
 			return err
 		}
 
 		db.handle = nil
+		db.limiter = nil
+		db.workers.Swap(nil).stop()
+		db.index = nil
 		db.opened.Store(false)
+
+		return errors.Join(flushErr, exportErr, opLogErr)
 	}
 
 	return nil
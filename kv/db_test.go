@@ -3,8 +3,8 @@ package kv
 import (
 	"fmt"
 	"math/rand"
-	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,17 +12,10 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
-//nolint:forbidigo
 func TestDbOpen(t *testing.T) {
 	t.Parallel()
 
-	// Create a temporary directory for the database
-	tmpDir, err := os.MkdirTemp("", "kvtest")
-	require.NoError(t, err)
-	t.Cleanup(func() {
-		removeErr := os.RemoveAll(tmpDir)
-		require.NoError(t, removeErr)
-	})
+	tmpDir := t.TempDir()
 
 	t.Run("calling open on a new db instance successfully opens the database", func(t *testing.T) {
 		t.Parallel()
@@ -33,7 +26,7 @@ func TestDbOpen(t *testing.T) {
 		dbInstance.path = filepath.Join(tmpDir, randomFileName("test.", ".db"))
 
 		// Open the database
-		gotErr := dbInstance.open()
+		gotErr := dbInstance.open(Options{})
 		t.Cleanup(func() {
 			require.NoError(t, gotErr)
 			require.NoError(t, dbInstance.close())
@@ -54,12 +47,12 @@ func TestDbOpen(t *testing.T) {
 		dbInstance.path = filepath.Join(tmpDir, randomFileName("test.", ".db"))
 
 		// Pre-open the database
-		require.NoError(t, dbInstance.open())
+		require.NoError(t, dbInstance.open(Options{}))
 		t.Cleanup(func() {
 			require.NoError(t, dbInstance.close())
 		})
 
-		gotErr := dbInstance.open()
+		gotErr := dbInstance.open(Options{})
 		t.Cleanup(func() {
 			require.NoError(t, gotErr)
 			require.NoError(t, dbInstance.close())
@@ -80,7 +73,7 @@ func TestDbOpen(t *testing.T) {
 		dbInstance.path = filepath.Join(tmpDir, randomFileName("test.", ".db"))
 
 		// Open the database
-		require.NoError(t, dbInstance.open())
+		require.NoError(t, dbInstance.open(Options{}))
 		t.Cleanup(func() {
 			require.NoError(t, dbInstance.close())
 		})
@@ -92,18 +85,142 @@ func TestDbOpen(t *testing.T) {
 			return nil
 		}))
 	})
+
+	t.Run("opening a store in the sharedReadOnly mode rejects writes", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, randomFileName("test.", ".db"))
+
+		// Prepare the dataset
+		prepared := newDB()
+		prepared.path = path
+		require.NoError(t, prepared.open(Options{}))
+		require.NoError(t, prepared.handle.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte(DefaultKvBucket)).Put([]byte("foo"), []byte(`"bar"`))
+		}))
+		require.NoError(t, prepared.close())
+
+		dbInstance := newDB()
+		dbInstance.path = path
+		require.NoError(t, dbInstance.open(Options{Mode: ModeSharedReadOnly}))
+		t.Cleanup(func() {
+			require.NoError(t, dbInstance.close())
+		})
+
+		assert.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			assert.Equal(t, []byte(`"bar"`), tx.Bucket([]byte(DefaultKvBucket)).Get([]byte("foo")))
+			return nil
+		}))
+
+		kv := &KV{bucket: []byte(DefaultKvBucket), db: dbInstance}
+		err := kv.mutate(mutation{op: "delete", key: []byte("foo")}, func(tx *bolt.Tx) error {
+			return tx.Bucket(kv.bucket).Delete([]byte("foo"))
+		})
+
+		var kvErr *Error
+		require.ErrorAs(t, err, &kvErr)
+		assert.Equal(t, ErrorName(ReadOnlyError), kvErr.Name)
+	})
+
+	t.Run("opening a store with clearOnStart deletes the data of previous runs", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, randomFileName("test.", ".db"))
+
+		// Leave data behind, as a previous run would
+		previous := newDB()
+		previous.path = path
+		require.NoError(t, previous.open(Options{}))
+		require.NoError(t, previous.handle.Update(func(tx *bolt.Tx) error {
+			for _, name := range []string{DefaultKvBucket, "orders", ProgressBucket} {
+				bucket, err := tx.CreateBucketIfNotExists([]byte(name))
+				if err != nil {
+					return err
+				}
+
+				if err := bucket.Put([]byte("foo"), []byte(`"bar"`)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}))
+		require.NoError(t, previous.close())
+
+		dbInstance := newDB()
+		dbInstance.path = path
+		require.NoError(t, dbInstance.open(Options{ClearOnStart: true, Resume: true}))
+		t.Cleanup(func() {
+			require.NoError(t, dbInstance.close())
+		})
+
+		assert.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			assert.Zero(t, tx.Bucket([]byte(DefaultKvBucket)).Stats().KeyN)
+			assert.Nil(t, tx.Bucket([]byte("orders")))
+			assert.NotNil(t, tx.Bucket([]byte(ProgressBucket)).Get([]byte("foo")))
+			assert.NotNil(t, tx.Bucket([]byte(MetaBucket)))
+			return nil
+		}))
+	})
+
+	t.Run("opening a store resets the state of previous runs, unless resuming", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, randomFileName("test.", ".db"))
+
+		// Leave state behind, as a previous run would
+		previous := newDB()
+		previous.path = path
+		require.NoError(t, previous.open(Options{}))
+		require.NoError(t, previous.handle.Update(func(tx *bolt.Tx) error {
+			for _, name := range runBuckets {
+				bucket, err := tx.CreateBucketIfNotExists([]byte(name))
+				if err != nil {
+					return err
+				}
+
+				if err := bucket.Put([]byte("foo"), []byte(`"bar"`)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}))
+		require.NoError(t, previous.close())
+
+		resumed := newDB()
+		resumed.path = path
+		require.NoError(t, resumed.open(Options{Resume: true}))
+		assert.NoError(t, resumed.handle.View(func(tx *bolt.Tx) error {
+			for _, name := range runBuckets {
+				assert.NotNil(t, tx.Bucket([]byte(name)), name)
+			}
+
+			return nil
+		}))
+		require.NoError(t, resumed.close())
+
+		dbInstance := newDB()
+		dbInstance.path = path
+		require.NoError(t, dbInstance.open(Options{}))
+		t.Cleanup(func() {
+			require.NoError(t, dbInstance.close())
+		})
+
+		assert.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			for _, name := range runBuckets {
+				assert.Nil(t, tx.Bucket([]byte(name)), name)
+			}
+
+			return nil
+		}))
+	})
 }
 
-//nolint:forbidigo
 func TestDbClose(t *testing.T) {
 	t.Parallel()
 
-	// Create a temporary directory for the database
-	tmpDir, err := os.MkdirTemp("", "kvtest")
-	require.NoError(t, err)
-	t.Cleanup(func() {
-		require.NoError(t, os.RemoveAll(tmpDir))
-	})
+	tmpDir := t.TempDir()
 
 	t.Run("1", func(t *testing.T) {
 		t.Parallel()
@@ -111,7 +228,7 @@ func TestDbClose(t *testing.T) {
 		// Initialize a new db instance and open it
 		dbInstance := newDB()
 		dbInstance.path = filepath.Join(tmpDir, randomFileName("test.", ".db"))
-		require.NoError(t, dbInstance.open())
+		require.NoError(t, dbInstance.open(Options{}))
 
 		gotErr := dbInstance.close()
 
@@ -129,8 +246,8 @@ func TestDbClose(t *testing.T) {
 		dbInstance.path = filepath.Join(tmpDir, randomFileName("test.", ".db"))
 
 		// Pre-open the database twice, so the ref count is 2
-		require.NoError(t, dbInstance.open())
-		require.NoError(t, dbInstance.open())
+		require.NoError(t, dbInstance.open(Options{}))
+		require.NoError(t, dbInstance.open(Options{}))
 
 		gotErr := dbInstance.close()
 
@@ -149,3 +266,31 @@ func TestDbClose(t *testing.T) {
 func randomFileName(prefix, suffix string) string {
 	return prefix + fmt.Sprint(rand.Intn(100)) + suffix //nolint:gosec
 }
+
+func TestDbUpdate(t *testing.T) {
+	t.Parallel()
+
+	dbInstance := openTestDB(t, Options{})
+	const writers = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		key := []byte(fmt.Sprintf("key%d", i))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, dbInstance.update(func(tx *bolt.Tx) error {
+				return tx.Bucket([]byte(DefaultKvBucket)).Put(key, []byte(`"bar"`))
+			}, true))
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(t, dbInstance.writers.Load())
+	assert.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, writers, tx.Bucket([]byte(DefaultKvBucket)).Stats().KeyN)
+		return nil
+	}))
+}
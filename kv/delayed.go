@@ -0,0 +1,131 @@
+package kv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// SetDelayed sets the value of a key in the store, but only makes it visible
+// once the given delay has elapsed.
+//
+// The delay is either a number of milliseconds, or a duration string such as "30s".
+// Until then, the key is treated as absent by Get, List and Size. Setting or
+// deleting the key in the meantime cancels the delay.
+func (k *KV) SetDelayed(key sobek.Value, value sobek.Value, delay sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonValue, err := k.marshal(keyBytes, value)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	visibilityDelay, err := toDuration(delay)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if visibilityDelay < 0 {
+		reject(fmt.Errorf("delay must not be negative, got %s", visibilityDelay))
+		return promise
+	}
+
+	go func() {
+		deadline := make([]byte, 8)
+		binary.BigEndian.PutUint64(deadline, uint64(time.Now().Add(visibilityDelay).UnixNano()))
+
+		err := k.mutate(mutation{op: "setDelayed", key: keyBytes, value: jsonValue}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			delayed, err := tx.CreateBucketIfNotExists(delayedBucket(k.bucket))
+			if err != nil {
+				return err
+			}
+
+			if err := delayed.Put(keyBytes, deadline); err != nil {
+				return err
+			}
+
+			return k.storeValue(tx, bucket, keyBytes, jsonValue)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(value)
+	}()
+
+	return promise
+}
+
+// delayedBucket returns the name of the internal bucket holding the
+// visibility deadlines of the delayed keys of the given bucket.
+func delayedBucket(bucket []byte) []byte {
+	return []byte(string(bucket) + "/delayed")
+}
+
+// isPending reports whether the key is delayed, and not visible yet.
+//
+// The delayed bucket is nil when no delayed key was ever set.
+func isPending(delayed *bolt.Bucket, key []byte, now time.Time) bool {
+	if delayed == nil {
+		return false
+	}
+
+	deadline := delayed.Get(key)
+	if len(deadline) != 8 {
+		return false
+	}
+
+	return now.UnixNano() < int64(binary.BigEndian.Uint64(deadline))
+}
+
+// countPending returns the number of delayed keys which are not visible yet.
+func countPending(delayed *bolt.Bucket, now time.Time) int64 {
+	if delayed == nil {
+		return 0
+	}
+
+	var pending int64
+	_ = delayed.ForEach(func(k, _ []byte) error {
+		if isPending(delayed, k, now) {
+			pending++
+		}
+
+		return nil
+	})
+
+	return pending
+}
+
+// undelay removes any visibility delay set on the key.
+func undelay(tx *bolt.Tx, bucket []byte, key []byte) error {
+	delayed := tx.Bucket(delayedBucket(bucket))
+	if delayed == nil {
+		return nil
+	}
+
+	return delayed.Delete(key)
+}
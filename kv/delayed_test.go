@@ -0,0 +1,103 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVSetDelayed(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keys are hidden until their delay elapses", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			const visible = () => Promise.all([
+				store.exists("job"),
+				store.list(),
+				store.size(),
+			]);
+
+			store.set("other", 1)
+				.then(() => store.setDelayed("job", "pending", 50))
+				.then(() => store.get("job").then(
+					() => { throw new Error("expected the key to be hidden from get"); },
+					(err) => {
+						if (String(err.name) !== "KeyNotFoundError") {
+							throw err;
+						}
+					},
+				))
+				.then(() => visible())
+				.then(([exists, entries, size]) => {
+					if (exists || entries.length !== 1 || size !== 1) {
+						throw new Error("expected the key to be hidden, got " + JSON.stringify({ exists, entries, size }));
+					}
+
+					const until = Date.now() + 60;
+					while (Date.now() < until) {}
+
+					return store.get("job");
+				})
+				.then((value) => {
+					if (value !== "pending") {
+						throw new Error("expected the value once visible, got " + value);
+					}
+
+					return visible();
+				})
+				.then(([exists, entries, size]) => {
+					if (!exists || entries.length !== 2 || size !== 2) {
+						throw new Error("expected the key to be visible, got " + JSON.stringify({ exists, entries, size }));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("setting the key cancels the delay", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.setDelayed("job", "pending", "1h")
+				.then(() => store.set("job", "done"))
+				.then(() => store.get("job"))
+				.then((value) => {
+					if (value !== "done") {
+						throw new Error("expected the value to be visible, got " + value);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("negative delays are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.setDelayed("job", "pending", -1)
+				.then(
+					() => { throw new Error("expected setDelayed to reject"); },
+					(err) => {
+						if (!String(err).includes("delay must not be negative")) {
+							throw new Error("expected the delay to be rejected, got " + err);
+						}
+					},
+				);
+		`)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,82 @@
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// diffValues returns a description of each difference between the expected
+// and actual JSON-decoded values, prefixed with the path they were found at.
+//
+// Objects and arrays are compared recursively, so that the differences point
+// at the nested fields and items which differ, rather than at the whole value.
+func diffValues(path string, expected, actual any) []string {
+	switch expectedValue := expected.(type) {
+	case map[string]any:
+		actualValue, isObject := actual.(map[string]any)
+		if !isObject {
+			break
+		}
+
+		fields := make([]string, 0, len(expectedValue)+len(actualValue))
+		for field := range expectedValue {
+			fields = append(fields, field)
+		}
+		for field := range actualValue {
+			if _, ok := expectedValue[field]; !ok {
+				fields = append(fields, field)
+			}
+		}
+		sort.Strings(fields)
+
+		var diffs []string
+		for _, field := range fields {
+			fieldPath := path + "." + field
+
+			expectedField, expectedOk := expectedValue[field]
+			actualField, actualOk := actualValue[field]
+
+			switch {
+			case !actualOk:
+				diffs = append(diffs, fieldPath+": expected "+formatValue(expectedField)+", but it is missing")
+			case !expectedOk:
+				diffs = append(diffs, fieldPath+": unexpected "+formatValue(actualField))
+			default:
+				diffs = append(diffs, diffValues(fieldPath, expectedField, actualField)...)
+			}
+		}
+
+		return diffs
+	case []any:
+		actualValue, isArray := actual.([]any)
+		if !isArray || len(actualValue) != len(expectedValue) {
+			break
+		}
+
+		var diffs []string
+		for i := range expectedValue {
+			diffs = append(diffs, diffValues(path+"["+strconv.Itoa(i)+"]", expectedValue[i], actualValue[i])...)
+		}
+
+		return diffs
+	}
+
+	if reflect.DeepEqual(expected, actual) {
+		return nil
+	}
+
+	return []string{path + ": expected " + formatValue(expected) + ", got " + formatValue(actual)}
+}
+
+// formatValue formats a JSON-decoded value for display in a diff.
+func formatValue(value any) string {
+	formatted, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(formatted)
+}
@@ -0,0 +1,45 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffValues(t *testing.T) {
+	t.Parallel()
+
+	t.Run("equal values have no differences", func(t *testing.T) {
+		t.Parallel()
+
+		value := map[string]any{"a": []any{1.0, "b"}, "c": nil}
+
+		assert.Empty(t, diffValues("key", value, value))
+	})
+
+	t.Run("different scalars are reported at their path", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{`key: expected 1, got "1"`}, diffValues("key", 1.0, "1"))
+	})
+
+	t.Run("nested differences are reported at the field level", func(t *testing.T) {
+		t.Parallel()
+
+		expected := map[string]any{"a": map[string]any{"b": 1.0}, "c": true, "items": []any{1.0, 2.0}}
+		actual := map[string]any{"a": map[string]any{"b": 2.0}, "d": false, "items": []any{1.0, 3.0}}
+
+		assert.Equal(t, []string{
+			"key.a.b: expected 1, got 2",
+			"key.c: expected true, but it is missing",
+			"key.d: unexpected false",
+			"key.items[1]: expected 2, got 3",
+		}, diffValues("key", expected, actual))
+	})
+
+	t.Run("arrays of different lengths are reported as a whole", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{"key: expected [1], got [1,2]"}, diffValues("key", []any{1.0}, []any{1.0, 2.0}))
+	})
+}
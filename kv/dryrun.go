@@ -0,0 +1,65 @@
+package kv
+
+import (
+	"sync"
+
+	"go.k6.io/k6/js/common"
+)
+
+// Mutation is a write operation recorded by a KV instance in dry-run mode.
+type Mutation struct {
+	// Op is the name of the KV method which attempted the write.
+	Op string `json:"op"`
+
+	// Key is the key the write applies to, if any.
+	Key string `json:"key,omitempty"`
+
+	// Value is the value that would have been written, if any.
+	Value any `json:"value,omitempty"`
+}
+
+// mutationLog holds the mutations recorded in dry-run mode, shared by all VUs.
+type mutationLog struct {
+	lock      sync.Mutex
+	mutations []mutation
+}
+
+// record appends a mutation to the log.
+func (l *mutationLog) record(m mutation) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.mutations = append(l.mutations, m)
+}
+
+// report returns the recorded mutations, in the order they were recorded.
+func (l *mutationLog) report() ([]Mutation, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	report := make([]Mutation, 0, len(l.mutations))
+	for _, m := range l.mutations {
+		entry := Mutation{Op: m.op, Key: string(m.key)}
+
+		if m.value != nil {
+			if err := decodeValue(m.value, &entry.Value); err != nil {
+				return nil, err
+			}
+		}
+
+		report = append(report, entry)
+	}
+
+	return report, nil
+}
+
+// DryRunReport returns the mutations recorded by all the KV instances
+// opened in dry-run mode, in the order they were attempted.
+func (k *KV) DryRunReport() []Mutation {
+	report, err := k.db.mutations.report()
+	if err != nil {
+		common.Throw(k.vu.Runtime(), err)
+	}
+
+	return report
+}
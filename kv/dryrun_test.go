@@ -0,0 +1,69 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVMutate(t *testing.T) {
+	t.Parallel()
+
+	put := func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(DefaultKvBucket)).Put([]byte("foo"), []byte(`"bar"`))
+	}
+
+	t.Run("mutations are committed by default", func(t *testing.T) {
+		t.Parallel()
+
+		kv := openTestKV(t, Options{})
+		dbInstance := kv.db
+		require.NoError(t, kv.mutate(mutation{op: "set", key: []byte("foo"), value: []byte(`"bar"`)}, put))
+
+		assert.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			assert.Equal(t, []byte(`"bar"`), tx.Bucket([]byte(DefaultKvBucket)).Get([]byte("foo")))
+			return nil
+		}))
+
+		report, err := dbInstance.mutations.report()
+		require.NoError(t, err)
+		assert.Empty(t, report)
+	})
+
+	t.Run("mutations are recorded and rolled back in dry-run mode", func(t *testing.T) {
+		t.Parallel()
+
+		kv := openTestKV(t, Options{DryRun: true})
+		dbInstance := kv.db
+		require.NoError(t, kv.mutate(mutation{op: "set", key: []byte("foo"), value: []byte(`"bar"`)}, put))
+
+		assert.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			assert.Nil(t, tx.Bucket([]byte(DefaultKvBucket)).Get([]byte("foo")))
+			return nil
+		}))
+
+		report, err := dbInstance.mutations.report()
+		require.NoError(t, err)
+		assert.Equal(t, []Mutation{{Op: "set", Key: "foo", Value: "bar"}}, report)
+	})
+
+	t.Run("batched mutations are recorded together in dry-run mode", func(t *testing.T) {
+		t.Parallel()
+
+		kv := openTestKV(t, Options{DryRun: true})
+		dbInstance := kv.db
+		require.NoError(t, kv.mutateMany([]mutation{
+			{op: "setMany", key: []byte("foo"), value: []byte(`"bar"`)},
+			{op: "setMany", key: []byte("baz"), value: []byte(`1`)},
+		}, put))
+
+		report, err := dbInstance.mutations.report()
+		require.NoError(t, err)
+		assert.Equal(t, []Mutation{
+			{Op: "setMany", Key: "foo", Value: "bar"},
+			{Op: "setMany", Key: "baz", Value: float64(1)},
+		}, report)
+	})
+}
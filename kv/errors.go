@@ -1,5 +1,11 @@
 package kv
 
+import (
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
 // ErrorName represents the name of an error
 type ErrorName string
 
@@ -26,8 +32,51 @@ const (
 	// KeyTooLargeError is emitted when the key is too large.
 	KeyTooLargeError = "KeyTooLargeError"
 
+	// InvalidKeyError is emitted when inserting a key which does not
+	// comply with the configured key validation rules.
+	InvalidKeyError = "InvalidKeyError"
+
 	// ValueTooLargeError is emitted when the value is too large.
 	ValueTooLargeError = "ValueTooLargeError"
+
+	// UnsupportedFormatError is emitted when opening a store written in
+	// a format version newer than the one supported by the module.
+	UnsupportedFormatError = "UnsupportedFormatError"
+
+	// TimeoutError is emitted when waiting on a coordination primitive
+	// does not complete within the given timeout.
+	TimeoutError = "TimeoutError"
+
+	// StateMismatchError is emitted when the contents of the store
+	// do not match the expected state.
+	StateMismatchError = "StateMismatchError"
+
+	// KeyExistsError is emitted when inserting a document whose key is already in use.
+	KeyExistsError = "KeyExistsError"
+
+	// ValidationError is emitted when a document does not comply with
+	// its collection's schema.
+	ValidationError = "ValidationError"
+
+	// CorruptedStoreError is emitted when verifying the integrity of the
+	// store at open finds it corrupted.
+	CorruptedStoreError = "CorruptedStoreError"
+
+	// StoreLockedError is emitted when the store's file is locked by another
+	// process for longer than the openTimeout option.
+	StoreLockedError = "StoreLockedError"
+
+	// TooManyOperationsError is emitted when an operation is attempted while
+	// the maximum number of operations are in flight and queued already.
+	TooManyOperationsError = "TooManyOperationsError"
+
+	// TypeMismatchError is emitted when an operation expects the value
+	// of a key to be of another type than the one it holds.
+	TypeMismatchError = "TypeMismatchError"
+
+	// ReadOnlyError is emitted when writing to a store opened in the
+	// sharedReadOnly mode.
+	ReadOnlyError = "ReadOnlyError"
 )
 
 // Error represents a custom error emitted by the kv module
@@ -53,3 +102,29 @@ func (e *Error) Error() string {
 }
 
 var _ error = (*Error)(nil)
+
+// asError returns the errors of the store as the matching typed errors,
+// so that scripts can tell them apart by name. Other errors are returned
+// as is.
+func asError(err error) error {
+	var kvErr *Error
+	if err == nil || errors.As(err, &kvErr) {
+		return err
+	}
+
+	for boltErr, name := range map[error]ErrorName{
+		bolt.ErrDatabaseNotOpen:  DatabaseNotOpenError,
+		bolt.ErrDatabaseReadOnly: ReadOnlyError,
+		bolt.ErrBucketNotFound:   BucketNotFoundError,
+		bolt.ErrBucketExists:     BucketExistsError,
+		bolt.ErrKeyRequired:      KeyRequiredError,
+		bolt.ErrKeyTooLarge:      KeyTooLargeError,
+		bolt.ErrValueTooLarge:    ValueTooLargeError,
+	} {
+		if errors.Is(err, boltErr) {
+			return NewError(name, err.Error())
+		}
+	}
+
+	return err
+}
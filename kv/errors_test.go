@@ -0,0 +1,64 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestAsError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorName
+	}{
+		{name: "bucket not found", err: bolt.ErrBucketNotFound, want: BucketNotFoundError},
+		{name: "wrapped key required", err: fmt.Errorf("put: %w", bolt.ErrKeyRequired), want: KeyRequiredError},
+		{name: "database not open", err: bolt.ErrDatabaseNotOpen, want: DatabaseNotOpenError},
+		{name: "typed error", err: NewError(KeyNotFoundError, "key foo not found"), want: KeyNotFoundError},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var kvErr *Error
+			require.ErrorAs(t, asError(tt.err), &kvErr)
+			assert.Equal(t, tt.want, kvErr.Name)
+		})
+	}
+
+	t.Run("other errors are returned as is", func(t *testing.T) {
+		t.Parallel()
+
+		err := errors.New("boom")
+		assert.Equal(t, err, asError(err))
+		assert.NoError(t, asError(nil))
+	})
+}
+
+func TestKVClosedStore(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	dbInstance := newDB()
+	dbInstance.path = filepath.Join(tmpDir, "closed.db")
+	require.NoError(t, dbInstance.open(Options{}))
+	require.NoError(t, dbInstance.close())
+
+	kv := &KV{bucket: []byte(DefaultKvBucket), db: dbInstance}
+
+	var kvErr *Error
+	require.ErrorAs(t, kv.view(func(*bolt.Tx) error { return nil }), &kvErr)
+	assert.Equal(t, DatabaseNotOpenError, kvErr.Name)
+}
@@ -0,0 +1,175 @@
+package kv
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// Exists resolves with whether a key exists in the store, without reading
+// its value.
+func (k *KV) Exists(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	k.db.workers.Load().run(func() {
+		exists, err := k.exists(keyBytes)
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(exists)
+	})
+
+	return promise
+}
+
+// ExistsMany resolves with an object mapping each of the given keys to
+// whether it exists in the store, checked within a single read, without
+// reading their values.
+func (k *KV) ExistsMany(keys sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyList, err := exportKeys(keys)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	k.db.workers.Load().run(func() {
+		exists := make(map[string]bool, len(keyList))
+
+		err := k.viewKeys("", func(indexed []string) error {
+			for _, key := range keyList {
+				i := sort.SearchStrings(indexed, string(key))
+				exists[string(key)] = i < len(indexed) && indexed[i] == string(key)
+			}
+
+			return nil
+		}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			visible := newVisibility(tx, k.bucket)
+			for _, key := range keyList {
+				exists[string(key)] = bucket.Get(key) != nil && !visible.hidden(key)
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(exists)
+	})
+
+	return promise
+}
+
+// exists reports whether a key exists in the store.
+func (k *KV) exists(key []byte) (bool, error) {
+	exists := false
+
+	err := k.viewKeys(string(key), func(keys []string) error {
+		exists = len(keys) > 0 && keys[0] == string(key)
+		return nil
+	}, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(k.bucket)
+		if bucket == nil {
+			return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+		}
+
+		exists = bucket.Get(key) != nil && !newVisibility(tx, k.bucket).hidden(key)
+
+		return nil
+	})
+
+	return exists, err
+}
+
+// Keys resolves with the keys of the store, without their values, ordered
+// lexicographically. Like KV.List, it accepts the prefix, glob, regex and
+// limit options.
+func (k *KV) Keys(options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	listOptions, err := ImportListOptions(k.vu.Runtime(), options)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if !listOptions.limitSet {
+		listOptions.Limit = k.options.DefaultListLimit
+	}
+
+	limit := int(listOptions.Limit)
+	prefix := []byte(listOptions.Prefix)
+
+	k.db.workers.Load().run(func() {
+		keys := []string{}
+
+		err := k.viewKeys(listOptions.Prefix, func(indexed []string) error {
+			if len(listOptions.filters) > 0 {
+				matching := []string{}
+				for _, key := range indexed {
+					if listOptions.matches([]byte(key)) {
+						matching = append(matching, key)
+					}
+				}
+
+				indexed = matching
+			}
+
+			if limit > 0 && len(indexed) > limit {
+				indexed = indexed[:limit]
+			}
+
+			keys = append(keys, indexed...)
+
+			return nil
+		}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			visible := newVisibility(tx, k.bucket)
+
+			cursor := bucket.Cursor()
+			for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+				if limit > 0 && len(keys) >= limit {
+					break
+				}
+
+				if !visible.hidden(key) && listOptions.matches(key) {
+					keys = append(keys, string(key))
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(keys)
+	})
+
+	return promise
+}
@@ -0,0 +1,53 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVExistsMany(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options string
+	}{
+		{name: "keys are looked up in the store", options: "{}"},
+		{name: "keys are looked up in the key index", options: "{ keyIndex: true }"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			vu := newTestVU(t)
+
+			err := vu.run(`
+				const store = kv.openKv(` + tt.options + `);
+
+				Promise.all([store.set("a", 1), store.set("c", 3), store.setDelayed("d", 4, "1h")])
+					.then(() => store.existsMany(["a", "b", "c", "d"]))
+					.then((exists) => {
+						const expected = { a: true, b: false, c: true, d: false };
+						if (Object.keys(expected).some((key) => exists[key] !== expected[key])) {
+							throw new Error("expected " + JSON.stringify(expected) + ", got " + JSON.stringify(exists));
+						}
+
+						return store.existsMany("a");
+					})
+					.then(
+						() => { throw new Error("expected existsMany to reject keys which are not an array"); },
+						(err) => {
+							if (!String(err).includes("expected an array of keys")) {
+								throw err;
+							}
+						},
+					);
+			`)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// ExpectState verifies that the store's contents match the expected state,
+// and resolves with true if they do.
+//
+// The expected state is an object whose properties are either keys, mapped to
+// the value they are expected to hold, or prefixes followed by a "*", mapped to
+// an object holding the number of keys expected to start with it:
+//
+//	kv.expectState({ "tenant": { id: 1 }, "user:*": { count: 10 } })
+//
+// Otherwise, the promise is rejected with a StateMismatchError describing
+// every difference found, down to the nested fields of the values.
+func (k *KV) ExpectState(expected sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	expectations, err := importStateExpectations(k.vu.Runtime(), expected)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var diffs []string
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			visible := newVisibility(tx, k.bucket)
+
+			for _, expectation := range expectations {
+				found, err := expectation.check(tx, bucket, visible)
+				if err != nil {
+					return err
+				}
+
+				diffs = append(diffs, found...)
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		if len(diffs) > 0 {
+			reject(NewError(
+				StateMismatchError,
+				"the store does not match the expected state:\n  "+strings.Join(diffs, "\n  "),
+			))
+			return
+		}
+
+		resolve(true)
+	}()
+
+	return promise
+}
+
+// stateExpectation is a single expectation passed to KV.ExpectState().
+type stateExpectation struct {
+	// key is the key, or the prefix, the expectation applies to.
+	key []byte
+
+	// isPrefix is true when the expectation applies to all the keys
+	// starting with key, rather than to key itself.
+	isPrefix bool
+
+	// value is the JSON-decoded value the key is expected to hold.
+	value any
+
+	// count is the number of keys expected to start with the prefix.
+	count int64
+}
+
+// check returns the differences between the expectation and the bucket's contents.
+func (e stateExpectation) check(tx *bolt.Tx, bucket *bolt.Bucket, visible visibility) ([]string, error) {
+	if e.isPrefix {
+		var count int64
+
+		cursor := bucket.Cursor()
+		for k, _ := cursor.Seek(e.key); k != nil && bytes.HasPrefix(k, e.key); k, _ = cursor.Next() {
+			if !visible.hidden(k) {
+				count++
+			}
+		}
+
+		if count != e.count {
+			return []string{
+				strconv.Quote(string(e.key)+"*") + ": expected " + strconv.FormatInt(e.count, 10) +
+					" keys, got " + strconv.FormatInt(count, 10),
+			}, nil
+		}
+
+		return nil, nil
+	}
+
+	jsonValue, err := loadValue(tx, bucket.Get(e.key))
+	if err != nil {
+		return nil, err
+	}
+
+	if jsonValue == nil || visible.hidden(e.key) {
+		return []string{strconv.Quote(string(e.key)) + ": expected " + formatValue(e.value) + ", but it is missing"}, nil
+	}
+
+	var value any
+	if err := json.Unmarshal(jsonValue, &value); err != nil {
+		return nil, err
+	}
+
+	return diffValues(strconv.Quote(string(e.key)), e.value, value), nil
+}
+
+// importStateExpectations instantiates the expectations passed to KV.ExpectState() from a sobek.Value.
+func importStateExpectations(rt *sobek.Runtime, expected sobek.Value) ([]stateExpectation, error) {
+	if common.IsNullish(expected) {
+		return nil, fmt.Errorf("expected state is required")
+	}
+
+	expectedObj := expected.ToObject(rt)
+
+	expectations := make([]stateExpectation, 0, len(expectedObj.Keys()))
+	for _, key := range expectedObj.Keys() {
+		value := expectedObj.Get(key)
+
+		if prefix, isPrefix := strings.CutSuffix(key, "*"); isPrefix {
+			count := value.ToObject(rt).Get("count")
+			if common.IsNullish(count) {
+				return nil, fmt.Errorf("expected state for %q must specify a count", key)
+			}
+
+			expectations = append(expectations, stateExpectation{
+				key:      []byte(prefix),
+				isPrefix: true,
+				count:    count.ToInteger(),
+			})
+
+			continue
+		}
+
+		// Round-trip the expected value through JSON, so that it compares
+		// with the stored values the way they are decoded.
+		jsonValue, err := json.Marshal(value.Export())
+		if err != nil {
+			return nil, fmt.Errorf("expected state for %q is not JSON-serializable: %w", key, err)
+		}
+
+		var decoded any
+		if err := json.Unmarshal(jsonValue, &decoded); err != nil {
+			return nil, err
+		}
+
+		expectations = append(expectations, stateExpectation{key: []byte(key), value: decoded})
+	}
+
+	return expectations, nil
+}
@@ -0,0 +1,70 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVExpectState(t *testing.T) {
+	t.Parallel()
+
+	t.Run("matching state resolves with true", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			Promise.all([
+				store.set("tenant", { id: 1, name: "acme" }),
+				store.set("user:1", "alice"),
+				store.set("user:2", "bob"),
+			])
+				.then(() => store.expectState({ tenant: { id: 1, name: "acme" }, "user:*": { count: 2 } }))
+				.then((matched) => {
+					if (matched !== true) {
+						throw new Error("expected expectState to resolve with true, got " + matched);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("mismatching state is rejected with every difference", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			Promise.all([
+				store.set("tenant", { id: 1, name: "acme" }),
+				store.set("user:1", "alice"),
+			])
+				.then(() => store.expectState({
+					tenant: { id: 2, name: "acme" },
+					"user:*": { count: 2 },
+					missing: true,
+				}))
+				.then(
+					() => { throw new Error("expected expectState to reject"); },
+					(err) => {
+						if (String(err.name) !== "StateMismatchError") {
+							throw err;
+						}
+
+						const message = String(err.message);
+						for (const diff of ['"tenant".id: expected 2', '"user:*": expected 2 keys, got 1', '"missing": expected true, but it is missing']) {
+							if (!message.includes(diff)) {
+								throw new Error("expected the error to report " + diff + ", got " + message);
+							}
+						}
+					},
+				);
+		`)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,156 @@
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// exportedEntry is an entry of an export file, in the same format as
+// the entries of a seed file.
+type exportedEntry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Export writes the entries of the store, or the ones whose key starts with
+// the prefix option, to a file, and resolves with the number of entries
+// written.
+//
+// Files with the .ndjson or .jsonl extension are written an entry object,
+// with key and value properties, per line. Other files are written a JSON
+// array of entry objects. Either can be loaded back with the seed option.
+func (k *KV) Export(path sobek.Value, options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(path) || path.String() == "" {
+		reject(fmt.Errorf("export expects the path of the file to write"))
+		return promise
+	}
+
+	var prefix string
+	if !common.IsNullish(options) {
+		if value := options.ToObject(k.vu.Runtime()).Get("prefix"); !common.IsNullish(value) {
+			prefix = value.String()
+		}
+	}
+
+	go func() {
+		var exported int64
+
+		err := k.view(func(tx *bolt.Tx) error {
+			var err error
+			exported, err = exportEntries(tx, k.bucket, []byte(prefix), path.String())
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(exported)
+	}()
+
+	return promise
+}
+
+// exportEntries writes the visible entries of the bucket whose key starts
+// with the prefix to the file at path, and returns how many were written.
+func exportEntries(tx *bolt.Tx, bucketName, prefix []byte, path string) (int64, error) {
+	bucket := tx.Bucket(bucketName)
+	if bucket == nil {
+		return 0, NewError(BucketNotFoundError, "bucket "+string(bucketName)+" not found")
+	}
+
+	file, err := os.Create(path) //nolint:forbidigo
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	ext := strings.ToLower(filepath.Ext(path))
+	lines := ext == ".ndjson" || ext == ".jsonl"
+
+	writer := bufio.NewWriter(file)
+	visible := newVisibility(tx, bucketName)
+
+	if !lines {
+		_, _ = writer.WriteString("[\n")
+	}
+
+	var exported int64
+
+	cursor := bucket.Cursor()
+	for key, raw := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, raw = cursor.Next() {
+		if visible.hidden(key) {
+			continue
+		}
+
+		value, err := loadValue(tx, raw)
+		if err != nil {
+			return exported, err
+		}
+
+		if value, err = exportedValue(value); err != nil {
+			return exported, err
+		}
+
+		entry, err := json.Marshal(exportedEntry{Key: string(key), Value: value})
+		if err != nil {
+			return exported, err
+		}
+
+		if !lines && exported > 0 {
+			_, _ = writer.WriteString(",\n")
+		}
+
+		_, _ = writer.Write(entry)
+		if lines {
+			_, _ = writer.WriteString("\n")
+		}
+
+		exported++
+	}
+
+	if !lines {
+		if exported > 0 {
+			_, _ = writer.WriteString("\n")
+		}
+
+		_, _ = writer.WriteString("]\n")
+	}
+
+	if err := writer.Flush(); err != nil {
+		return exported, fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	return exported, file.Close()
+}
+
+// exportedValue returns the JSON form of a stored value: binary values are
+// exported as base64-encoded strings, and values encoded by a registered
+// Serializer as JSON.
+func exportedValue(value []byte) ([]byte, error) {
+	if !isBinary(value) && !isSerialized(value) {
+		return value, nil
+	}
+
+	var decoded any
+	if err := decodeValue(value, &decoded); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(decoded)
+}
@@ -0,0 +1,54 @@
+package kv
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestExportEntries(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	dbInstance := newDB()
+	dbInstance.path = filepath.Join(tmpDir, "export.db")
+	require.NoError(t, dbInstance.open(Options{ExportOnClose: filepath.Join(tmpDir, "onclose.json")}))
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(DefaultKvBucket))
+		for key, value := range map[string]string{"id:1": `"a"`, "id:2": `{"b":2}`, "other": `3`} {
+			if err := bucket.Put([]byte(key), []byte(value)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	for _, name := range []string{"ids.json", "ids.ndjson"} {
+		path := filepath.Join(tmpDir, name)
+
+		require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			exported, err := exportEntries(tx, []byte(DefaultKvBucket), []byte("id:"), path)
+			assert.Equal(t, int64(2), exported)
+			return err
+		}))
+
+		// Exported files can be loaded back as seed files.
+		entries, err := readSeed(path)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "id:1", *entries[0].Key)
+		assert.JSONEq(t, `{"b":2}`, string(entries[1].Value))
+	}
+
+	require.NoError(t, dbInstance.close())
+
+	entries, err := readSeed(filepath.Join(tmpDir, "onclose.json"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
@@ -0,0 +1,79 @@
+package kv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileGlob compiles a glob pattern to the regular expression matching
+// the same keys, as a whole.
+//
+// In the pattern, '*' matches any sequence of characters, '?' any single
+// character, '[...]' any character of the class, negated by a leading '!'
+// or '^', and '\' escapes the character following it.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			expr.WriteString("(?s:.*)")
+		case '?':
+			expr.WriteString("(?s:.)")
+		case '\\':
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("invalid glob %q: trailing escape", pattern)
+			}
+
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			end := i + 1
+			if end < len(runes) && (runes[end] == '!' || runes[end] == '^') {
+				end++
+			}
+
+			// A closing bracket right after the opening one is part of the class.
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+
+			if end == len(runes) {
+				return nil, fmt.Errorf("invalid glob %q: unterminated character class", pattern)
+			}
+
+			expr.WriteString("[")
+
+			class := runes[i+1 : end]
+			if class[0] == '!' || class[0] == '^' {
+				expr.WriteString("^")
+				class = class[1:]
+			}
+
+			// Dashes are kept as is, to delimit ranges.
+			for _, c := range class {
+				if c == '-' {
+					expr.WriteRune(c)
+				} else {
+					expr.WriteString(regexp.QuoteMeta(string(c)))
+				}
+			}
+
+			expr.WriteString("]")
+			i = end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
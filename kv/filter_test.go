@@ -0,0 +1,44 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		glob    string
+		key     string
+		matches bool
+	}{
+		{"user:*:active", "user:42:active", true},
+		{"user:*:active", "user:42:active:since", false},
+		{"user:*:active", "admin:user:42:active", false},
+		{"order-?", "order-1", true},
+		{"order-?", "order-12", false},
+		{"order-[0-9]", "order-7", true},
+		{"order-[!0-9]", "order-7", false},
+		{"order-[^0-9]", "order-x", true},
+		{"a.b", "axb", false},
+		{`files/\*`, "files/*", true},
+		{`files/\*`, "files/a", false},
+		{"[]]", "]", true},
+		{"*", "multi\nline", true},
+	}
+
+	for _, tt := range tests {
+		filter, err := compileGlob(tt.glob)
+		require.NoError(t, err, tt.glob)
+		assert.Equal(t, tt.matches, filter.MatchString(tt.key), "%q matching %q", tt.glob, tt.key)
+	}
+
+	_, err := compileGlob("user:[")
+	require.Error(t, err)
+
+	_, err = compileGlob(`user:\`)
+	require.Error(t, err)
+}
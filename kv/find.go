@@ -0,0 +1,95 @@
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// wherePredicate selects the values whose field, at the path, is equal to
+// the expected value.
+type wherePredicate struct {
+	path     []string
+	expected any
+}
+
+// importWhere imports the where option of KV.Find, an object with the field
+// to compare, as a dot-separated path, and the value it equals.
+func importWhere(rt *sobek.Runtime, where sobek.Value) (*wherePredicate, error) {
+	whereObj := where.ToObject(rt)
+
+	field := whereObj.Get("field")
+	if common.IsNullish(field) || field.String() == "" {
+		return nil, errors.New("where.field is required")
+	}
+
+	equals := whereObj.Get("equals")
+	if equals == nil || sobek.IsUndefined(equals) {
+		return nil, errors.New("where.equals is required")
+	}
+
+	// Compare values as JSON-decoded, as the stored values are.
+	jsonEquals, err := json.Marshal(equals.Export())
+	if err != nil {
+		return nil, fmt.Errorf("invalid where.equals: %w", err)
+	}
+
+	predicate := &wherePredicate{path: splitPath(field.String())}
+	if err := json.Unmarshal(jsonEquals, &predicate.expected); err != nil {
+		return nil, fmt.Errorf("invalid where.equals: %w", err)
+	}
+
+	return predicate, nil
+}
+
+// matches reports whether a stored value is selected by the predicate.
+func (p *wherePredicate) matches(jsonValue []byte) (bool, error) {
+	var value any
+	if err := decodeValue(jsonValue, &value); err != nil {
+		return false, err
+	}
+
+	field, found := getPath(value, p.path)
+	if !found {
+		return false, nil
+	}
+
+	return len(diffValues("", p.expected, field)) == 0, nil
+}
+
+// Find returns the key-value pairs of the store whose value's field equals
+// the given one, as selected by the where option, such as
+// { field: "status", equals: "pending" }.
+//
+// Values are filtered as the store is scanned, so that only the matching
+// ones are returned to the script. It accepts the options of KV.List,
+// whose limit counts matching entries only.
+func (k *KV) Find(options sobek.Value) *sobek.Promise {
+	rt := k.vu.Runtime()
+
+	listOptions, err := ImportListOptions(rt, options)
+	if err == nil {
+		where := sobek.Undefined()
+		if !common.IsNullish(options) {
+			where = options.ToObject(rt).Get("where")
+		}
+
+		if common.IsNullish(where) {
+			err = errors.New("the where option is required")
+		} else {
+			listOptions.where, err = importWhere(rt, where)
+		}
+	}
+
+	if err != nil {
+		promise, _, reject := promises.New(k.vu)
+		reject(err)
+		return promise
+	}
+
+	return k.list(listOptions)
+}
@@ -0,0 +1,64 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVListEntriesWhere(t *testing.T) {
+	t.Parallel()
+
+	dbInstance := openTestDB(t, Options{})
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(DefaultKvBucket))
+		for key, value := range map[string]string{
+			"job:1":  `{"status":"pending","owner":{"vu":1}}`,
+			"job:2":  `{"status":"done","owner":{"vu":2}}`,
+			"job:3":  `{"status":"pending","owner":{"vu":2}}`,
+			"job:4":  `"pending"`,
+			"other":  `{"status":"pending"}`,
+			"job:5":  `{"status":["pending"]}`,
+			"job:6":  "\x01binary",
+			"job:7":  `{"status":"pending","owner":{"vu":2.0}}`,
+			"job:8":  `{}`,
+			"job:9":  `null`,
+			"job:10": `{"status":null}`,
+		} {
+			if err := bucket.Put([]byte(key), []byte(value)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	kv := &KV{bucket: []byte(DefaultKvBucket), db: dbInstance}
+	find := func(options ListOptions, where wherePredicate) []string {
+		var keys []string
+
+		options.where = &where
+		require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			entries, _, err := kv.listEntries(tx, tx.Bucket([]byte(DefaultKvBucket)), options)
+			for _, entry := range entries {
+				keys = append(keys, entry.Key)
+			}
+
+			return err
+		}))
+
+		return keys
+	}
+
+	pending := wherePredicate{path: []string{"status"}, expected: "pending"}
+	assert.Equal(t, []string{"job:1", "job:3", "job:7"}, find(ListOptions{Prefix: "job:"}, pending))
+	assert.Equal(t, []string{"job:1", "job:3"}, find(ListOptions{Prefix: "job:", Limit: 2, limitSet: true}, pending))
+
+	secondVU := wherePredicate{path: []string{"owner", "vu"}, expected: float64(2)}
+	assert.Equal(t, []string{"job:2", "job:3", "job:7"}, find(ListOptions{}, secondVU))
+
+	null := wherePredicate{path: []string{"status"}, expected: nil}
+	assert.Equal(t, []string{"job:10"}, find(ListOptions{}, null))
+}
@@ -0,0 +1,119 @@
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// FormatVersion is the version of the format the store is written in.
+	//
+	// It must be incremented whenever the way data is laid out in the store
+	// changes, along with a migration upgrading stores from the previous version.
+	FormatVersion = 1
+
+	// MetaBucket is the name of the internal bucket holding the store's metadata.
+	MetaBucket = "k6/meta"
+
+	// formatKey is the key the format header is stored under in the MetaBucket.
+	formatKey = "format"
+)
+
+// formatHeader describes the format the store is written in.
+//
+// How each value is encoded, such as whether it is compressed, is recorded
+// by a marker prefixing the value itself rather than in the header, so that
+// a store can be opened with different options than it was written with.
+type formatHeader struct {
+	// Version is the version of the store's format.
+	Version int `json:"version"`
+}
+
+// currentFormat returns the header of the format this version of the module writes.
+func currentFormat() formatHeader {
+	return formatHeader{Version: FormatVersion}
+}
+
+// ensureFormat checks the format the store is written in, migrating it to the
+// current format version if it is older, and records the current format header.
+//
+// Stores created before the header was introduced are considered to be
+// written in version 0 of the format.
+func ensureFormat(tx *bolt.Tx) error {
+	meta, err := tx.CreateBucketIfNotExists([]byte(MetaBucket))
+	if err != nil {
+		return fmt.Errorf("failed to create metadata bucket: %w", err)
+	}
+
+	header, err := readFormat(meta)
+	if err != nil {
+		return err
+	}
+
+	for version := header.Version + 1; version <= FormatVersion; version++ {
+		if err := migrate(tx, version); err != nil {
+			return fmt.Errorf("failed to migrate the store to format version %d: %w", version, err)
+		}
+	}
+
+	if header.Version == FormatVersion {
+		return nil
+	}
+
+	raw, err := json.Marshal(currentFormat())
+	if err != nil {
+		return err
+	}
+
+	return meta.Put([]byte(formatKey), raw)
+}
+
+// checkFormat checks that the format the store is written in is supported,
+// without migrating it, for stores opened read-only.
+func checkFormat(tx *bolt.Tx) error {
+	meta := tx.Bucket([]byte(MetaBucket))
+	if meta == nil {
+		return nil
+	}
+
+	_, err := readFormat(meta)
+
+	return err
+}
+
+// readFormat returns the format header recorded in the metadata bucket, and
+// fails with an UnsupportedFormatError if the format is newer than supported.
+func readFormat(meta *bolt.Bucket) (formatHeader, error) {
+	var header formatHeader
+	if raw := meta.Get([]byte(formatKey)); raw != nil {
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return header, fmt.Errorf("failed to decode the store's format header: %w", err)
+		}
+	}
+
+	if header.Version > FormatVersion {
+		return header, NewError(
+			UnsupportedFormatError,
+			"the store is written in format version "+strconv.Itoa(header.Version)+
+				", which is newer than the supported version "+strconv.Itoa(FormatVersion)+
+				"; upgrade xk6-kv to open it",
+		)
+	}
+
+	return header, nil
+}
+
+// migrate upgrades the store from the previous format version to the given one.
+func migrate(_ *bolt.Tx, version int) error {
+	switch version {
+	case 1:
+		// Version 1 introduced the format header itself, and did not
+		// change how data is laid out: there is nothing to migrate.
+		return nil
+	default:
+		return fmt.Errorf("no migration to format version %d", version)
+	}
+}
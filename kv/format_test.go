@@ -0,0 +1,60 @@
+package kv
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestEnsureFormat(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	openBolt := func(t *testing.T, name string) *bolt.DB {
+		t.Helper()
+
+		handle, err := bolt.Open(filepath.Join(tmpDir, name), 0o600, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, handle.Close())
+		})
+
+		return handle
+	}
+
+	t.Run("the current format header is written to new stores", func(t *testing.T) {
+		t.Parallel()
+
+		handle := openBolt(t, "new.db")
+		require.NoError(t, handle.Update(ensureFormat))
+
+		assert.NoError(t, handle.View(func(tx *bolt.Tx) error {
+			var header formatHeader
+			require.NoError(t, json.Unmarshal(tx.Bucket([]byte(MetaBucket)).Get([]byte(formatKey)), &header))
+			assert.Equal(t, currentFormat(), header)
+			return nil
+		}))
+	})
+
+	t.Run("stores written in a newer format are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		handle := openBolt(t, "newer.db")
+		require.NoError(t, handle.Update(func(tx *bolt.Tx) error {
+			meta, err := tx.CreateBucket([]byte(MetaBucket))
+			require.NoError(t, err)
+			return meta.Put([]byte(formatKey), []byte(`{"version":999}`))
+		}))
+
+		gotErr := handle.Update(ensureFormat)
+
+		var kvErr *Error
+		require.ErrorAs(t, gotErr, &kvErr)
+		assert.Equal(t, ErrorName(UnsupportedFormatError), kvErr.Name)
+	})
+}
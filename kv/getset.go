@@ -0,0 +1,163 @@
+package kv
+
+import (
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// GetSet atomically sets the value of a key in the store, and resolves with
+// the value it held before, or null if it did not exist.
+func (k *KV) GetSet(key sobek.Value, value sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonValue, err := k.marshal(keyBytes, value)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	settle := k.reviveLater(resolve, reject)
+
+	k.db.workers.Load().run(func() {
+		var previous any
+
+		err := k.mutate(mutation{op: "getSet", key: keyBytes, value: jsonValue}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			jsonPrevious, err := loadValue(tx, bucket.Get(keyBytes))
+			if err != nil {
+				return err
+			}
+
+			if jsonPrevious != nil && !newVisibility(tx, k.bucket).hidden(keyBytes) {
+				// Decode the previous value before it is overwritten, as the
+				// memory it points to is only valid until then.
+				if err := decodeValue(jsonPrevious, &previous); err != nil {
+					return err
+				}
+			}
+
+			if err := undelay(tx, k.bucket, keyBytes); err != nil {
+				return err
+			}
+
+			return k.storeValue(tx, bucket, keyBytes, jsonValue)
+		})
+		settle(func() (any, error) {
+			if err != nil || previous == nil {
+				return previous, err
+			}
+
+			return k.revive(keyBytes, previous)
+		})
+	})
+
+	return promise
+}
+
+// GetOrSet atomically resolves with the value of a key in the store, or sets
+// it to the given default value and resolves with it, if it does not exist.
+func (k *KV) GetOrSet(key sobek.Value, defaultValue sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonValue, err := k.marshal(keyBytes, defaultValue)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	settle := k.reviveLater(resolve, reject)
+
+	k.db.workers.Load().run(func() {
+		var (
+			existing any
+			found    bool
+		)
+
+		// Look the value up in a read transaction first, so that hits
+		// neither wait for, nor hold up, the writers of the store.
+		err := k.view(func(tx *bolt.Tx) error {
+			var err error
+			found, err = k.decodeExisting(tx, keyBytes, &existing)
+
+			return err
+		})
+
+		if err == nil && !found {
+			err = k.mutate(mutation{op: "getOrSet", key: keyBytes, value: jsonValue}, func(tx *bolt.Tx) error {
+				// The key may have been set since it was looked up.
+				var err error
+				if found, err = k.decodeExisting(tx, keyBytes, &existing); err != nil {
+					return err
+				}
+
+				if found {
+					return errUnchanged
+				}
+
+				if err := undelay(tx, k.bucket, keyBytes); err != nil {
+					return err
+				}
+
+				return k.storeValue(tx, tx.Bucket(k.bucket), keyBytes, jsonValue)
+			})
+		}
+
+		settle(func() (any, error) {
+			if err != nil {
+				return nil, err
+			}
+
+			if !found {
+				return defaultValue, nil
+			}
+
+			return k.revive(keyBytes, existing)
+		})
+	})
+
+	return promise
+}
+
+// decodeExisting decodes the value of the key into value, and reports
+// whether the key exists.
+func (k *KV) decodeExisting(tx *bolt.Tx, key []byte, value *any) (bool, error) {
+	bucket := tx.Bucket(k.bucket)
+	if bucket == nil {
+		return false, NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+	}
+
+	jsonValue, err := loadValue(tx, bucket.Get(key))
+	if err != nil || jsonValue == nil || newVisibility(tx, k.bucket).hidden(key) {
+		return false, err
+	}
+
+	return true, decodeValue(jsonValue, value)
+}
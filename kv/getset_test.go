@@ -0,0 +1,104 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVGetOrSet(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing keys are set to the default value", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.getOrSet("session", { id: 1 })
+				.then((value) => {
+					if (value.id !== 1) {
+						throw new Error("expected the default value, got " + JSON.stringify(value));
+					}
+
+					return store.get("session");
+				})
+				.then((value) => {
+					if (value.id !== 1) {
+						throw new Error("expected the default value to be set, got " + JSON.stringify(value));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("existing values are served without writing", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			let writes;
+
+			store.set("session", { id: 1 })
+				.then(() => store.stats())
+				.then((stats) => {
+					writes = stats.writes;
+					return store.getOrSet("session", { id: 2 });
+				})
+				.then((value) => {
+					if (value.id !== 1) {
+						throw new Error("expected the existing value, got " + JSON.stringify(value));
+					}
+
+					return Promise.all([store.get("session"), store.stats()]);
+				})
+				.then(([value, stats]) => {
+					if (value.id !== 1) {
+						throw new Error("expected the value to be unchanged, got " + JSON.stringify(value));
+					}
+
+					if (stats.writes !== writes) {
+						throw new Error("expected the existing value to be served without writing");
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+}
+
+func TestKVGetSet(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	err := vu.run(`
+		const store = kv.openKv();
+
+		store.getSet("counter", 1)
+			.then((previous) => {
+				if (previous !== null) {
+					throw new Error("expected null for a missing key, got " + JSON.stringify(previous));
+				}
+
+				return store.getSet("counter", { count: 2 });
+			})
+			.then((previous) => {
+				if (previous !== 1) {
+					throw new Error("expected the previous value, got " + JSON.stringify(previous));
+				}
+
+				return store.get("counter");
+			})
+			.then((value) => {
+				if (value.count !== 2) {
+					throw new Error("expected the new value to be set, got " + JSON.stringify(value));
+				}
+			});
+	`)
+	require.NoError(t, err)
+}
@@ -0,0 +1,195 @@
+package kv
+
+import (
+	"encoding/json"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// Hset atomically sets a field of the object held by a key, creating it if
+// the key does not exist, and resolves with true if the field is new.
+//
+// Only the stored object is read and written, within a single transaction,
+// so that concurrent updates of different fields are not lost.
+func (k *KV) Hset(key sobek.Value, field string, value sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonValue, err := json.Marshal(value.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var created bool
+
+		err := k.mutate(mutation{op: "hset", key: keyBytes}, func(tx *bolt.Tx) error {
+			hash, err := k.loadObject(tx, keyBytes)
+			if err != nil {
+				return err
+			}
+
+			_, found := hash[field]
+			created = !found
+			hash[field] = jsonValue
+
+			return k.replaceValue(tx, keyBytes, hash)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(created)
+	}()
+
+	return promise
+}
+
+// Hget resolves with a field of the object held by a key, or null if either
+// does not exist.
+func (k *KV) Hget(key sobek.Value, field string) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var value any
+
+		err := k.view(func(tx *bolt.Tx) error {
+			hash, err := k.loadObject(tx, keyBytes)
+			if err != nil {
+				return err
+			}
+
+			if jsonValue, found := hash[field]; found {
+				return json.Unmarshal(jsonValue, &value)
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(value)
+	}()
+
+	return promise
+}
+
+// Hdel atomically deletes a field of the object held by a key, deleting the
+// key once the object is empty, and resolves with true if the field existed.
+func (k *KV) Hdel(key sobek.Value, field string) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var deleted bool
+
+		err := k.mutate(mutation{op: "hdel", key: keyBytes}, func(tx *bolt.Tx) error {
+			hash, err := k.loadObject(tx, keyBytes)
+			if err != nil {
+				return err
+			}
+
+			if _, deleted = hash[field]; !deleted {
+				return nil
+			}
+
+			delete(hash, field)
+
+			return k.replaceValue(tx, keyBytes, hash)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(deleted)
+	}()
+
+	return promise
+}
+
+// Hgetall resolves with the object held by a key, or an empty object if the
+// key does not exist.
+func (k *KV) Hgetall(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		fields := make(map[string]any)
+
+		err := k.view(func(tx *bolt.Tx) error {
+			hash, err := k.loadObject(tx, keyBytes)
+			if err != nil {
+				return err
+			}
+
+			for field, jsonValue := range hash {
+				var value any
+				if err := json.Unmarshal(jsonValue, &value); err != nil {
+					return err
+				}
+
+				fields[field] = value
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(fields)
+	}()
+
+	return promise
+}
+
+// loadObject returns the JSON-encoded fields of the object held by a
+// visible key of the bucket, or none if it does not exist.
+func (k *KV) loadObject(tx *bolt.Tx, key []byte) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := k.loadTyped(tx, key, "an object", &fields); err != nil {
+		return nil, err
+	}
+
+	// A null value decodes to a nil map.
+	if fields == nil {
+		return nil, NewError(TypeMismatchError, "the value of key "+string(key)+" is not an object")
+	}
+
+	return fields, nil
+}
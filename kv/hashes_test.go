@@ -0,0 +1,45 @@
+package kv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVLoadObject(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+	user := []byte("user")
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		hash, err := kv.loadObject(tx, user)
+		require.NoError(t, err)
+		assert.Empty(t, hash)
+
+		hash["name"] = json.RawMessage(`"alice"`)
+		hash["age"] = json.RawMessage(`42`)
+		require.NoError(t, kv.replaceValue(tx, user, hash))
+		assert.Equal(t, []byte(`{"age":42,"name":"alice"}`), tx.Bucket(kv.bucket).Get(user))
+
+		// Empty objects are deleted
+		require.NoError(t, kv.replaceValue(tx, user, map[string]json.RawMessage{}))
+		assert.Nil(t, tx.Bucket(kv.bucket).Get(user))
+
+		// Keys holding other types are rejected
+		for _, value := range []string{`null`, `[1,2]`, `"alice"`} {
+			require.NoError(t, tx.Bucket(kv.bucket).Put(user, []byte(value)))
+			_, err = kv.loadObject(tx, user)
+
+			var kvErr *Error
+			require.ErrorAs(t, err, &kvErr)
+			assert.Equal(t, ErrorName(TypeMismatchError), kvErr.Name)
+		}
+
+		return nil
+	}))
+}
@@ -0,0 +1,137 @@
+package kv
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/eventloop"
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/testutils"
+	"go.k6.io/k6/metrics"
+)
+
+// openTestDB opens a store in a temporary directory of the test, with the
+// given options, and closes it once the test completes.
+func openTestDB(t *testing.T, options Options) *db {
+	t.Helper()
+
+	dbInstance := newDB()
+	dbInstance.path = filepath.Join(t.TempDir(), "test.db")
+	require.NoError(t, dbInstance.open(options))
+	t.Cleanup(func() {
+		require.NoError(t, dbInstance.close())
+	})
+
+	return dbInstance
+}
+
+// openTestKV opens a store, as openTestDB does, and returns a KV instance
+// on its default bucket, with the same options.
+func openTestKV(t *testing.T, options Options) *KV {
+	t.Helper()
+
+	return &KV{bucket: []byte(DefaultKvBucket), db: openTestDB(t, options), options: options}
+}
+
+// testVU is a VU running the JS code of a test on an event loop, in the
+// init context until its state is set.
+type testVU struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	runtime *sobek.Runtime
+	initEnv *common.InitEnvironment
+	state   *lib.State
+	loop    *eventloop.EventLoop
+	events  *event.System
+}
+
+var _ modules.VU = &testVU{}
+
+// newTestVU returns a VU exposing the exports of the module as the kv
+// global, whose default store is in a temporary directory of the test, and
+// is closed once the test completes.
+func newTestVU(t *testing.T) *testVU {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	vu := &testVU{
+		ctx:     ctx,
+		cancel:  cancel,
+		runtime: sobek.New(),
+		initEnv: &common.InitEnvironment{
+			TestPreInitState: &lib.TestPreInitState{Registry: metrics.NewRegistry()},
+		},
+		events: event.NewEventSystem(10, testutils.NewLogger(t)),
+	}
+	vu.runtime.SetFieldNameMapper(common.FieldNameMapper{})
+	vu.loop = eventloop.New(vu)
+
+	rm := New()
+	rm.db.path = filepath.Join(t.TempDir(), "test.db")
+	t.Cleanup(func() {
+		for rm.db.opened.Load() {
+			require.NoError(t, rm.db.close())
+		}
+	})
+
+	exports := rm.NewModuleInstance(vu).Exports()
+	require.NoError(t, vu.runtime.Set("kv", exports.Named))
+
+	return vu
+}
+
+// run runs the code on the event loop, until every promise it created is
+// settled, and returns the first error it threw, or rejection it left
+// unhandled.
+func (vu *testVU) run(code string) error {
+	defer vu.loop.WaitOnRegistered()
+
+	return vu.loop.Start(func() error {
+		_, err := vu.runtime.RunString(code)
+		return err
+	})
+}
+
+// enterVUContext moves the VU out of the init context, as the VU of the
+// given ID, and returns the channel its samples are pushed to.
+func (vu *testVU) enterVUContext(id uint64) chan metrics.SampleContainer {
+	samples := make(chan metrics.SampleContainer, 100)
+
+	vu.state = &lib.State{
+		VUID:       id,
+		VUIDGlobal: id,
+		Samples:    samples,
+		Tags:       lib.NewVUStateTags(vu.initEnv.Registry.RootTagSet()),
+	}
+
+	return samples
+}
+
+// emit emits an event of the given type to the VU's local subscribers, and
+// waits for them to process it.
+func (vu *testVU) emit(t *testing.T, eventType event.Type) {
+	t.Helper()
+
+	wait := vu.events.Emit(&event.Event{Type: eventType})
+	require.NoError(t, wait(vu.ctx))
+}
+
+func (vu *testVU) Context() context.Context { return vu.ctx }
+
+func (vu *testVU) Events() common.Events { return common.Events{Local: vu.events} }
+
+func (vu *testVU) InitEnv() *common.InitEnvironment { return vu.initEnv }
+
+func (vu *testVU) State() *lib.State { return vu.state }
+
+func (vu *testVU) Runtime() *sobek.Runtime { return vu.runtime }
+
+func (vu *testVU) RegisterCallback() func(func() error) { return vu.loop.RegisterCallback() }
@@ -0,0 +1,434 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/promises"
+)
+
+// IndexesBucket is the name of the internal bucket holding the secondary
+// indexes declared with the indexes option, in a nested bucket per bucket
+// of the store, itself holding a nested bucket per index.
+//
+// An index maps the terms of its field to the keys holding them, with an
+// entry made of indexTermPrefix, the term, a zero byte, and the key, and
+// maps each key to its term, with an entry made of indexKeyPrefix and the
+// key, so that the key is unindexed without decoding its previous value.
+const IndexesBucket = "k6/indexes"
+
+const (
+	indexTermPrefix = 't'
+	indexKeyPrefix  = 'k'
+)
+
+// IndexOptions declares a secondary index, through the indexes option.
+type IndexOptions struct {
+	// Name is the name KV.FindByIndex looks the index up by.
+	Name string `json:"name"`
+
+	// Field is the field of the object values the index maps to their
+	// keys, possibly nested, as a dot-separated path such as "address.city".
+	Field string `json:"field"`
+}
+
+// importIndexOptions imports the indexes option, an array of IndexOptions.
+func importIndexOptions(indexes sobek.Value) ([]IndexOptions, error) {
+	declared, ok := indexes.Export().([]any)
+	if !ok {
+		return nil, fmt.Errorf("indexes must be an array, got %v", indexes)
+	}
+
+	imported := make([]IndexOptions, 0, len(declared))
+	names := make(map[string]bool, len(declared))
+
+	for _, index := range declared {
+		properties, ok := index.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("indexes must be objects with a name and a field, got %v", index)
+		}
+
+		name, _ := properties["name"].(string)
+		field, _ := properties["field"].(string)
+
+		if name == "" || field == "" {
+			return nil, fmt.Errorf("indexes must have a name and a field, got %v", index)
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("index %q is declared more than once", name)
+		}
+
+		names[name] = true
+		imported = append(imported, IndexOptions{Name: name, Field: field})
+	}
+
+	return imported, nil
+}
+
+// FindByIndex resolves with the entries of the bucket whose value holds the
+// given value in the field of the named index, declared with the indexes
+// option, ordered by key.
+//
+// The keys are looked up in the index, rather than by reading every value
+// of the bucket.
+func (k *KV) FindByIndex(name sobek.Value, value sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	index, found := k.db.lookupIndex(name.String())
+	if !found {
+		reject(fmt.Errorf("index %q is not declared with the indexes option", name.String()))
+		return promise
+	}
+
+	jsonValue, err := json.Marshal(value.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	term, indexed := indexTerm(jsonValue)
+
+	settle := k.reviveLater(resolve, reject)
+
+	k.db.workers.Load().run(func() {
+		entries := []ListEntry{}
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			if !indexed {
+				return nil
+			}
+
+			keys := keysByTerm(tx, k.bucket, index, term)
+
+			visible := newVisibility(tx, k.bucket)
+			for _, key := range keys {
+				if visible.hidden(key) {
+					continue
+				}
+
+				jsonValue, err := loadValue(tx, bucket.Get(key))
+				if err != nil || jsonValue == nil {
+					return err
+				}
+
+				var value any
+				if err := decodeValue(jsonValue, &value); err != nil {
+					return err
+				}
+
+				entries = append(entries, ListEntry{string(key), value})
+			}
+
+			return nil
+		})
+
+		settle(func() (any, error) {
+			if err != nil {
+				return nil, err
+			}
+
+			for i, entry := range entries {
+				value, err := k.revive([]byte(entry.Key), entry.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				entries[i].Value = value
+			}
+
+			return entries, nil
+		})
+	})
+
+	return promise
+}
+
+// lookupIndex returns the index declared under the given name.
+func (db *db) lookupIndex(name string) (IndexOptions, bool) {
+	for _, index := range db.options.Indexes {
+		if index.Name == name {
+			return index, true
+		}
+	}
+
+	return IndexOptions{}, false
+}
+
+// keysByTerm returns the keys of the bucket which the index maps the term
+// to, in order.
+//
+// Should the index not be built, as the store was opened in the
+// sharedReadOnly mode before it was ever declared, the bucket is scanned.
+func keysByTerm(tx *bolt.Tx, bucketName []byte, index IndexOptions, term []byte) [][]byte {
+	var keys [][]byte
+
+	if entries := indexBucket(tx, bucketName, index.Name); entries != nil {
+		prefix := indexTermEntry(term, nil)
+
+		cursor := entries.Cursor()
+		for entry, _ := cursor.Seek(prefix); entry != nil && bytes.HasPrefix(entry, prefix); entry, _ = cursor.Next() {
+			keys = append(keys, bytes.Clone(entry[len(prefix):]))
+		}
+
+		return keys
+	}
+
+	bucket := tx.Bucket(bucketName)
+	path := splitPath(index.Field)
+
+	_ = bucket.ForEach(func(key, raw []byte) error {
+		value, err := loadValue(tx, raw)
+		if err != nil {
+			return nil //nolint:nilerr
+		}
+
+		if found, ok := fieldTerm(value, path); ok && bytes.Equal(found, term) {
+			keys = append(keys, bytes.Clone(key))
+		}
+
+		return nil
+	})
+
+	return keys
+}
+
+// reindex updates the entries of the indexes of the KV instance's bucket
+// for the key, about to hold the value, or to be deleted if it is nil.
+func (k *KV) reindex(tx *bolt.Tx, key, value []byte) error {
+	if err := unindexValue(tx, k.bucket, key); err != nil {
+		return err
+	}
+
+	if value == nil || len(k.db.options.Indexes) == 0 {
+		return nil
+	}
+
+	return indexValue(tx, k.bucket, key, value, k.db.options.Indexes)
+}
+
+// indexValue adds the entries of the key, holding the value, to the indexes of
+// the bucket.
+func indexValue(tx *bolt.Tx, bucketName, key, value []byte, indexes []IndexOptions) error {
+	document, err := decodeDocument(value)
+	if err != nil {
+		// Only JSON values are indexed.
+		return nil //nolint:nilerr
+	}
+
+	for _, declared := range indexes {
+		field, found := getPath(document, splitPath(declared.Field))
+		if !found {
+			continue
+		}
+
+		term, ok := termOf(field)
+		if !ok {
+			continue
+		}
+
+		root, err := tx.CreateBucketIfNotExists([]byte(IndexesBucket))
+		if err != nil {
+			return err
+		}
+
+		indexes, err := root.CreateBucketIfNotExists(bucketName)
+		if err != nil {
+			return err
+		}
+
+		entries, err := indexes.CreateBucketIfNotExists([]byte(declared.Name))
+		if err != nil {
+			return err
+		}
+
+		if err := entries.Put(indexTermEntry(term, key), []byte{}); err != nil {
+			return err
+		}
+
+		if err := entries.Put(indexKeyEntry(key), term); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unindexValue removes the entries of the key from every index of the bucket.
+func unindexValue(tx *bolt.Tx, bucketName, key []byte) error {
+	root := tx.Bucket([]byte(IndexesBucket))
+	if root == nil {
+		return nil
+	}
+
+	indexes := root.Bucket(bucketName)
+	if indexes == nil {
+		return nil
+	}
+
+	var names [][]byte
+	_ = indexes.ForEach(func(name, _ []byte) error {
+		names = append(names, bytes.Clone(name))
+		return nil
+	})
+
+	for _, name := range names {
+		entries := indexes.Bucket(name)
+
+		term := entries.Get(indexKeyEntry(key))
+		if term == nil {
+			continue
+		}
+
+		if err := entries.Delete(indexTermEntry(term, key)); err != nil {
+			return err
+		}
+
+		if err := entries.Delete(indexKeyEntry(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropIndexes deletes the indexes of the bucket.
+func dropIndexes(tx *bolt.Tx, bucketName []byte) error {
+	root := tx.Bucket([]byte(IndexesBucket))
+	if root == nil || root.Bucket(bucketName) == nil {
+		return nil
+	}
+
+	return root.DeleteBucket(bucketName)
+}
+
+// buildIndexes builds the declared indexes of every bucket of the store
+// from scratch, dropping the indexes which are no longer declared.
+//
+// Rebuilding the indexes when the store is opened keeps them consistent
+// with the values, whichever indexes they were written with.
+func buildIndexes(indexes []IndexOptions) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(IndexesBucket)) != nil {
+			if err := tx.DeleteBucket([]byte(IndexesBucket)); err != nil {
+				return err
+			}
+		}
+
+		if len(indexes) == 0 {
+			return nil
+		}
+
+		var names [][]byte
+		_ = tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if validateBucketName(string(name)) == nil {
+				names = append(names, bytes.Clone(name))
+			}
+
+			return nil
+		})
+
+		for _, name := range names {
+			err := tx.Bucket(name).ForEach(func(key, raw []byte) error {
+				value, err := loadValue(tx, raw)
+				if err != nil || value == nil {
+					return err
+				}
+
+				return indexValue(tx, name, key, value, indexes)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to build the indexes of bucket %s: %w", name, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// indexBucket returns the bucket holding the entries of the named index of
+// the bucket, or nil if it has none.
+func indexBucket(tx *bolt.Tx, bucketName []byte, name string) *bolt.Bucket {
+	root := tx.Bucket([]byte(IndexesBucket))
+	if root == nil {
+		return nil
+	}
+
+	indexes := root.Bucket(bucketName)
+	if indexes == nil {
+		return nil
+	}
+
+	return indexes.Bucket([]byte(name))
+}
+
+// indexTermEntry returns the index entry mapping the term to the key.
+func indexTermEntry(term, key []byte) []byte {
+	entry := make([]byte, 0, 2+len(term)+len(key))
+	entry = append(entry, indexTermPrefix)
+	entry = append(entry, term...)
+	entry = append(entry, 0)
+
+	return append(entry, key...)
+}
+
+// indexKeyEntry returns the index entry mapping the key to its term.
+func indexKeyEntry(key []byte) []byte {
+	return append([]byte{indexKeyPrefix}, key...)
+}
+
+// indexTerm returns the term a JSON value is indexed under, and false if
+// values of its type are not indexed.
+func indexTerm(value []byte) ([]byte, bool) {
+	return fieldTerm(value, nil)
+}
+
+// fieldTerm returns the term the field at the path of a JSON value is
+// indexed under, and false if it has no such field, or values of its type
+// are not indexed.
+func fieldTerm(value []byte, path []string) ([]byte, bool) {
+	document, err := decodeDocument(value)
+	if err != nil {
+		return nil, false
+	}
+
+	field, found := getPath(document, path)
+	if !found {
+		return nil, false
+	}
+
+	return termOf(field)
+}
+
+// termOf returns the term a field is indexed under: its JSON encoding, with
+// numbers in their shortest form, so that 1 and 1.0 are indexed alike. Only
+// strings, numbers and booleans are indexed.
+//
+// JSON encodings never hold a zero byte, which separates the terms from the
+// keys in the index entries.
+func termOf(field any) ([]byte, bool) {
+	switch field := field.(type) {
+	case string, bool:
+		term, err := json.Marshal(field)
+		return term, err == nil
+	case json.Number:
+		number, err := field.Float64()
+		if err != nil {
+			return nil, false
+		}
+
+		term, err := json.Marshal(number)
+
+		return term, err == nil
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,113 @@
+package kv
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVIndexes(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	byEmail := IndexOptions{Name: "byEmail", Field: "email"}
+	byCity := IndexOptions{Name: "byCity", Field: "address.city"}
+
+	dbInstance := newDB()
+	dbInstance.path = filepath.Join(tmpDir, "indexes.db")
+	require.NoError(t, dbInstance.open(Options{Indexes: []IndexOptions{byEmail, byCity}}))
+
+	kv := &KV{bucket: []byte(DefaultKvBucket), db: dbInstance}
+	lookup := func(index IndexOptions, term string) []string {
+		var keys []string
+
+		require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			for _, key := range keysByTerm(tx, kv.bucket, index, []byte(term)) {
+				keys = append(keys, string(key))
+			}
+
+			return nil
+		}))
+
+		return keys
+	}
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		for key, value := range map[string]string{
+			"user:1": `{"email":"ada@example.com","address":{"city":"London"}}`,
+			"user:2": `{"email":"alan@example.com","address":{"city":"London"}}`,
+			"user:3": `{"email":"grace@example.com"}`,
+			"note":   `"not an object"`,
+		} {
+			if err := kv.storeValue(tx, bucket, []byte(key), []byte(value)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"user:1", "user:2"}, lookup(byCity, `"London"`))
+	assert.Equal(t, []string{"user:2"}, lookup(byEmail, `"alan@example.com"`))
+	assert.Empty(t, lookup(byEmail, `"nobody@example.com"`))
+
+	// Overwriting and deleting keys updates their entries
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		if err := kv.storeValue(tx, bucket, []byte("user:2"), []byte(`{"email":"turing@example.com"}`)); err != nil {
+			return err
+		}
+
+		return kv.removeValue(tx, bucket, []byte("user:3"))
+	}))
+
+	assert.Equal(t, []string{"user:1"}, lookup(byCity, `"London"`))
+	assert.Empty(t, lookup(byEmail, `"alan@example.com"`))
+	assert.Equal(t, []string{"user:2"}, lookup(byEmail, `"turing@example.com"`))
+	assert.Empty(t, lookup(byEmail, `"grace@example.com"`))
+
+	// Indexes which are no longer declared are dropped when the store is opened
+	require.NoError(t, dbInstance.close())
+
+	dbInstance = newDB()
+	dbInstance.path = filepath.Join(tmpDir, "indexes.db")
+	require.NoError(t, dbInstance.open(Options{Indexes: []IndexOptions{byEmail}}))
+	t.Cleanup(func() {
+		require.NoError(t, dbInstance.close())
+	})
+
+	kv.db = dbInstance
+
+	assert.Equal(t, []string{"user:1"}, lookup(byEmail, `"ada@example.com"`))
+	require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+		assert.Nil(t, indexBucket(tx, kv.bucket, byCity.Name))
+		return nil
+	}))
+}
+
+func TestIndexTerm(t *testing.T) {
+	t.Parallel()
+
+	for value, term := range map[string]string{
+		`"ada"`: `"ada"`,
+		`1.0`:   `1`,
+		`1e3`:   `1000`,
+		`true`:  `true`,
+	} {
+		got, ok := indexTerm([]byte(value))
+		require.True(t, ok, value)
+		assert.Equal(t, term, string(got), value)
+	}
+
+	for _, value := range []string{`null`, `{"a":1}`, `[1]`, "\x01binary"} {
+		_, ok := indexTerm([]byte(value))
+		assert.False(t, ok, value)
+	}
+}
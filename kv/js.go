@@ -0,0 +1,36 @@
+package kv
+
+import (
+	"github.com/grafana/sobek"
+)
+
+// settle calls onFulfilled or onRejected once the given value is settled.
+//
+// If the value is a thenable, such as a Promise returned by an async function,
+// the callbacks are chained to it. Otherwise onFulfilled is called right away
+// with the value itself.
+//
+// It must be called from the event loop, and the callbacks are called from it too.
+func settle(
+	rt *sobek.Runtime,
+	value sobek.Value,
+	onFulfilled func(sobek.Value),
+	onRejected func(sobek.Value),
+) {
+	obj, isObject := value.(*sobek.Object)
+	if !isObject {
+		onFulfilled(value)
+		return
+	}
+
+	then, isThenable := sobek.AssertFunction(obj.Get("then"))
+	if !isThenable {
+		onFulfilled(value)
+		return
+	}
+
+	_, err := then(obj, rt.ToValue(onFulfilled), rt.ToValue(onRejected))
+	if err != nil {
+		onRejected(rt.ToValue(err))
+	}
+}
@@ -0,0 +1,349 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/promises"
+)
+
+// keyIndex holds the sorted visible keys of the store's buckets in memory,
+// when the store is opened with the KeyIndex option, so that looking keys
+// up, counting, sampling and listing them does not scan the store.
+//
+// A bucket's keys are indexed on first use, and indexed again once the
+// visibility of one of its delayed or expiring keys changes, or the bucket
+// is cleared. The keys set or deleted by the writes committed in between
+// are added to, or removed from, the indexed keys, one by one.
+type keyIndex struct {
+	lock    sync.Mutex
+	buckets map[string]*indexedKeys
+
+	// generations counts the writes committed to each bucket, so that keys
+	// indexed while a write was committed are not kept.
+	generations map[string]uint64
+}
+
+// indexedKeys are the indexed keys of a bucket.
+type indexedKeys struct {
+	// keys are the visible keys of the bucket, in order.
+	keys []string
+
+	// validUntil is when the visibility of the first of the bucket's delayed
+	// or expiring keys changes, or zero if none does.
+	validUntil time.Time
+}
+
+// newKeyIndex returns an empty key index.
+func newKeyIndex() *keyIndex {
+	return &keyIndex{buckets: make(map[string]*indexedKeys), generations: make(map[string]uint64)}
+}
+
+// lookup returns the indexed keys of the bucket, if they are up to date,
+// along with the bucket's generation.
+func (i *keyIndex) lookup(bucket string, now time.Time) (*indexedKeys, uint64) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	indexed := i.buckets[bucket]
+	if indexed != nil && !indexed.validUntil.IsZero() && !now.Before(indexed.validUntil) {
+		indexed = nil
+	}
+
+	return indexed, i.generations[bucket]
+}
+
+// store keeps the keys of the bucket, unless a write was committed to it
+// since the given generation.
+func (i *keyIndex) store(bucket string, generation uint64, indexed *indexedKeys) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.generations[bucket] == generation {
+		i.buckets[bucket] = indexed
+	}
+}
+
+// invalidate forgets the keys of the bucket. It is a no-op on a nil index.
+func (i *keyIndex) invalidate(bucket string) {
+	if i == nil {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.generations[bucket]++
+	delete(i.buckets, bucket)
+}
+
+// update adds the key to, or removes it from, the indexed keys of the
+// bucket, depending on whether it is visible in the store, once a write to
+// it is committed. It is a no-op on a nil index.
+//
+// As the key is looked up in the store, rather than in the transaction
+// which wrote it, the index ends up up to date whichever order the writes
+// to the key are committed and reported in.
+func (i *keyIndex) update(handle *bolt.DB, bucket string, key []byte) {
+	if i == nil {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	// Discard the keys indexed while the write was committed.
+	i.generations[bucket]++
+
+	indexed := i.buckets[bucket]
+	if indexed == nil {
+		return
+	}
+
+	var (
+		visible  bool
+		deadline time.Time
+	)
+
+	err := handle.View(func(tx *bolt.Tx) error {
+		values := tx.Bucket([]byte(bucket))
+		if values == nil {
+			return NewError(BucketNotFoundError, "bucket "+bucket+" not found")
+		}
+
+		keyVisibility := newVisibility(tx, []byte(bucket))
+		visible = values.Get(key) != nil && !keyVisibility.hidden(key)
+
+		for _, deadlines := range []*bolt.Bucket{keyVisibility.delayed, keyVisibility.expiry} {
+			if next, found := expiryOf(deadlines, key); found && next.After(keyVisibility.now) {
+				if deadline.IsZero() || next.Before(deadline) {
+					deadline = next
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		delete(i.buckets, bucket)
+		return
+	}
+
+	i.buckets[bucket] = indexed.with(string(key), visible, deadline)
+}
+
+// with returns a copy of the indexed keys, which are read without holding
+// the index's lock, holding the key or not, and whose visibility changes
+// by the deadline, unless it is zero.
+func (indexed *indexedKeys) with(key string, visible bool, deadline time.Time) *indexedKeys {
+	updated := &indexedKeys{validUntil: indexed.validUntil}
+	if !deadline.IsZero() && (updated.validUntil.IsZero() || deadline.Before(updated.validUntil)) {
+		updated.validUntil = deadline
+	}
+
+	at := sort.SearchStrings(indexed.keys, key)
+	found := at < len(indexed.keys) && indexed.keys[at] == key
+
+	switch {
+	case visible && !found:
+		updated.keys = make([]string, 0, len(indexed.keys)+1)
+		updated.keys = append(updated.keys, indexed.keys[:at]...)
+		updated.keys = append(updated.keys, key)
+		updated.keys = append(updated.keys, indexed.keys[at:]...)
+	case !visible && found:
+		updated.keys = make([]string, 0, len(indexed.keys)-1)
+		updated.keys = append(updated.keys, indexed.keys[:at]...)
+		updated.keys = append(updated.keys, indexed.keys[at+1:]...)
+	default:
+		updated.keys = indexed.keys
+	}
+
+	return updated
+}
+
+// updateIndex adds the key to, or removes it from, the indexed keys of the
+// KV instance's bucket, once the transaction writing it is committed.
+func (k *KV) updateIndex(tx *bolt.Tx, key []byte) {
+	if k.db.index == nil {
+		return
+	}
+
+	handle, bucket, key := k.db.handle, string(k.bucket), bytes.Clone(key)
+	tx.OnCommit(func() {
+		k.db.index.update(handle, bucket, key)
+	})
+}
+
+// invalidateIndex forgets the indexed keys of the bucket, once the
+// transaction is committed.
+func (k *KV) invalidateIndex(tx *bolt.Tx, bucket []byte) {
+	if k.db.index == nil {
+		return
+	}
+
+	name := string(bucket)
+	tx.OnCommit(func() {
+		k.db.index.invalidate(name)
+	})
+}
+
+// indexedKeys returns the visible keys of the KV instance's bucket starting
+// with the prefix, in order, indexing them first if needed. It reports false
+// if the store is not opened with the KeyIndex option.
+//
+// It must be called from within KV.limit, rather than a transaction.
+func (k *KV) indexedKeys(prefix string) ([]string, bool, error) {
+	if k.db.index == nil {
+		return nil, false, nil
+	}
+
+	indexed, generation := k.db.index.lookup(string(k.bucket), time.Now())
+	if indexed == nil {
+		err := k.db.handle.View(func(tx *bolt.Tx) error {
+			var err error
+			indexed, err = k.indexKeys(tx)
+
+			return err
+		})
+		if err != nil {
+			return nil, true, err
+		}
+
+		k.db.index.store(string(k.bucket), generation, indexed)
+	}
+
+	return prefixRange(indexed.keys, prefix), true, nil
+}
+
+// indexKeys reads the visible keys of the KV instance's bucket, and when the
+// visibility of the first of its delayed or expiring keys changes.
+func (k *KV) indexKeys(tx *bolt.Tx) (*indexedKeys, error) {
+	bucket := tx.Bucket(k.bucket)
+	if bucket == nil {
+		return nil, NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+	}
+
+	visible := newVisibility(tx, k.bucket)
+	indexed := &indexedKeys{}
+
+	_ = bucket.ForEach(func(key, _ []byte) error {
+		if !visible.hidden(key) {
+			indexed.keys = append(indexed.keys, string(key))
+		}
+
+		return nil
+	})
+
+	for _, deadlines := range []*bolt.Bucket{visible.delayed, visible.expiry} {
+		if deadlines == nil {
+			continue
+		}
+
+		_ = deadlines.ForEach(func(_, raw []byte) error {
+			if len(raw) != 8 {
+				return nil
+			}
+
+			deadline := time.Unix(0, int64(binary.BigEndian.Uint64(raw)))
+			if deadline.After(visible.now) && (indexed.validUntil.IsZero() || deadline.Before(indexed.validUntil)) {
+				indexed.validUntil = deadline
+			}
+
+			return nil
+		})
+	}
+
+	return indexed, nil
+}
+
+// prefixRange returns the sorted keys starting with the prefix.
+func prefixRange(keys []string, prefix string) []string {
+	start := sort.SearchStrings(keys, prefix)
+	end := start + sort.Search(len(keys)-start, func(i int) bool {
+		return !strings.HasPrefix(keys[start+i], prefix)
+	})
+
+	return keys[start:end]
+}
+
+// sampleIndexed returns up to n distinct keys picked uniformly at random
+// among the sorted keys, with Floyd's algorithm.
+func sampleIndexed(keys []string, n int) []string {
+	if n >= len(keys) {
+		return append([]string{}, keys...)
+	}
+
+	picked := make(map[int]bool, n)
+	sampled := make([]string, 0, n)
+
+	for j := len(keys) - n; j < len(keys); j++ {
+		//nolint:gosec // Sampling keys does not need a cryptographically secure source.
+		i := rand.Intn(j + 1)
+		if picked[i] {
+			i = j
+		}
+
+		picked[i] = true
+		sampled = append(sampled, keys[i])
+	}
+
+	return sampled
+}
+
+// viewKeys runs indexed with the indexed keys starting with the prefix, when
+// the store is opened with the KeyIndex option, or fn within a read-only
+// transaction otherwise, within the limits of in-flight operations.
+func (k *KV) viewKeys(prefix string, indexed func(keys []string) error, fn func(tx *bolt.Tx) error) error {
+	return k.limit(func() error {
+		k.flushFor(func(buffered string) bool { return strings.HasPrefix(buffered, prefix) })
+		k.db.stats.reads.Add(1)
+
+		keys, isIndexed, err := k.indexedKeys(prefix)
+		if !isIndexed {
+			return k.db.handle.View(fn)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return indexed(keys)
+	})
+}
+
+// RebuildKeyIndex indexes the keys of the bucket again, when the store is
+// opened with the keyIndex option, and resolves with the number of keys
+// indexed. The index is kept up to date on its own, so this is only needed
+// to recover from the store's file being changed by other means.
+func (k *KV) RebuildKeyIndex() *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	go func() {
+		var count int
+
+		err := k.limit(func() error {
+			k.flushFor(nil)
+			k.db.index.invalidate(string(k.bucket))
+
+			keys, _, err := k.indexedKeys("")
+			count = len(keys)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(count)
+	}()
+
+	return promise
+}
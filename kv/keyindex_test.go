@@ -0,0 +1,111 @@
+package kv
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestPrefixRange(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{"order:1", "user:1", "user:2", "userx"}
+
+	assert.Equal(t, []string{"user:1", "user:2"}, prefixRange(keys, "user:"))
+	assert.Equal(t, keys, prefixRange(keys, ""))
+	assert.Empty(t, prefixRange(keys, "product:"))
+}
+
+func TestSampleIndexed(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{"a", "b", "c", "d", "e"}
+
+	sampled := sampleIndexed(keys, 3)
+	assert.Len(t, sampled, 3)
+
+	distinct := make(map[string]bool)
+	for _, key := range sampled {
+		assert.Contains(t, keys, key)
+		distinct[key] = true
+	}
+	assert.Len(t, distinct, 3)
+
+	assert.ElementsMatch(t, keys, sampleIndexed(keys, 10))
+}
+
+func TestKVIndexedKeys(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{KeyIndex: true})
+	set := func(key string, ttl time.Duration) {
+		require.NoError(t, kv.mutate(mutation{op: "set", key: []byte(key)}, func(tx *bolt.Tx) error {
+			if err := kv.storeValue(tx, tx.Bucket(kv.bucket), []byte(key), []byte(`1`)); err != nil {
+				return err
+			}
+
+			if ttl > 0 {
+				return expire(tx, kv.bucket, []byte(key), ttl)
+			}
+
+			return nil
+		}))
+	}
+
+	set("user:2", 0)
+	set("user:1", 0)
+
+	keys, indexed, err := kv.indexedKeys("user:")
+	require.NoError(t, err)
+	assert.True(t, indexed)
+	assert.Equal(t, []string{"user:1", "user:2"}, keys)
+
+	// Writes are seen once committed, without indexing the keys again
+	set("user:3", 100*time.Millisecond)
+
+	indexedKeys, _ := kv.db.index.lookup(string(kv.bucket), time.Now())
+	require.NotNil(t, indexedKeys)
+	assert.Equal(t, []string{"user:1", "user:2", "user:3"}, indexedKeys.keys)
+	assert.False(t, indexedKeys.validUntil.IsZero())
+
+	require.NoError(t, kv.mutate(mutation{op: "delete", key: []byte("user:2")}, func(tx *bolt.Tx) error {
+		return kv.removeValue(tx, tx.Bucket(kv.bucket), []byte("user:2"))
+	}))
+
+	require.NoError(t, kv.mutate(mutation{op: "setDelayed", key: []byte("user:4")}, func(tx *bolt.Tx) error {
+		delayed, err := tx.CreateBucketIfNotExists(delayedBucket(kv.bucket))
+		if err != nil {
+			return err
+		}
+
+		deadline := make([]byte, 8)
+		binary.BigEndian.PutUint64(deadline, uint64(time.Now().Add(time.Hour).UnixNano()))
+
+		if err := delayed.Put([]byte("user:4"), deadline); err != nil {
+			return err
+		}
+
+		return kv.storeValue(tx, tx.Bucket(kv.bucket), []byte("user:4"), []byte(`1`))
+	}))
+
+	indexedKeys, _ = kv.db.index.lookup(string(kv.bucket), time.Now())
+	require.NotNil(t, indexedKeys)
+	assert.Equal(t, []string{"user:1", "user:3"}, indexedKeys.keys)
+
+	set("user:2", 0)
+
+	keys, _, err = kv.indexedKeys("user:")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:1", "user:2", "user:3"}, keys)
+
+	// Expired keys are hidden once their deadline passes
+	time.Sleep(150 * time.Millisecond)
+
+	keys, _, err = kv.indexedKeys("user:")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:1", "user:2"}, keys)
+}
@@ -0,0 +1,65 @@
+package kv
+
+import (
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// validateKey checks that a key can be written to the store, according
+// to the key validation rules the KV instance was opened with.
+func (k *KV) validateKey(key []byte) error {
+	if len(key) == 0 {
+		return NewError(KeyRequiredError, "key must not be empty")
+	}
+
+	if k.options.MaxKeyLength > 0 && int64(len(key)) > k.options.MaxKeyLength {
+		return NewError(
+			KeyTooLargeError,
+			"key "+strconv.Quote(string(key))+" is "+strconv.Itoa(len(key))+
+				" bytes long, exceeding the maximum of "+strconv.FormatInt(k.options.MaxKeyLength, 10),
+		)
+	}
+
+	if k.options.RejectControlCharacters && hasControlCharacters(key) {
+		return NewError(InvalidKeyError, "key "+strconv.Quote(string(key))+" contains control characters")
+	}
+
+	if k.options.keyPattern != nil && !k.options.keyPattern.Match(key) {
+		return NewError(
+			InvalidKeyError,
+			"key "+strconv.Quote(string(key))+" does not match the pattern "+k.options.KeyPattern,
+		)
+	}
+
+	return nil
+}
+
+// validateValue checks that the encoded value of a key can be written to the
+// store, according to the MaxValueSize option the KV instance was opened with.
+func (k *KV) validateValue(key, value []byte) error {
+	if k.options.MaxValueSize > 0 && int64(len(value)) > k.options.MaxValueSize {
+		return NewError(
+			ValueTooLargeError,
+			"value of key "+strconv.Quote(string(key))+" is "+strconv.Itoa(len(value))+
+				" bytes long, exceeding the maximum of "+strconv.FormatInt(k.options.MaxValueSize, 10),
+		)
+	}
+
+	return nil
+}
+
+// hasControlCharacters reports whether the key contains control characters,
+// or bytes which are not valid UTF-8.
+func hasControlCharacters(key []byte) bool {
+	for len(key) > 0 {
+		r, size := utf8.DecodeRune(key)
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			return true
+		}
+
+		key = key[size:]
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package kv
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		options  Options
+		key      string
+		wantName ErrorName
+	}{
+		{name: "any non-empty key is accepted by default", key: "foo\nbar"},
+		{name: "empty keys are rejected", key: "", wantName: KeyRequiredError},
+		{name: "keys within the length limit are accepted", options: Options{MaxKeyLength: 3}, key: "foo"},
+		{name: "keys exceeding the length limit are rejected", options: Options{MaxKeyLength: 3}, key: "fooo", wantName: KeyTooLargeError},
+		{name: "control characters are rejected if configured", options: Options{RejectControlCharacters: true}, key: "foo\x00", wantName: InvalidKeyError},
+		{name: "invalid UTF-8 is rejected with control characters", options: Options{RejectControlCharacters: true}, key: "foo\xff", wantName: InvalidKeyError},
+		{name: "printable keys are accepted with control characters rejected", options: Options{RejectControlCharacters: true}, key: "user:é"},
+		{name: "keys matching the pattern are accepted", options: Options{KeyPattern: "^user:[0-9]+$"}, key: "user:42"},
+		{name: "keys not matching the pattern are rejected", options: Options{KeyPattern: "^user:[0-9]+$"}, key: "user:x", wantName: InvalidKeyError},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tt.options.KeyPattern != "" {
+				tt.options.keyPattern = regexp.MustCompile(tt.options.KeyPattern)
+			}
+
+			kv := &KV{options: tt.options}
+			gotErr := kv.validateKey([]byte(tt.key))
+
+			if tt.wantName == "" {
+				assert.NoError(t, gotErr)
+				return
+			}
+
+			var kvErr *Error
+			require.ErrorAs(t, gotErr, &kvErr)
+			assert.Equal(t, tt.wantName, kvErr.Name)
+		})
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	t.Parallel()
+
+	kv := &KV{options: Options{MaxValueSize: 5}}
+
+	assert.NoError(t, kv.validateValue([]byte("foo"), []byte(`"bar"`)))
+
+	var kvErr *Error
+	require.ErrorAs(t, kv.validateValue([]byte("foo"), []byte(`"barr"`)), &kvErr)
+	assert.Equal(t, ErrorName(ValueTooLargeError), kvErr.Name)
+
+	assert.NoError(t, (&KV{}).validateValue([]byte("foo"), []byte(`"unlimited by default"`)))
+}
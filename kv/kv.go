@@ -1,16 +1,20 @@
 package kv
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/grafana/sobek"
 	bolt "go.etcd.io/bbolt"
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules"
 	"go.k6.io/k6/js/promises"
+	"go.k6.io/k6/metrics"
 )
 
 // KV is a key-value database that can be used to store and retrieve data.
@@ -29,6 +33,33 @@ type KV struct {
 
 	// vu is the VU instance that this KV instance belongs to.
 	vu modules.VU
+
+	// options are the options this KV instance was opened with.
+	options Options
+
+	// boundMetrics holds the k6 metrics bound to keys of the store.
+	boundMetrics map[string][]*metrics.Metric
+
+	// heartbeat holds the state of the VU's roster registration.
+	heartbeat heartbeat
+
+	// limiter limits the number of operations the VU runs concurrently.
+	limiter *opLimiter
+
+	// codecs are the hooks registered to convert values to and from JSON.
+	codecs []codec
+
+	// churnMetrics are the k6 metrics the changes made to keys are reported to,
+	// if the store was opened in the init context.
+	churnMetrics *churnMetrics
+
+	// clearEachIteration starts deleting the keys of the iteration scope
+	// at the end of every iteration, once.
+	clearEachIteration sync.Once
+
+	// replaying is true for the KV instances KV.Replay applies operations
+	// with, which are not recorded to the operation log again.
+	replaying bool
 }
 
 // NewKV returns a new KV instance.
@@ -43,7 +74,8 @@ func NewKV(vu modules.VU, db *db) *KV {
 // Set sets the value of a key in the store.
 //
 // If the key does not exist, it is created. If the key already exists, its value is overwritten.
-func (k *KV) Set(key sobek.Value, value sobek.Value) *sobek.Promise {
+// See [SetOptions] for how to set a TTL on the key.
+func (k *KV) Set(key sobek.Value, value sobek.Value, options sobek.Value) *sobek.Promise {
 	promise, resolve, reject := promises.New(k.vu)
 
 	// Convert the key to a byte slice
@@ -53,35 +85,79 @@ func (k *KV) Set(key sobek.Value, value sobek.Value) *sobek.Promise {
 		return promise
 	}
 
-	jsonValue, err := json.Marshal(value.Export())
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonValue, err := k.marshal(keyBytes, value)
 	if err != nil {
 		reject(err)
 		return promise
 	}
 
-	go func() {
-		// Update the value in the database within a BoltDB transaction
-		err := k.db.handle.Update(func(tx *bolt.Tx) error {
-			bucket := tx.Bucket(k.bucket)
-			if bucket == nil {
-				return fmt.Errorf("bucket not found")
-			}
+	setOptions, err := ImportSetOptions(k.vu.Runtime(), options)
+	if err != nil {
+		reject(err)
+		return promise
+	}
 
-			return bucket.Put(keyBytes, jsonValue)
-		})
-		if err != nil {
+	k.db.workers.Load().run(func() {
+		if err := k.set(keyBytes, jsonValue, setOptions); err != nil {
 			reject(err)
 			return
 		}
 
 		resolve(value)
-	}()
+	})
 
 	return promise
 }
 
+// set sets the JSON-encoded value of a key in the store.
+func (k *KV) set(key, jsonValue []byte, setOptions SetOptions) error {
+	// Update the value in the database within a BoltDB transaction
+	return k.mutateLater(mutation{op: "set", key: key, value: jsonValue, batch: true}, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(k.bucket)
+		if bucket == nil {
+			return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+		}
+
+		if err := undelay(tx, k.bucket, key); err != nil {
+			return err
+		}
+
+		if err := k.storeValue(tx, bucket, key, jsonValue); err != nil {
+			return err
+		}
+
+		if setOptions.TTL > 0 {
+			return k.expireKey(tx, key, time.Now().Add(setOptions.TTL))
+		}
+
+		return nil
+	})
+}
+
 // Get returns the value of a key in the store.
 func (k *KV) Get(key sobek.Value) *sobek.Promise {
+	return k.get(key, nil)
+}
+
+// GetOrDefault returns the value of a key in the store, or the fallback
+// value if the key does not exist, rather than rejecting.
+func (k *KV) GetOrDefault(key sobek.Value, fallback sobek.Value) *sobek.Promise {
+	if fallback == nil {
+		fallback = sobek.Undefined()
+	}
+
+	return k.get(key, fallback)
+}
+
+// get returns the value of a key in the store. If the key does not exist,
+// the fallback value is returned if it is not nil, and a KeyNotFoundError
+// otherwise.
+func (k *KV) get(key sobek.Value, fallback sobek.Value) *sobek.Promise {
 	promise, resolve, reject := promises.New(k.vu)
 
 	// Convert the key to a byte slice
@@ -91,40 +167,65 @@ func (k *KV) Get(key sobek.Value) *sobek.Promise {
 		return promise
 	}
 
-	go func() {
-		var jsonValue []byte
+	settle := k.reviveLater(resolve, reject)
 
-		// Get the value from the database within a BoltDB transaction
-		err := k.db.handle.View(func(tx *bolt.Tx) error {
-			bucket := tx.Bucket(k.bucket)
-			if bucket == nil {
-				return fmt.Errorf("bucket not found")
+	k.db.workers.Load().run(func() {
+		jsonValue, err := k.lookup(keyBytes)
+
+		missing := err == nil && jsonValue == nil
+		if missing && fallback == nil {
+			err = NewError(KeyNotFoundError, "key "+string(keyBytes)+" not found")
+		}
+
+		var value any
+		if err == nil && !missing {
+			err = decodeValue(jsonValue, &value)
+		}
+
+		settle(func() (any, error) {
+			if err != nil {
+				return nil, err
 			}
 
-			jsonValue = bucket.Get(keyBytes)
+			if missing {
+				return fallback, nil
+			}
 
-			return nil
+			return k.revive(keyBytes, value)
 		})
-		if err != nil {
-			reject(err)
-			return
-		}
+	})
 
-		if jsonValue == nil {
-			reject(NewError(KeyNotFoundError, "key "+key.String()+" not found"))
-			return
+	return promise
+}
+
+// lookup returns a copy of the JSON-encoded value of a key in the store,
+// or nil if it does not exist, and counts the lookup in the store's stats.
+func (k *KV) lookup(key []byte) ([]byte, error) {
+	var jsonValue []byte
+
+	// Get the value from the database within a BoltDB transaction
+	err := k.viewKey(key, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(k.bucket)
+		if bucket == nil {
+			return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
 		}
 
-		var value any
-		if err := json.Unmarshal(jsonValue, &value); err != nil {
-			reject(err)
-			return
+		if newVisibility(tx, k.bucket).hidden(key) {
+			return nil
 		}
 
-		resolve(k.vu.Runtime().ToValue(value))
-	}()
+		// Copy the value, as the memory it points to
+		// is only valid within the transaction.
+		value, err := loadValue(tx, bucket.Get(key))
+		jsonValue = bytes.Clone(value)
 
-	return promise
+		return err
+	})
+	if err == nil {
+		k.db.stats.lookup(jsonValue)
+	}
+
+	return jsonValue, err
 }
 
 // Delete deletes a key from the store.
@@ -137,14 +238,18 @@ func (k *KV) Delete(key sobek.Value) *sobek.Promise {
 		return promise
 	}
 
-	go func() {
-		err := k.db.handle.Update(func(tx *bolt.Tx) error {
+	k.db.workers.Load().run(func() {
+		err := k.mutateLater(mutation{op: "delete", key: keyBytes, batch: true}, func(tx *bolt.Tx) error {
 			bucket := tx.Bucket(k.bucket)
 			if bucket == nil {
 				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
 			}
 
-			return bucket.Delete(keyBytes)
+			if err := undelay(tx, k.bucket, keyBytes); err != nil {
+				return err
+			}
+
+			return k.removeValue(tx, bucket, keyBytes)
 		})
 		if err != nil {
 			reject(err)
@@ -152,7 +257,7 @@ func (k *KV) Delete(key sobek.Value) *sobek.Promise {
 		}
 
 		resolve(true)
-	}()
+	})
 
 	return promise
 }
@@ -160,54 +265,81 @@ func (k *KV) Delete(key sobek.Value) *sobek.Promise {
 // List returns all the key-value pairs in the store.
 //
 // The returned list is ordered lexicographically by key.
-// The returned list is limited to 1000 entries by default.
+// The returned list is limited to the defaultListLimit option's number of entries
+// by default, 1000 unless configured otherwise.
 // The returned list can be limited to a maximum number of entries by passing a limit option.
 // The returned list can be limited to keys that start with a given prefix by passing a prefix option.
+// When a cursor option is passed, a ListPage is returned instead, whose cursor reads the next page.
 // See [ListOptions] for more details
 func (k *KV) List(options sobek.Value) *sobek.Promise {
+	listOptions, err := ImportListOptions(k.vu.Runtime(), options)
+	if err != nil {
+		promise, _, reject := promises.New(k.vu)
+		reject(err)
+		return promise
+	}
+
+	return k.list(listOptions)
+}
+
+// list returns the key-value pairs in the store selected by the options.
+func (k *KV) list(listOptions ListOptions) *sobek.Promise {
 	promise, resolve, reject := promises.New(k.vu)
 
-	listOptions := ImportListOptions(k.vu.Runtime(), options)
+	if !listOptions.limitSet {
+		listOptions.Limit = k.options.DefaultListLimit
+	}
+
+	settle := k.reviveLater(resolve, reject)
 
-	go func() {
-		var entries []ListEntry
+	k.db.workers.Load().run(func() {
+		var (
+			entries []ListEntry
+			done    bool
+		)
 
-		err := k.db.handle.View(func(tx *bolt.Tx) error {
+		err := k.view(func(tx *bolt.Tx) error {
 			bucket := tx.Bucket(k.bucket)
 			if bucket == nil {
 				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
 			}
 
-			var listed int64
-			return bucket.ForEach(func(k, v []byte) error {
-				if listOptions.limitSet && listed >= listOptions.Limit {
-					return ErrStop
-				}
+			var err error
+			entries, done, err = k.listEntries(tx, bucket, listOptions)
 
-				key := string(k)
+			return err
+		})
 
-				if !strings.HasPrefix(key, listOptions.Prefix) {
-					return nil
-				}
+		settle(func() (any, error) {
+			if err != nil {
+				return nil, err
+			}
 
-				var value any
-				if err := json.Unmarshal(v, &value); err != nil {
-					return err
+			for i, entry := range entries {
+				value, err := k.revive([]byte(entry.Key), entry.Value)
+				if err != nil {
+					return nil, err
 				}
 
-				entries = append(entries, ListEntry{key, value})
-				listed++
+				entries[i].Value = value
+			}
 
-				return nil
-			})
-		})
-		if err != nil && !errors.Is(err, ErrStop) {
-			reject(err)
-			return
-		}
+			cursor := listOptions.Cursor
+			if len(entries) > 0 {
+				cursor = encodeCursor(entries[len(entries)-1].Key)
+			}
 
-		resolve(k.vu.Runtime().ToValue(entries))
-	}()
+			for i := range entries {
+				entries[i].Key = strings.TrimPrefix(entries[i].Key, listOptions.scope)
+			}
+
+			if !listOptions.paginate {
+				return entries, nil
+			}
+
+			return ListPage{Entries: entries, Cursor: cursor, Done: done}, nil
+		})
+	})
 
 	return promise
 }
@@ -224,58 +356,165 @@ type ListOptions struct {
 	// with the given prefix.
 	Prefix string `json:"prefix"`
 
-	// Limit is the maximum number of entries to return.
+	// Glob selects the keys matching the given glob pattern as a whole,
+	// such as "user:*:active".
+	Glob string `json:"glob"`
+
+	// Regex selects the keys matching the given regular expression, such
+	// as "^order-[0-9]+$".
+	Regex string `json:"regex"`
+
+	// Limit is the maximum number of entries to return. Zero means no limit.
+	// It defaults to the store's DefaultListLimit option.
 	Limit int64 `json:"limit"`
 
+	// Fields selects the fields of the object values to return. Only these
+	// fields are decoded, and the others are omitted. Values which are not
+	// objects are returned as a whole.
+	Fields []string `json:"fields"`
+
+	// Cursor is the cursor of the page to read, as returned by a previous
+	// call to KV.List(). Setting it, even to an empty string or undefined
+	// for the first page, makes KV.List() return a ListPage.
+	Cursor string `json:"cursor"`
+
 	limitSet bool
+
+	// paginate is true when the cursor option is set.
+	paginate bool
+
+	// after is the key the page to read starts after, decoded from Cursor.
+	after []byte
+
+	// scope is the prefix of the ScopedKV listing the keys, if any, which
+	// is removed from the returned keys.
+	scope string
+
+	// filters are the compiled Glob and Regex options, which the keys,
+	// without the scope, must all match.
+	filters []*regexp.Regexp
+
+	// where is the predicate the values must match, set by KV.Find.
+	where *wherePredicate
+}
+
+// matches reports whether a key matches the glob and regex options.
+func (o ListOptions) matches(key []byte) bool {
+	key = key[len(o.scope):]
+
+	for _, filter := range o.filters {
+		if !filter.Match(key) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // ErrStop is used to stop a BoltDB iteration.
 var ErrStop = errors.New("stop")
 
 // ImportListOptions instantiates a ListOptions from a sobek.Value.
-func ImportListOptions(rt *sobek.Runtime, options sobek.Value) ListOptions {
+func ImportListOptions(rt *sobek.Runtime, options sobek.Value) (ListOptions, error) {
 	listOptions := ListOptions{}
 
 	// If no options are passed, return the default options
 	if common.IsNullish(options) {
-		return listOptions
+		return listOptions, nil
 	}
 
 	// Interpret the options as an object
 	optionsObj := options.ToObject(rt)
 
-	listOptions.Prefix = optionsObj.Get("prefix").String()
+	if prefix := optionsObj.Get("prefix"); !common.IsNullish(prefix) {
+		listOptions.Prefix = prefix.String()
+	}
+
+	if glob := optionsObj.Get("glob"); !common.IsNullish(glob) {
+		filter, err := compileGlob(glob.String())
+		if err != nil {
+			return listOptions, err
+		}
+
+		listOptions.Glob = glob.String()
+		listOptions.filters = append(listOptions.filters, filter)
+	}
+
+	if regex := optionsObj.Get("regex"); !common.IsNullish(regex) {
+		filter, err := regexp.Compile(regex.String())
+		if err != nil {
+			return listOptions, fmt.Errorf("invalid regex %q: %w", regex.String(), err)
+		}
+
+		listOptions.Regex = regex.String()
+		listOptions.filters = append(listOptions.filters, filter)
+	}
+
+	if fields := optionsObj.Get("fields"); !common.IsNullish(fields) {
+		var projected []string
+		if err := rt.ExportTo(fields, &projected); err == nil {
+			listOptions.Fields = projected
+		}
+	}
+
+	// The cursor option is set as soon as the property exists,
+	// so that the first page can be read with an undefined cursor.
+	if cursor := optionsObj.Get("cursor"); cursor != nil {
+		listOptions.paginate = true
+
+		if !common.IsNullish(cursor) && cursor.String() != "" {
+			after, err := decodeCursor(cursor.String())
+			if err != nil {
+				return listOptions, fmt.Errorf("invalid cursor %q: %w", cursor.String(), err)
+			}
+
+			listOptions.Cursor = cursor.String()
+			listOptions.after = after
+		}
+	}
 
 	limitValue := optionsObj.Get("limit")
 	if limitValue == nil {
-		return listOptions
+		return listOptions, nil
 	}
 
 	var limit int64
 	err := rt.ExportTo(limitValue, &limit)
 	if err == nil {
+		if limit < 0 {
+			return listOptions, fmt.Errorf("limit must not be negative, got %d", limit)
+		}
+
 		listOptions.Limit = limit
 		listOptions.limitSet = true
 	}
 
-	return listOptions
+	return listOptions, nil
 }
 
-// Clear deletes all the keys in the store.
-func (k *KV) Clear() *sobek.Promise {
+// Clear deletes all the keys in the store, or the ones starting with the
+// prefix option, if set.
+//
+// Rather than deleting keys one by one, the bucket is dropped and recreated
+// within a single transaction, which releases its pages in bulk.
+func (k *KV) Clear(options sobek.Value) *sobek.Promise {
+	if !common.IsNullish(options) {
+		if prefix := options.ToObject(k.vu.Runtime()).Get("prefix"); !common.IsNullish(prefix) && prefix.String() != "" {
+			return k.clearPrefix([]byte(prefix.String()))
+		}
+	}
+
 	promise, resolve, reject := promises.New(k.vu)
 
-	go func() {
-		err := k.db.handle.Update(func(tx *bolt.Tx) error {
-			bucket := tx.Bucket(k.bucket)
-			if bucket == nil {
-				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+	k.db.workers.Load().run(func() {
+		err := k.mutate(mutation{op: "clear"}, func(tx *bolt.Tx) error {
+			if err := k.dropBucket(tx, k.bucket, OpLogClear); err != nil {
+				return err
 			}
 
-			return bucket.ForEach(func(k, v []byte) error {
-				return bucket.Delete(k)
-			})
+			_, err := tx.CreateBucket(k.bucket)
+
+			return err
 		})
 		if err != nil {
 			reject(err)
@@ -283,7 +522,7 @@ func (k *KV) Clear() *sobek.Promise {
 		}
 
 		resolve(true)
-	}()
+	})
 
 	return promise
 }
@@ -292,16 +531,19 @@ func (k *KV) Clear() *sobek.Promise {
 func (k *KV) Size() *sobek.Promise {
 	promise, resolve, reject := promises.New(k.vu)
 
-	go func() {
+	k.db.workers.Load().run(func() {
 		var size int64
 
-		err := k.db.handle.View(func(tx *bolt.Tx) error {
+		err := k.viewKeys("", func(keys []string) error {
+			size = int64(len(keys))
+			return nil
+		}, func(tx *bolt.Tx) error {
 			bucket := tx.Bucket(k.bucket)
 			if bucket == nil {
 				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
 			}
 
-			size = int64(bucket.Stats().KeyN)
+			size = int64(bucket.Stats().KeyN) - newVisibility(tx, k.bucket).countHidden()
 
 			return nil
 		})
@@ -311,7 +553,7 @@ func (k *KV) Size() *sobek.Promise {
 		}
 
 		resolve(size)
-	}()
+	})
 
 	return promise
 }
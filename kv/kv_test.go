@@ -0,0 +1,51 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVGetOrDefault(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	err := vu.run(`
+		const store = kv.openKv();
+
+		store.set("theme", "dark")
+			.then(() => store.setDelayed("job", "pending", "1h"))
+			.then(() => Promise.all([
+				store.getOrDefault("theme", "light"),
+				store.getOrDefault("missing", { theme: "light" }),
+				store.getOrDefault("missing"),
+				store.getOrDefault("job", "none"),
+			]))
+			.then(([existing, fallback, undefinedFallback, hidden]) => {
+				if (existing !== "dark") {
+					throw new Error("expected the existing value, got " + existing);
+				}
+
+				if (fallback.theme !== "light") {
+					throw new Error("expected the fallback value, got " + JSON.stringify(fallback));
+				}
+
+				if (undefinedFallback !== undefined) {
+					throw new Error("expected undefined without a fallback, got " + undefinedFallback);
+				}
+
+				if (hidden !== "none") {
+					throw new Error("expected the fallback value for hidden keys, got " + hidden);
+				}
+
+				return store.exists("missing");
+			})
+			.then((exists) => {
+				if (exists) {
+					throw new Error("expected the fallback value not to be set");
+				}
+			});
+	`)
+	require.NoError(t, err)
+}
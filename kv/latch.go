@@ -0,0 +1,186 @@
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// LatchesBucket is the name of the internal bucket holding the state
+// of the countdown latches.
+const LatchesBucket = "k6/latches"
+
+// Latch is a countdown latch shared between all VUs.
+//
+// A latch is initialized with a count, which is decremented by calls to
+// CountDown. Calls to Wait block until the count reaches zero.
+type Latch struct {
+	// name is the key the latch's state is stored under.
+	name []byte
+
+	// count is the initial count of the latch.
+	count int64
+
+	// kv is the KV instance the latch's state is stored through.
+	kv *KV
+}
+
+// Latch returns the countdown latch with the given name.
+//
+// The latch is created with the given count the first time it is counted
+// down or waited on. Subsequent calls with the same name, from any VU, share
+// the same latch, and their count argument is ignored.
+func (k *KV) Latch(name sobek.Value, count sobek.Value) *sobek.Object {
+	rt := k.vu.Runtime()
+
+	if common.IsNullish(name) || name.String() == "" {
+		common.Throw(rt, NewError(KeyRequiredError, "latch name is required"))
+		return nil
+	}
+
+	if common.IsNullish(count) || count.ToInteger() < 0 {
+		common.Throw(rt, fmt.Errorf("latch count must be a non-negative integer, got %v", count))
+		return nil
+	}
+
+	latch := &Latch{
+		name:  []byte(name.String()),
+		count: count.ToInteger(),
+		kv:    k,
+	}
+
+	return rt.ToValue(latch).ToObject(rt)
+}
+
+// CountDown decrements the latch's count, and resolves with the remaining count.
+//
+// Counting down a latch that has already reached zero has no effect. The
+// count is decremented in dry-run mode as well, so that latches open.
+func (l *Latch) CountDown() *sobek.Promise {
+	promise, resolve, reject := promises.New(l.kv.vu)
+
+	go func() {
+		var remaining int64
+
+		err := l.kv.coordinate(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(LatchesBucket))
+			if err != nil {
+				return err
+			}
+
+			remaining, err = l.load(bucket)
+			if err != nil {
+				return err
+			}
+
+			if remaining > 0 {
+				remaining--
+			}
+
+			return l.store(bucket, remaining)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(remaining)
+	}()
+
+	return promise
+}
+
+// Count resolves with the latch's current count.
+func (l *Latch) Count() *sobek.Promise {
+	promise, resolve, reject := promises.New(l.kv.vu)
+
+	go func() {
+		remaining, err := l.current()
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(remaining)
+	}()
+
+	return promise
+}
+
+// Wait resolves once the latch's count reaches zero.
+//
+// If a timeout is provided, either as a number of milliseconds or as a
+// duration string such as "30s", the returned promise is rejected with
+// a TimeoutError if the count has not reached zero by then.
+func (l *Latch) Wait(timeout sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(l.kv.vu)
+
+	waitTimeout, err := toDuration(timeout)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		err := pollUntil(l.kv.vu.Context(), waitTimeout, func() (bool, error) {
+			remaining, err := l.current()
+			return remaining == 0, err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(true)
+	}()
+
+	return promise
+}
+
+// current returns the latch's current count.
+func (l *Latch) current() (int64, error) {
+	remaining := l.count
+
+	err := l.kv.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(LatchesBucket))
+		if bucket == nil {
+			return nil
+		}
+
+		var err error
+		remaining, err = l.load(bucket)
+
+		return err
+	})
+
+	return remaining, err
+}
+
+// load reads the latch's count from the bucket, defaulting to its initial count.
+func (l *Latch) load(bucket *bolt.Bucket) (int64, error) {
+	raw := bucket.Get(l.name)
+	if raw == nil {
+		return l.count, nil
+	}
+
+	var remaining int64
+	if err := json.Unmarshal(raw, &remaining); err != nil {
+		return 0, fmt.Errorf("failed to decode latch %s: %w", l.name, err)
+	}
+
+	return remaining, nil
+}
+
+// store writes the latch's count to the bucket.
+func (l *Latch) store(bucket *bolt.Bucket, remaining int64) error {
+	raw, err := json.Marshal(remaining)
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put(l.name, raw)
+}
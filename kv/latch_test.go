@@ -0,0 +1,106 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("waiters resolve once the count reaches zero", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+			const latch = store.latch("ready", 2);
+
+			const waiting = latch.wait("5s");
+
+			latch.countDown()
+				.then((remaining) => {
+					if (remaining !== 1) {
+						throw new Error("expected a count of 1, got " + remaining);
+					}
+
+					// Latches are shared by name, whatever count they are returned with.
+					return store.latch("ready", 10).count();
+				})
+				.then((count) => {
+					if (count !== 1) {
+						throw new Error("expected the latch to be shared, got a count of " + count);
+					}
+
+					return Promise.all([latch.countDown(), latch.countDown()]);
+				})
+				.then((remaining) => {
+					if (remaining[0] !== 0 || remaining[1] !== 0) {
+						throw new Error("expected the count to stop at zero, got " + remaining);
+					}
+
+					return waiting;
+				})
+				.then((opened) => {
+					if (opened !== true) {
+						throw new Error("expected wait to resolve with true, got " + opened);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("latches open in dry-run mode", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv({ dryRun: true });
+			const latch = store.latch("ready", 1);
+
+			latch.countDown().then(() => latch.wait("5s"));
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("waiters time out when the count does not reach zero", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.latch("ready", 1).wait(50)
+				.then(
+					() => { throw new Error("expected the latch not to open"); },
+					(err) => {
+						if (String(err.name) !== "TimeoutError") {
+							throw new Error("expected a TimeoutError, got " + JSON.stringify(err));
+						}
+					},
+				);
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("latches of stores opened in the sharedReadOnly mode cannot be counted down", func(t *testing.T) {
+		t.Parallel()
+
+		kv := openTestKV(t, Options{})
+		kv.db.readOnly = true
+
+		latch := &Latch{name: []byte("ready"), count: 1, kv: kv}
+
+		remaining, err := latch.current()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), remaining)
+
+		var kvErr *Error
+		require.ErrorAs(t, kv.coordinate(nil), &kvErr)
+		require.Equal(t, ErrorName(ReadOnlyError), kvErr.Name)
+	})
+}
@@ -0,0 +1,137 @@
+package kv
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// opLimiter limits the number of operations running concurrently, queueing
+// the operations in excess until one completes.
+//
+// A nil opLimiter does not limit anything.
+type opLimiter struct {
+	// slots holds a value per running operation.
+	slots chan struct{}
+
+	// queued is the number of operations waiting for a slot.
+	queued atomic.Int64
+
+	// maxQueued is the maximum number of operations waiting for a slot.
+	// Zero means no limit.
+	maxQueued int64
+}
+
+// newOpLimiter returns an opLimiter allowing up to maxInFlight concurrent
+// operations, or nil if maxInFlight is zero.
+func newOpLimiter(maxInFlight, maxQueued int64) *opLimiter {
+	if maxInFlight <= 0 {
+		return nil
+	}
+
+	return &opLimiter{slots: make(chan struct{}, maxInFlight), maxQueued: maxQueued}
+}
+
+// acquire waits for a slot to be available, and takes it.
+//
+// It fails with a TooManyOperationsError if too many operations are queued
+// already, and with the context's error if it is done first.
+func (l *opLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if queued := l.queued.Add(1); l.maxQueued > 0 && queued > l.maxQueued {
+		l.queued.Add(-1)
+
+		return NewError(
+			TooManyOperationsError,
+			"too many operations in flight, and "+strconv.FormatInt(l.maxQueued, 10)+" queued already",
+		)
+	}
+	defer l.queued.Add(-1)
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the slot taken by a completed operation.
+func (l *opLimiter) release() {
+	if l == nil {
+		return
+	}
+
+	<-l.slots
+}
+
+// limit runs fn once both the VU's and the store's limits of in-flight
+// operations allow it.
+//
+// The writes buffered by the store are not applied first: operations which
+// need to see them call KV.flushFor. The errors of the store are returned
+// as the matching typed errors.
+func (k *KV) limit(fn func() error) error {
+	k.db.swap.RLock()
+	defer k.db.swap.RUnlock()
+
+	if k.db.handle == nil {
+		return NewError(DatabaseNotOpenError, "the store is closed")
+	}
+
+	if k.limiter == nil && k.db.limiter == nil {
+		return asError(fn())
+	}
+
+	// Go code accessing the store through a Store has no VU.
+	ctx := context.Background()
+	if k.vu != nil {
+		ctx = k.vu.Context()
+	}
+
+	if err := k.limiter.acquire(ctx); err != nil {
+		return err
+	}
+	defer k.limiter.release()
+
+	if err := k.db.limiter.acquire(ctx); err != nil {
+		return err
+	}
+	defer k.db.limiter.release()
+
+	return asError(fn())
+}
+
+// view runs fn within a read-only transaction, within the limits
+// of in-flight operations.
+func (k *KV) view(fn func(tx *bolt.Tx) error) error {
+	return k.limit(func() error {
+		k.flushFor(nil)
+		k.db.stats.reads.Add(1)
+
+		return k.db.handle.View(fn)
+	})
+}
+
+// viewKey runs fn within a read-only transaction reading the key alone,
+// within the limits of in-flight operations. Unlike KV.view, it only
+// applies the buffered writes first if one of them is to the key.
+func (k *KV) viewKey(key []byte, fn func(tx *bolt.Tx) error) error {
+	return k.limit(func() error {
+		k.flushFor(func(buffered string) bool { return buffered == string(key) })
+		k.db.stats.reads.Add(1)
+
+		return k.db.handle.View(fn)
+	})
+}
@@ -0,0 +1,67 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil limiter does not limit", func(t *testing.T) {
+		t.Parallel()
+
+		var limiter *opLimiter
+		assert.Nil(t, newOpLimiter(0, 0))
+		assert.NoError(t, limiter.acquire(context.Background()))
+		limiter.release()
+	})
+
+	t.Run("operations in excess are queued", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := newOpLimiter(1, 0)
+		require.NoError(t, limiter.acquire(context.Background()))
+
+		acquired := make(chan error)
+		go func() {
+			acquired <- limiter.acquire(context.Background())
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("expected the operation to be queued")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		limiter.release()
+		assert.NoError(t, <-acquired)
+	})
+
+	t.Run("operations overflowing the queue are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		limiter := newOpLimiter(1, 1)
+		require.NoError(t, limiter.acquire(context.Background()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		queued := make(chan error)
+		go func() {
+			queued <- limiter.acquire(ctx)
+		}()
+
+		require.Eventually(t, func() bool { return limiter.queued.Load() == 1 }, time.Second, time.Millisecond)
+
+		var kvErr *Error
+		require.True(t, errors.As(limiter.acquire(context.Background()), &kvErr))
+		assert.Equal(t, ErrorName(TooManyOperationsError), kvErr.Name)
+
+		cancel()
+		assert.ErrorIs(t, <-queued, context.Canceled)
+	})
+}
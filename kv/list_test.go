@@ -0,0 +1,63 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVDefaultListLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists are limited to the default limit unless a limit is passed", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			const entries = {};
+			for (let i = 0; i < 1001; i++) {
+				entries["key-" + String(i).padStart(4, "0")] = i;
+			}
+
+			store.setMany(entries)
+				.then(() => Promise.all([store.list(), store.keys(), store.list({ limit: 0 }), store.list({ limit: 5 })]))
+				.then(([listed, keys, unlimited, limited]) => {
+					if (listed.length !== 1000 || keys.length !== 1000) {
+						throw new Error("expected 1000 entries by default, got " + listed.length + " and " + keys.length);
+					}
+
+					if (unlimited.length !== 1001 || limited.length !== 5) {
+						throw new Error("expected the passed limits to apply, got " + unlimited.length + " and " + limited.length);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("the default limit is configurable", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const limited = kv.openKv({ defaultListLimit: 2 });
+			const unlimited = kv.openKv({ defaultListLimit: 0 });
+
+			limited.setMany({ a: 1, b: 2, c: 3 })
+				.then(() => Promise.all([limited.list(), limited.keys(), unlimited.list()]))
+				.then(([listed, keys, all]) => {
+					if (listed.length !== 2 || keys.length !== 2) {
+						throw new Error("expected 2 entries, got " + listed.length + " and " + keys.length);
+					}
+
+					if (all.length !== 3) {
+						throw new Error("expected a default limit of 0 to mean no limit, got " + all.length);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+}
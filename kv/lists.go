@@ -0,0 +1,169 @@
+package kv
+
+import (
+	"encoding/json"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// Lpush atomically prepends a value to the list held by a key, creating it
+// if the key does not exist, and resolves with the length of the list.
+//
+// Lists are stored as JSON arrays, so that KV.Get returns them as arrays.
+// Used with Rpop, or Rpush with Lpop, they form queues, and used with Lpop,
+// stacks.
+func (k *KV) Lpush(key sobek.Value, value sobek.Value) *sobek.Promise {
+	return k.pushList("lpush", key, value, func(list []json.RawMessage, value []byte) []json.RawMessage {
+		return append([]json.RawMessage{value}, list...)
+	})
+}
+
+// Rpush atomically appends a value to the list held by a key, creating it
+// if the key does not exist, and resolves with the length of the list.
+func (k *KV) Rpush(key sobek.Value, value sobek.Value) *sobek.Promise {
+	return k.pushList("rpush", key, value, func(list []json.RawMessage, value []byte) []json.RawMessage {
+		return append(list, value)
+	})
+}
+
+// Lpop atomically removes the first value of the list held by a key, deleting
+// the key once the list is empty, and resolves with it, or with null if the
+// list is empty.
+func (k *KV) Lpop(key sobek.Value) *sobek.Promise {
+	return k.popList("lpop", key, func(list []json.RawMessage) ([]json.RawMessage, []byte) {
+		return list[1:], list[0]
+	})
+}
+
+// Rpop atomically removes the last value of the list held by a key, deleting
+// the key once the list is empty, and resolves with it, or with null if the
+// list is empty.
+func (k *KV) Rpop(key sobek.Value) *sobek.Promise {
+	return k.popList("rpop", key, func(list []json.RawMessage) ([]json.RawMessage, []byte) {
+		return list[:len(list)-1], list[len(list)-1]
+	})
+}
+
+// Llen resolves with the length of the list held by a key, or 0 if the key
+// does not exist.
+func (k *KV) Llen(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var length int
+
+		err := k.view(func(tx *bolt.Tx) error {
+			list, err := k.loadArray(tx, keyBytes)
+			length = len(list)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(length)
+	}()
+
+	return promise
+}
+
+// pushList atomically replaces the list held by a key with the one push
+// returns, given its values and the JSON-encoded value, and resolves with
+// its length.
+func (k *KV) pushList(
+	op string, key, value sobek.Value, push func(list []json.RawMessage, value []byte) []json.RawMessage,
+) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonValue, err := json.Marshal(value.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var length int
+
+		err := k.mutate(mutation{op: op, key: keyBytes}, func(tx *bolt.Tx) error {
+			list, err := k.loadArray(tx, keyBytes)
+			if err != nil {
+				return err
+			}
+
+			list = push(list, jsonValue)
+			length = len(list)
+
+			return k.replaceValue(tx, keyBytes, list)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(length)
+	}()
+
+	return promise
+}
+
+// popList atomically replaces the non-empty list held by a key with the one
+// pop returns, given its values, and resolves with the value pop removed.
+func (k *KV) popList(
+	op string, key sobek.Value, pop func(list []json.RawMessage) ([]json.RawMessage, []byte),
+) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var popped any
+
+		err := k.mutate(mutation{op: op, key: keyBytes}, func(tx *bolt.Tx) error {
+			list, err := k.loadArray(tx, keyBytes)
+			if err != nil || len(list) == 0 {
+				return err
+			}
+
+			list, jsonValue := pop(list)
+			if err := json.Unmarshal(jsonValue, &popped); err != nil {
+				return err
+			}
+
+			return k.replaceValue(tx, keyBytes, list)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(popped)
+	}()
+
+	return promise
+}
@@ -0,0 +1,135 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVLists(t *testing.T) {
+	t.Parallel()
+
+	t.Run("values are pushed and popped at both ends", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.rpush("list", 2)
+				.then(() => store.rpush("list", 3))
+				.then(() => store.lpush("list", 1))
+				.then((length) => {
+					if (length !== 3) {
+						throw new Error("expected a length of 3, got " + length);
+					}
+
+					return store.get("list");
+				})
+				.then((list) => {
+					if (JSON.stringify(list) !== "[1,2,3]") {
+						throw new Error("expected [1,2,3], got " + JSON.stringify(list));
+					}
+
+					return Promise.all([store.lpop("list"), store.rpop("list")]);
+				})
+				.then(([first, last]) => {
+					if (first !== 1 || last !== 3) {
+						throw new Error("expected to pop 1 and 3, got " + first + " and " + last);
+					}
+
+					return store.llen("list");
+				})
+				.then((length) => {
+					if (length !== 1) {
+						throw new Error("expected a length of 1, got " + length);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("popping the last value deletes the list", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.lpush("list", "only")
+				.then(() => store.rpop("list"))
+				.then(() => Promise.all([store.exists("list"), store.llen("list")]))
+				.then(([exists, length]) => {
+					if (exists || length !== 0) {
+						throw new Error("expected the emptied list to be deleted");
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("popping a missing or empty list resolves with null", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.set("empty", [])
+				.then(() => Promise.all([
+					store.lpop("missing"),
+					store.rpop("missing"),
+					store.lpop("empty"),
+					store.llen("missing"),
+				]))
+				.then(([first, last, empty, length]) => {
+					if (first !== null || last !== null || empty !== null) {
+						throw new Error("expected null, got " + JSON.stringify([first, last, empty]));
+					}
+
+					if (length !== 0) {
+						throw new Error("expected a missing list to have a length of 0, got " + length);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("keys holding other types are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			const expectMismatch = (promise) => promise.then(
+				() => { throw new Error("expected a TypeMismatchError"); },
+				(err) => {
+					if (String(err.name) !== "TypeMismatchError") {
+						throw err;
+					}
+				},
+			);
+
+			store.set("scalar", "foo")
+				.then(() => Promise.all([
+					expectMismatch(store.lpush("scalar", 1)),
+					expectMismatch(store.rpush("scalar", 1)),
+					expectMismatch(store.lpop("scalar")),
+					expectMismatch(store.rpop("scalar")),
+					expectMismatch(store.llen("scalar")),
+				]))
+				.then(() => store.get("scalar"))
+				.then((value) => {
+					if (value !== "foo") {
+						throw new Error("expected the value to be left unchanged, got " + JSON.stringify(value));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+}
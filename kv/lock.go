@@ -0,0 +1,56 @@
+package kv
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// openRetries is the number of times opening a store whose file is
+	// locked by another process is retried, when the Retry option is set.
+	openRetries = 3
+
+	// openBackoff is the delay before the first retry to open a store, which
+	// doubles with each retry.
+	openBackoff = 500 * time.Millisecond
+)
+
+// openFile opens the store's file, tuned by the NoSync, InitialMmapSize and
+// FreelistType options. It waits for the lock another process holds on it
+// for at most the OpenTimeout option, and retries with an increasing backoff
+// when the Retry option is set.
+//
+// A StoreLockedError is returned if the lock could not be taken in time.
+func openFile(path string, mode os.FileMode, readOnly bool, options Options) (*bolt.DB, error) {
+	boltOptions := &bolt.Options{
+		ReadOnly:        readOnly,
+		Timeout:         options.OpenTimeout,
+		NoSync:          options.NoSync,
+		InitialMmapSize: int(options.InitialMmapSize),
+		FreelistType:    bolt.FreelistType(options.FreelistType),
+	}
+
+	attempts := 1
+	if options.Retry && options.OpenTimeout > 0 {
+		attempts += openRetries
+	}
+
+	backoff := openBackoff
+	for attempt := 1; ; attempt++ {
+		handle, err := bolt.Open(path, mode, boltOptions)
+		if !errors.Is(err, bolt.ErrTimeout) {
+			return handle, err
+		}
+
+		if attempt == attempts {
+			return nil, NewError(StoreLockedError, "store "+path+
+				" is locked by another process, which could not release it within "+options.OpenTimeout.String())
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,63 @@
+package kv
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestOpenFile(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	t.Run("locked store is reported once the timeout elapses", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, "locked.db")
+		holder, err := bolt.Open(path, 0o600, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, holder.Close())
+		})
+
+		_, err = openFile(path, 0o600, false, Options{OpenTimeout: 50 * time.Millisecond})
+
+		var kvErr *Error
+		require.ErrorAs(t, err, &kvErr)
+		assert.Equal(t, ErrorName(StoreLockedError), kvErr.Name)
+	})
+
+	t.Run("locked store is opened once released when retrying", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, "released.db")
+		holder, err := bolt.Open(path, 0o600, nil)
+		require.NoError(t, err)
+
+		time.AfterFunc(100*time.Millisecond, func() {
+			_ = holder.Close()
+		})
+
+		handle, err := openFile(path, 0o600, false, Options{OpenTimeout: 50 * time.Millisecond, Retry: true})
+		require.NoError(t, err)
+		assert.NoError(t, handle.Close())
+	})
+	t.Run("tuning options are passed to bolt", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, "tuned.db")
+		handle, err := openFile(path, 0o600, false, Options{NoSync: true, FreelistType: FreelistHashmap})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, handle.Close())
+		})
+
+		assert.True(t, handle.NoSync)
+		assert.Equal(t, bolt.FreelistMapType, handle.FreelistType)
+	})
+}
@@ -0,0 +1,95 @@
+package kv
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// MemoizeBucket is the name of the internal bucket holding the values
+// computed through KV.Memoize.
+const MemoizeBucket = "k6/memoize"
+
+// Memoize resolves with the value cached under the given key, computing it
+// with fn, exactly once across all the VUs sharing the store, if it is absent.
+//
+// While a VU computes the value, the other callers wait for it and resolve with
+// the stored, JSON-serialized, result. If fn throws or rejects, the callers
+// waiting for it are rejected, and the next caller computes the value again,
+// as it does should fn not complete in time. See [MemoizeOptions] for how long
+// computed values are cached for, and callers wait for them.
+func (k *KV) Memoize(key sobek.Value, fn sobek.Value, options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(key) || key.String() == "" {
+		reject(NewError(KeyRequiredError, "memoize key is required"))
+		return promise
+	}
+
+	callable, isFunction := sobek.AssertFunction(fn)
+	if !isFunction {
+		reject(fmt.Errorf("memoize %s: expected a function, got %v", key, fn))
+		return promise
+	}
+
+	memoizeOptions, err := ImportMemoizeOptions(k.vu.Runtime(), options)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	call := onceCall{
+		bucket:  []byte(MemoizeBucket),
+		key:     []byte(key.String()),
+		ttl:     memoizeOptions.TTL,
+		timeout: memoizeOptions.Timeout,
+	}
+
+	k.callOnce(call, callable, resolve, reject)
+
+	return promise
+}
+
+// MemoizeOptions are the options that can be passed to KV.Memoize().
+type MemoizeOptions struct {
+	// TTL is how long a computed value is cached for, after which
+	// it is computed again. Zero, the default, caches it forever.
+	TTL time.Duration `json:"ttl"`
+
+	// Timeout is how long the function has to compute the value, and the
+	// other callers wait for it. Defaults to DefaultOnceTimeout.
+	Timeout time.Duration `json:"timeout"`
+}
+
+// ImportMemoizeOptions instantiates a MemoizeOptions from a sobek.Value.
+func ImportMemoizeOptions(rt *sobek.Runtime, options sobek.Value) (MemoizeOptions, error) {
+	memoizeOptions := MemoizeOptions{Timeout: DefaultOnceTimeout}
+
+	// If no options are passed, return the default options
+	if common.IsNullish(options) {
+		return memoizeOptions, nil
+	}
+
+	timeout, err := importOnceTimeout(options.ToObject(rt))
+	if err != nil {
+		return memoizeOptions, err
+	}
+
+	memoizeOptions.Timeout = timeout
+
+	ttl, err := toDuration(options.ToObject(rt).Get("ttl"))
+	if err != nil {
+		return memoizeOptions, fmt.Errorf("invalid ttl: %w", err)
+	}
+
+	if ttl < 0 {
+		return memoizeOptions, fmt.Errorf("ttl must not be negative, got %s", ttl)
+	}
+
+	memoizeOptions.TTL = ttl
+
+	return memoizeOptions, nil
+}
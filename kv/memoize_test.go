@@ -0,0 +1,149 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVMemoize(t *testing.T) {
+	t.Parallel()
+
+	t.Run("cached values are computed once, and served without writing", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			let calls = 0;
+			const compute = () => "token-" + ++calls;
+
+			let writes;
+
+			Promise.all([store.memoize("token", compute), store.memoize("token", compute)])
+				.then(([first, second]) => {
+					if (calls !== 1 || first !== "token-1" || second !== "token-1") {
+						throw new Error("expected the value to be computed once, got " + first + " and " + second);
+					}
+
+					return store.stats();
+				})
+				.then((stats) => {
+					writes = stats.writes;
+					return store.memoize("token", compute);
+				})
+				.then((value) => {
+					if (value !== "token-1") {
+						throw new Error("expected the cached value, got " + value);
+					}
+
+					return store.stats();
+				})
+				.then((stats) => {
+					if (stats.writes !== writes) {
+						throw new Error("expected the cached value to be served without writing");
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("cached values are computed once in dry-run mode", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv({ dryRun: true });
+
+			let calls = 0;
+			const compute = () => "token-" + ++calls;
+
+			store.memoize("token", compute)
+				.then(() => store.memoize("token", compute))
+				.then((value) => {
+					if (calls !== 1 || value !== "token-1") {
+						throw new Error("expected the cached value, got " + value);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("expired values are computed again", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			let calls = 0;
+			const compute = () => "token-" + ++calls;
+
+			store.memoize("token", compute, { ttl: 1 })
+				.then(() => {
+					const until = Date.now() + 5;
+					while (Date.now() < until) {}
+
+					return store.memoize("token", compute, { ttl: 1 });
+				})
+				.then((value) => {
+					if (value !== "token-2") {
+						throw new Error("expected the value to be computed again, got " + value);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("failures are rejected, and computed again by the next caller", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.memoize("token", () => Promise.reject(new Error("unavailable")))
+				.then(
+					() => { throw new Error("expected memoize to reject"); },
+					(err) => {
+						if (!String(err).includes("unavailable")) {
+							throw new Error("expected the failure to be rejected, got " + err);
+						}
+					},
+				)
+				.then(() => store.memoize("token", () => "abc"))
+				.then((value) => {
+					if (value !== "abc") {
+						throw new Error("expected the value to be computed again, got " + value);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid options are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.memoize("token", () => "abc", { timeout: -1 })
+				.then(
+					() => { throw new Error("expected memoize to reject"); },
+					(err) => {
+						if (!String(err).includes("timeout must not be negative")) {
+							throw new Error("expected the timeout to be rejected, got " + err);
+						}
+					},
+				);
+		`)
+		require.NoError(t, err)
+	})
+}
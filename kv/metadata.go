@@ -0,0 +1,92 @@
+package kv
+
+import (
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// ValueWithMetadata is the value of a key, along with its metadata, as
+// returned by KV.GetWithMetadata().
+type ValueWithMetadata struct {
+	// Value is the value of the key.
+	Value any `json:"value" js:"value"`
+
+	// Version is the version of the key, which increases every time it is
+	// written. Keys written before versions were tracked have the version 0.
+	Version uint64 `json:"version" js:"version"`
+
+	// CreatedAt is the time the key was created at, in milliseconds since
+	// the Unix epoch, or 0 if unknown.
+	CreatedAt int64 `json:"createdAt" js:"createdAt"`
+
+	// UpdatedAt is the time the key was last written at, in milliseconds
+	// since the Unix epoch, or 0 if unknown.
+	UpdatedAt int64 `json:"updatedAt" js:"updatedAt"`
+}
+
+// GetWithMetadata resolves with the value of a key in the store, along
+// with its version and the times it was created and last updated at.
+func (k *KV) GetWithMetadata(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	settle := k.reviveLater(resolve, reject)
+
+	go func() {
+		var (
+			result ValueWithMetadata
+			found  bool
+		)
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			if newVisibility(tx, k.bucket).hidden(keyBytes) {
+				return nil
+			}
+
+			jsonValue, err := loadValue(tx, bucket.Get(keyBytes))
+			if err != nil || jsonValue == nil {
+				return err
+			}
+
+			metadata := metadataOf(tx, k.bucket, keyBytes)
+			result.Version = metadata.version
+			result.CreatedAt = metadata.createdAt
+			result.UpdatedAt = metadata.updatedAt
+			found = true
+
+			return decodeValue(jsonValue, &result.Value)
+		})
+		if err == nil && !found {
+			err = NewError(KeyNotFoundError, "key "+string(keyBytes)+" not found")
+		}
+
+		settle(func() (any, error) {
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := k.revive(keyBytes, result.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Value = value
+
+			return result, nil
+		})
+	}()
+
+	return promise
+}
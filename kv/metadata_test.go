@@ -0,0 +1,71 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVGetWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	t.Run("overwrites update the version and time, but not the creation time", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+			let first;
+
+			store.set("key", "first")
+				.then(() => store.getWithMetadata("key"))
+				.then((result) => {
+					first = result;
+
+					// Let the clock move past the first write.
+					const until = Date.now() + 5;
+					while (Date.now() < until) {}
+
+					return store.set("key", "second");
+				})
+				.then(() => store.getWithMetadata("key"))
+				.then((second) => {
+					if (first.value !== "first" || second.value !== "second") {
+						throw new Error("expected the written values, got " + JSON.stringify([first.value, second.value]));
+					}
+
+					if (second.version <= first.version) {
+						throw new Error("expected the version to increase, got " + first.version + " then " + second.version);
+					}
+
+					if (second.updatedAt <= first.updatedAt) {
+						throw new Error("expected updatedAt to increase, got " + first.updatedAt + " then " + second.updatedAt);
+					}
+
+					if (first.createdAt === 0 || second.createdAt !== first.createdAt) {
+						throw new Error("expected createdAt to be kept, got " + first.createdAt + " then " + second.createdAt);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("missing keys are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			kv.openKv().getWithMetadata("missing").then(
+				() => { throw new Error("expected getWithMetadata to reject"); },
+				(err) => {
+					if (String(err.name) !== "KeyNotFoundError") {
+						throw err;
+					}
+				},
+			);
+		`)
+		require.NoError(t, err)
+	})
+}
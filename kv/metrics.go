@@ -0,0 +1,108 @@
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/metrics"
+)
+
+// BindCounterMetric binds a key holding a number to a k6 Counter metric.
+//
+// Whenever the key's value increases, the increase is added to the metric,
+// so that the metric's total follows the value accumulated in the store.
+// Decreases, such as the key being reset, are ignored.
+//
+// It must be called in the init context.
+func (k *KV) BindCounterMetric(key sobek.Value, metricName sobek.Value) {
+	k.bindMetric(key, metricName, metrics.Counter)
+}
+
+// BindGaugeMetric binds a key holding a number to a k6 Gauge metric,
+// which is set to the key's value whenever it is written.
+//
+// It must be called in the init context.
+func (k *KV) BindGaugeMetric(key sobek.Value, metricName sobek.Value) {
+	k.bindMetric(key, metricName, metrics.Gauge)
+}
+
+// BindTrendMetric binds a key holding a number to a k6 Trend metric,
+// to which the key's value is added whenever it is written.
+//
+// It must be called in the init context.
+func (k *KV) BindTrendMetric(key sobek.Value, metricName sobek.Value) {
+	k.bindMetric(key, metricName, metrics.Trend)
+}
+
+// bindMetric registers a metric of the given type, and binds it to the key.
+func (k *KV) bindMetric(key sobek.Value, metricName sobek.Value, metricType metrics.MetricType) {
+	rt := k.vu.Runtime()
+
+	initEnv := k.vu.InitEnv()
+	if initEnv == nil {
+		common.Throw(rt, errors.New("metrics can only be bound to keys in the init context"))
+		return
+	}
+
+	if common.IsNullish(key) || key.String() == "" {
+		common.Throw(rt, NewError(KeyRequiredError, "the key to bind the metric to is required"))
+		return
+	}
+
+	metric, err := initEnv.Registry.NewMetric(metricName.String(), metricType)
+	if err != nil {
+		common.Throw(rt, err)
+		return
+	}
+
+	if k.boundMetrics == nil {
+		k.boundMetrics = make(map[string][]*metrics.Metric)
+	}
+
+	k.boundMetrics[key.String()] = append(k.boundMetrics[key.String()], metric)
+}
+
+// emitMetrics pushes samples to the metrics bound to a key, given the
+// JSON-encoded values the key held before and after it was written.
+//
+// Values which are not numbers are ignored.
+func (k *KV) emitMetrics(bound []*metrics.Metric, before, after []byte) {
+	state := k.vu.State()
+	if state == nil || after == nil {
+		return
+	}
+
+	var value float64
+	if err := json.Unmarshal(after, &value); err != nil {
+		return
+	}
+
+	var previous float64
+	if before != nil {
+		_ = json.Unmarshal(before, &previous)
+	}
+
+	ctm := state.Tags.GetCurrentValues()
+	now := time.Now()
+
+	for _, metric := range bound {
+		sampleValue := value
+
+		if metric.Type == metrics.Counter {
+			sampleValue = value - previous
+			if sampleValue <= 0 {
+				continue
+			}
+		}
+
+		metrics.PushIfNotDone(k.vu.Context(), state.Samples, metrics.Sample{
+			TimeSeries: metrics.TimeSeries{Metric: metric, Tags: ctm.Tags},
+			Time:       now,
+			Metadata:   ctm.Metadata,
+			Value:      sampleValue,
+		})
+	}
+}
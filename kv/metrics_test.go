@@ -0,0 +1,57 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/metrics"
+)
+
+func TestKVBindMetrics(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	require.NoError(t, vu.run(`
+		const store = kv.openKv();
+
+		store.bindCounterMetric("orders", "kv_orders");
+		store.bindGaugeMetric("queue", "kv_queue");
+		store.bindTrendMetric("latency", "kv_latency");
+	`))
+
+	samples := vu.enterVUContext(1)
+
+	require.NoError(t, vu.run(`
+		store.set("orders", 2)
+			.then(() => store.set("orders", 5))
+			.then(() => store.set("orders", 0))
+			.then(() => store.set("queue", 7))
+			.then(() => store.set("latency", 120))
+			.then(() => store.set("latency", "not a number"));
+	`))
+
+	close(samples)
+
+	// Ignore the samples of the metrics the store pushes on its own.
+	values := map[string][]float64{"kv_orders": nil, "kv_queue": nil, "kv_latency": nil}
+	for container := range samples {
+		for _, sample := range container.GetSamples() {
+			if pushed, bound := values[sample.Metric.Name]; bound {
+				values[sample.Metric.Name] = append(pushed, sample.Value)
+			}
+		}
+	}
+
+	// Counters are only pushed the increases of their key.
+	assert.Equal(t, map[string][]float64{
+		"kv_orders":  {2, 3},
+		"kv_queue":   {7},
+		"kv_latency": {120},
+	}, values)
+
+	metric := vu.initEnv.Registry.Get("kv_orders")
+	require.NotNil(t, metric)
+	assert.Equal(t, metrics.Counter, metric.Type)
+}
@@ -8,6 +8,8 @@
 package kv
 
 import (
+	"sync"
+
 	"github.com/grafana/sobek"
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules"
@@ -18,6 +20,10 @@ type (
 	// instances for each VU.
 	RootModule struct {
 		db *db
+
+		// datasets holds the stores opened through the dataset option, by path.
+		datasets map[string]*db
+		lock     sync.Mutex
 	}
 
 	// ModuleInstance represents an instance of the JS module.
@@ -37,7 +43,27 @@ var (
 
 // New returns a pointer to a new RootModule instance
 func New() *RootModule {
-	return &RootModule{db: newDB()}
+	return &RootModule{db: newDB(), datasets: make(map[string]*db)}
+}
+
+// dataset returns the store at the given path, or the default store if
+// the path is empty.
+func (rm *RootModule) dataset(path string) *db {
+	if path == "" || path == rm.db.path {
+		return rm.db
+	}
+
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+
+	store, found := rm.datasets[path]
+	if !found {
+		store = newDB()
+		store.path = path
+		rm.datasets[path] = store
+	}
+
+	return store
 }
 
 // NewModuleInstance implements the modules.Module interface and returns
@@ -54,26 +80,56 @@ func (rm *RootModule) NewModuleInstance(vu modules.VU) modules.Instance {
 func (mi *ModuleInstance) Exports() modules.Exports {
 	return modules.Exports{Named: map[string]interface{}{
 		"openKv": mi.OpenKv,
+		"KV":     mi.NewKV,
 	}}
 }
 
-// NewKV implements the modules.Instance interface and returns
-// a new KV instance.
-func (mi *ModuleInstance) NewKV(_ sobek.ConstructorCall) *sobek.Object {
-	return mi.vu.Runtime().ToValue(mi.kv).ToObject(mi.vu.Runtime())
+// NewKV is the constructor of the KV class exported by the module, so that
+// `new KV(options)` opens the store and returns a KV instance, like openKv.
+func (mi *ModuleInstance) NewKV(call sobek.ConstructorCall) *sobek.Object {
+	return mi.OpenKv(call.Argument(0))
 }
 
 // OpenKv opens the KV store and returns a KV instance.
-func (mi *ModuleInstance) OpenKv() *sobek.Object {
-	if err := mi.rm.db.open(); err != nil {
+//
+// The store is shared by all VUs. Options affecting how the store itself is
+// opened are only taken into account on the first call. See [Options] for details.
+func (mi *ModuleInstance) OpenKv(options sobek.Value) *sobek.Object {
+	openOptions, err := ImportOptions(mi.vu.Runtime(), options)
+	if err != nil {
 		common.Throw(mi.vu.Runtime(), err)
 		return nil
 	}
 
-	kv := NewKV(mi.vu, mi.rm.db)
-	kv.bucket = []byte(DefaultKvBucket)
+	store := mi.rm.dataset(openOptions.Dataset)
+	if err := store.open(openOptions); err != nil {
+		common.Throw(mi.vu.Runtime(), err)
+		return nil
+	}
+
+	if err := store.ensureBucket([]byte(openOptions.Bucket)); err != nil {
+		_ = store.close()
+		common.Throw(mi.vu.Runtime(), err)
+		return nil
+	}
+
+	kv := NewKV(mi.vu, store)
+	kv.bucket = []byte(openOptions.Bucket)
+	kv.options = openOptions
+	kv.limiter = newOpLimiter(openOptions.MaxInFlightOpsPerVU, openOptions.MaxQueuedOps)
 	mi.kv = kv
 
+	// The churn metrics can only be registered in the init context.
+	if initEnv := mi.vu.InitEnv(); initEnv != nil {
+		churn, err := registerChurnMetrics(initEnv.Registry)
+		if err != nil {
+			common.Throw(mi.vu.Runtime(), err)
+			return nil
+		}
+
+		kv.churnMetrics = churn
+	}
+
 	return mi.vu.Runtime().ToValue(mi.kv).ToObject(mi.vu.Runtime())
 }
 
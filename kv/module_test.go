@@ -0,0 +1,54 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleNewKV(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the constructor opens the store like openKv", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const constructed = new kv.KV();
+			const opened = kv.openKv();
+			const other = new kv.KV({ bucket: "other" });
+
+			constructed.set("foo", "bar")
+				.then(() => Promise.all([opened.get("foo"), other.exists("foo")]))
+				.then(([value, exists]) => {
+					if (value !== "bar") {
+						throw new Error("expected both instances to share the store, got " + value);
+					}
+
+					if (exists) {
+						throw new Error("expected the options to be applied to the constructed instance");
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid options are thrown", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			try {
+				new kv.KV({ bucket: "k6/internal" });
+				throw new Error("expected the constructor to throw");
+			} catch (err) {
+				if (!String(err).includes("k6/internal")) {
+					throw err;
+				}
+			}
+		`)
+		require.NoError(t, err)
+	})
+}
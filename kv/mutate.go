@@ -0,0 +1,250 @@
+package kv
+
+import (
+	"bytes"
+	"errors"
+
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/metrics"
+)
+
+// mutation is the raw form of a Mutation, as passed to KV.mutate.
+type mutation struct {
+	// op is the name of the KV method performing the mutation.
+	op string
+
+	// key is the key the mutation applies to, if any.
+	key []byte
+
+	// value is the JSON-encoded value the mutation writes, if any.
+	value []byte
+
+	// internal is true when the key belongs to one of the internal
+	// buckets, rather than to the KV instance's bucket.
+	internal bool
+
+	// batch is true when the mutation's function only has effects on the
+	// transaction, so that it can be run more than once, and thus batched
+	// with concurrent writes. See db.update.
+	batch bool
+}
+
+// errUnchanged is returned by the functions run by KV.mutate which leave the
+// store unchanged, such as a compare-and-swap whose value does not match,
+// so that their transaction is rolled back, and not counted, recorded in
+// dry-run mode, or undoable, as a write. KV.mutate then returns nil.
+var errUnchanged = errors.New("unchanged")
+
+// observedKey holds the values a key with bound metrics held before and
+// after being mutated.
+type observedKey struct {
+	key    []byte
+	bound  []*metrics.Metric
+	before []byte
+	after  []byte
+}
+
+// mutate runs fn within a read-write transaction, and commits it.
+//
+// In dry-run mode, the transaction is rolled back instead, so that the
+// store is left untouched, and the mutation is recorded if fn succeeded.
+//
+// If the mutated key is undoable, and fn changes its value, the value it
+// held before is kept within the same transaction. If metrics are bound to it, samples are pushed to
+// them once the transaction is committed.
+func (k *KV) mutate(m mutation, fn func(tx *bolt.Tx) error) error {
+	return k.mutateMany([]mutation{m}, fn)
+}
+
+// mutateMany runs fn, applying several mutations, within a single
+// read-write transaction, and commits it. See KV.mutate for details.
+func (k *KV) mutateMany(ms []mutation, fn func(tx *bolt.Tx) error) error {
+	if k.db.readOnly {
+		return NewError(ReadOnlyError, "the store is opened in the "+ModeSharedReadOnly+" mode, and cannot be written to")
+	}
+
+	var (
+		undoable []mutation
+		observed []*observedKey
+	)
+
+	for _, m := range ms {
+		if m.internal || m.key == nil {
+			continue
+		}
+
+		if k.undoable(m.key) {
+			undoable = append(undoable, m)
+		}
+
+		if bound := k.boundMetrics[string(m.key)]; len(bound) > 0 {
+			observed = append(observed, &observedKey{key: m.key, bound: bound})
+		}
+	}
+
+	if len(undoable) > 0 {
+		undone := fn
+		fn = func(tx *bolt.Tx) error {
+			previous := make([][]byte, len(undoable))
+			for i, m := range undoable {
+				value, err := k.currentValue(tx, m.key)
+				if err != nil {
+					return err
+				}
+
+				previous[i] = value
+			}
+
+			if err := undone(tx); err != nil {
+				return err
+			}
+
+			for i, m := range undoable {
+				current, err := k.currentValue(tx, m.key)
+				if err != nil {
+					return err
+				}
+
+				// Keep the undo record of keys left unchanged, such as by a
+				// failed compare-and-swap, which would otherwise be lost.
+				if bytes.Equal(current, previous[i]) {
+					continue
+				}
+
+				if err := k.recordUndo(tx, m.key, previous[i]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+	}
+
+	if len(observed) > 0 {
+		fn = k.observe(observed, fn)
+	}
+
+	batch := true
+	for _, m := range ms {
+		batch = batch && m.batch
+	}
+
+	if !k.options.DryRun {
+		// Apply the buffered writes first, so that writes apply in order.
+		err := k.limit(func() error {
+			k.db.writes.flush(k.db.handle)
+			return k.db.update(fn, batch)
+		})
+		if err != nil {
+			if errors.Is(err, errUnchanged) {
+				return nil
+			}
+
+			return err
+		}
+
+		k.db.stats.writes.Add(1)
+
+		for _, o := range observed {
+			k.emitMetrics(o.bound, o.before, o.after)
+		}
+
+		return nil
+	}
+
+	err := k.limit(func() error {
+		k.flushFor(nil)
+		return k.dryRun(ms, fn)
+	})
+	if errors.Is(err, errUnchanged) {
+		return nil
+	}
+
+	return err
+}
+
+// coordinate runs fn, updating the state of a coordination primitive, such
+// as a barrier, within a read-write transaction, and commits it.
+//
+// Unlike mutations, it is committed in dry-run mode as well, as the VUs
+// coordinating through the primitive would otherwise wait for each other
+// forever.
+func (k *KV) coordinate(fn func(tx *bolt.Tx) error) error {
+	if k.db.readOnly {
+		return NewError(ReadOnlyError, "the store is opened in the "+ModeSharedReadOnly+" mode, and cannot be written to")
+	}
+
+	return k.limit(func() error {
+		if err := k.db.update(fn, false); err != nil {
+			return err
+		}
+
+		k.db.stats.writes.Add(1)
+
+		return nil
+	})
+}
+
+// observe wraps fn so that it records the values the observed keys
+// hold before and after it runs.
+func (k *KV) observe(observed []*observedKey, fn func(tx *bolt.Tx) error) func(tx *bolt.Tx) error {
+	// Copy the values, as the memory they point to
+	// is only valid within the transaction.
+	current := func(tx *bolt.Tx, bucket *bolt.Bucket, key []byte) ([]byte, error) {
+		raw, err := loadValue(tx, bucket.Get(key))
+		return bytes.Clone(raw), err
+	}
+
+	return func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(k.bucket)
+		if bucket == nil {
+			return fn(tx)
+		}
+
+		for _, o := range observed {
+			before, err := current(tx, bucket, o.key)
+			if err != nil {
+				return err
+			}
+
+			o.before = before
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		for _, o := range observed {
+			after, err := current(tx, bucket, o.key)
+			if err != nil {
+				return err
+			}
+
+			o.after = after
+		}
+
+		return nil
+	}
+}
+
+// dryRun runs fn within a read-write transaction, and rolls it back,
+// recording the mutations if fn succeeded.
+func (k *KV) dryRun(ms []mutation, fn func(tx *bolt.Tx) error) error {
+	tx, err := k.db.handle.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	for _, m := range ms {
+		k.db.mutations.record(m)
+	}
+
+	return nil
+}
@@ -0,0 +1,420 @@
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// OnceBucket is the name of the internal bucket holding the state
+// of the functions executed through KV.Once.
+const OnceBucket = "k6/once"
+
+// DefaultOnceTimeout is how long the callers of KV.Once and KV.Memoize wait
+// for the caller which claimed the function to complete it, by default.
+const DefaultOnceTimeout = time.Minute
+
+// onceState is the state of a shared function call, as persisted in the store.
+type onceState struct {
+	// Done is true once the function has returned or thrown.
+	Done bool `json:"done"`
+
+	// ClaimedAt is the time, in Unix nanoseconds, the caller running the
+	// function claimed it at, which identifies its claim.
+	ClaimedAt int64 `json:"claimedAt,omitempty"`
+
+	// Deadline is the time, in Unix nanoseconds, after which the claim is
+	// abandoned, should the function not be done by then, so that it can be
+	// claimed again.
+	Deadline int64 `json:"deadline,omitempty"`
+
+	// Result holds the JSON-encoded value the function returned.
+	Result json.RawMessage `json:"result,omitempty"`
+
+	// Error holds the message of the error the function threw, if any.
+	Error string `json:"error,omitempty"`
+
+	// ExpiresAt is the time, in Unix nanoseconds, after which the outcome
+	// is discarded and the function can be called again. Zero means never.
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+}
+
+// claimable reports whether the call can be claimed, as its outcome has
+// expired, or the caller which claimed it did not complete it in time.
+func (s onceState) claimable(now time.Time) bool {
+	if !s.Done {
+		return now.UnixNano() >= s.Deadline
+	}
+
+	return s.ExpiresAt != 0 && now.UnixNano() >= s.ExpiresAt
+}
+
+// onceCall describes a function call shared by all VUs through the store.
+type onceCall struct {
+	// bucket is the name of the internal bucket the call's state is stored in.
+	bucket []byte
+
+	// key is the key the call's state is stored under.
+	key []byte
+
+	// ttl is how long a successful outcome is kept for. Zero means forever.
+	ttl time.Duration
+
+	// timeout is how long the caller which claimed the call has to complete
+	// it, and the other callers wait for it.
+	timeout time.Duration
+}
+
+// Once calls fn exactly once across all the VUs sharing the store, and
+// resolves with the value it returned.
+//
+// The first caller for a given name claims it and runs fn, awaiting it if it
+// returns a promise. Every other caller waits for it to complete, and resolves
+// with the stored, JSON-serialized, return value. If fn throws or rejects,
+// the callers waiting for it are rejected, and the next caller runs fn again.
+//
+// Should fn not complete within the timeout, see [OnceOptions], or the VU
+// running it stop first, its claim is abandoned: the callers waiting for it
+// are rejected with a TimeoutError, and the next caller runs fn again.
+//
+// Claims and outcomes are committed in dry-run mode as well, as they
+// coordinate the VUs rather than hold the test's data.
+func (k *KV) Once(name sobek.Value, fn sobek.Value, options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(name) || name.String() == "" {
+		reject(NewError(KeyRequiredError, "once name is required"))
+		return promise
+	}
+
+	callable, isFunction := sobek.AssertFunction(fn)
+	if !isFunction {
+		reject(fmt.Errorf("once %s: expected a function, got %v", name, fn))
+		return promise
+	}
+
+	onceOptions, err := ImportOnceOptions(k.vu.Runtime(), options)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	call := onceCall{bucket: []byte(OnceBucket), key: []byte(name.String()), timeout: onceOptions.Timeout}
+	k.callOnce(call, callable, resolve, reject)
+
+	return promise
+}
+
+// OnceOptions are the options that can be passed to KV.Once().
+type OnceOptions struct {
+	// Timeout is how long the function has to complete, and the other
+	// callers wait for it. Defaults to DefaultOnceTimeout.
+	Timeout time.Duration `json:"timeout"`
+}
+
+// ImportOnceOptions instantiates a OnceOptions from a sobek.Value.
+func ImportOnceOptions(rt *sobek.Runtime, options sobek.Value) (OnceOptions, error) {
+	onceOptions := OnceOptions{Timeout: DefaultOnceTimeout}
+
+	// If no options are passed, return the default options
+	if common.IsNullish(options) {
+		return onceOptions, nil
+	}
+
+	timeout, err := importOnceTimeout(options.ToObject(rt))
+	if err != nil {
+		return onceOptions, err
+	}
+
+	onceOptions.Timeout = timeout
+
+	return onceOptions, nil
+}
+
+// importOnceTimeout imports the timeout option of KV.Once and KV.Memoize,
+// defaulting to DefaultOnceTimeout.
+func importOnceTimeout(options *sobek.Object) (time.Duration, error) {
+	timeout, err := toDuration(options.Get("timeout"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	if timeout < 0 {
+		return 0, fmt.Errorf("timeout must not be negative, got %s", timeout)
+	}
+
+	if timeout == 0 {
+		return DefaultOnceTimeout, nil
+	}
+
+	return timeout, nil
+}
+
+// callOnce settles the caller's promise with the outcome of the shared call,
+// running the function if the caller is the one to claim it.
+//
+// It must be called from the event loop.
+func (k *KV) callOnce(call onceCall, fn sobek.Callable, resolve func(any), reject func(any)) {
+	callback := k.vu.RegisterCallback()
+
+	k.db.workers.Load().run(func() {
+		state, claimed, err := k.claimOnce(call)
+		if err != nil || !claimed {
+			callback(func() error { return nil })
+		}
+
+		switch {
+		case err != nil:
+			reject(err)
+		case state.Done:
+			settleOnce(call, state, resolve, reject)
+		case !claimed:
+			// Wait on a goroutine of its own, rather than holding a worker.
+			go k.awaitOnce(call, resolve, reject)
+		default:
+			callback(func() error {
+				k.runOnce(call, state, fn, resolve, reject)
+				return nil
+			})
+		}
+	})
+}
+
+// runOnce calls the function claimed by the current VU, and persists its
+// outcome. Should the VU stop before the function completes, the claim is
+// released, so that the function can be claimed again.
+//
+// It must be called from the event loop.
+func (k *KV) runOnce(call onceCall, claim onceState, fn sobek.Callable, resolve func(any), reject func(any)) {
+	rt := k.vu.Runtime()
+
+	settled := make(chan struct{})
+	go func() {
+		select {
+		case <-k.vu.Context().Done():
+			_ = k.releaseOnce(call, claim)
+		case <-settled:
+		}
+	}()
+
+	complete := func(state onceState, settle func()) {
+		close(settled)
+
+		go func() {
+			if err := k.storeOnce(call, state); err != nil {
+				_ = k.releaseOnce(call, claim)
+				reject(err)
+
+				return
+			}
+
+			settle()
+		}()
+	}
+
+	onFulfilled := func(result sobek.Value) {
+		state := onceState{Done: true}
+
+		raw, err := json.Marshal(result.Export())
+		if err != nil {
+			state.Error = err.Error()
+		} else {
+			state.Result = raw
+		}
+
+		if call.ttl > 0 {
+			state.ExpiresAt = time.Now().Add(call.ttl).UnixNano()
+		}
+
+		complete(state, func() { resolve(result) })
+	}
+
+	onRejected := func(reason sobek.Value) {
+		// Failures expire right away, so that the callers waiting for the
+		// function are rejected, and the next caller calls it again.
+		state := onceState{Done: true, Error: reason.String(), ExpiresAt: time.Now().UnixNano()}
+
+		complete(state, func() { reject(reason) })
+	}
+
+	result, err := fn(sobek.Undefined())
+	if err != nil {
+		var exception *sobek.Exception
+		if errors.As(err, &exception) {
+			onRejected(exception.Value())
+		} else {
+			onRejected(rt.ToValue(err))
+		}
+
+		return
+	}
+
+	settle(rt, result, onFulfilled, onRejected)
+}
+
+// awaitOnce waits for the function claimed by another VU to complete,
+// and settles the caller's promise with its stored outcome.
+func (k *KV) awaitOnce(call onceCall, resolve func(any), reject func(any)) {
+	state, err := k.waitOnce(call)
+	if err != nil {
+		reject(err)
+		return
+	}
+
+	settleOnce(call, state, resolve, reject)
+}
+
+// settleOnce settles the caller's promise with the given stored outcome.
+func settleOnce(call onceCall, state onceState, resolve func(any), reject func(any)) {
+	if state.Error != "" {
+		reject(fmt.Errorf("%s failed: %s", call.key, state.Error))
+		return
+	}
+
+	var value any
+	if err := json.Unmarshal(state.Result, &value); err != nil {
+		reject(err)
+		return
+	}
+
+	resolve(value)
+}
+
+// claimOnce claims the call, unless it has a stored outcome which has not
+// expired, or another caller claimed it and can still complete it, and
+// returns its state, which is the claim if the caller claimed it.
+func (k *KV) claimOnce(call onceCall) (onceState, bool, error) {
+	now := time.Now()
+
+	// Serve the stored outcomes, and the calls in progress, without going
+	// through a write transaction.
+	state, found, err := k.lookupOnce(call)
+	if err != nil || (found && !state.claimable(now)) {
+		return state, false, err
+	}
+
+	claimed := false
+
+	err = k.coordinate(func(tx *bolt.Tx) error {
+		claimed = false
+
+		bucket, err := tx.CreateBucketIfNotExists(call.bucket)
+		if err != nil {
+			return err
+		}
+
+		if raw := bucket.Get(call.key); raw != nil {
+			if err := json.Unmarshal(raw, &state); err != nil {
+				return err
+			}
+
+			if !state.claimable(now) {
+				return nil
+			}
+		}
+
+		state = onceState{ClaimedAt: now.UnixNano(), Deadline: now.Add(call.timeout).UnixNano()}
+
+		raw, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+
+		claimed = true
+
+		return bucket.Put(call.key, raw)
+	})
+
+	return state, claimed && err == nil, err
+}
+
+// releaseOnce deletes the claim of the call, unless it was completed, or
+// claimed again since, so that the next caller claims it.
+func (k *KV) releaseOnce(call onceCall, claim onceState) error {
+	return k.coordinate(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(call.bucket)
+		if bucket == nil {
+			return nil
+		}
+
+		raw := bucket.Get(call.key)
+		if raw == nil {
+			return nil
+		}
+
+		var state onceState
+		if err := json.Unmarshal(raw, &state); err != nil {
+			return err
+		}
+
+		if state.Done || state.ClaimedAt != claim.ClaimedAt {
+			return nil
+		}
+
+		return bucket.Delete(call.key)
+	})
+}
+
+// storeOnce persists the outcome of the call.
+func (k *KV) storeOnce(call onceCall, state onceState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return k.coordinate(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(call.bucket)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(call.key, raw)
+	})
+}
+
+// lookupOnce returns the stored state of the call, if any.
+func (k *KV) lookupOnce(call onceCall) (onceState, bool, error) {
+	var (
+		state onceState
+		found bool
+	)
+
+	err := k.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(call.bucket)
+		if bucket == nil {
+			return nil
+		}
+
+		raw := bucket.Get(call.key)
+		if raw == nil {
+			return nil
+		}
+
+		found = true
+
+		return json.Unmarshal(raw, &state)
+	})
+
+	return state, found, err
+}
+
+// waitOnce waits for the call to be done, and returns its outcome. It
+// returns a TimeoutError if it is not done within the call's timeout.
+func (k *KV) waitOnce(call onceCall) (onceState, error) {
+	var state onceState
+
+	err := pollUntil(k.vu.Context(), call.timeout, func() (bool, error) {
+		var err error
+		state, _, err = k.lookupOnce(call)
+
+		return state.Done, err
+	})
+
+	return state, err
+}
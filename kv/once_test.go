@@ -0,0 +1,165 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVOnce(t *testing.T) {
+	t.Parallel()
+
+	t.Run("concurrent callers share the outcome of a single call", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			let calls = 0;
+			const setup = () => {
+				calls++;
+				return { token: "abc" };
+			};
+
+			Promise.all([store.once("setup", setup), store.once("setup", setup), store.once("setup", setup)])
+				.then((results) => {
+					if (calls !== 1) {
+						throw new Error("expected setup to be called once, got " + calls);
+					}
+
+					for (const result of results) {
+						if (result.token !== "abc") {
+							throw new Error("expected every caller to get the token, got " + JSON.stringify(result));
+						}
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("calls are coordinated in dry-run mode, without being reported", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv({ dryRun: true });
+
+			let calls = 0;
+			const setup = () => ++calls;
+
+			store.once("setup", setup)
+				.then(() => store.once("setup", setup))
+				.then((result) => {
+					if (calls !== 1 || result !== 1) {
+						throw new Error("expected setup to be called once, got " + calls + " calls");
+					}
+
+					const report = store.dryRunReport();
+					if (report.length !== 0) {
+						throw new Error("expected no mutation to be reported, got " + JSON.stringify(report));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("failures are rejected, and retried by the next caller", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.once("failing", () => { throw new Error("boom"); })
+				.then(
+					() => { throw new Error("expected once to reject"); },
+					(err) => {
+						if (!String(err).includes("boom")) {
+							throw new Error("expected the failure to be rejected, got " + err);
+						}
+					},
+				)
+				.then(() => store.once("failing", () => 42))
+				.then((value) => {
+					if (value !== 42) {
+						throw new Error("expected the call to be retried, got " + value);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("claims not completed in time are abandoned", func(t *testing.T) {
+		t.Parallel()
+
+		kv := openTestKV(t, Options{})
+		kv.vu = newTestVU(t)
+
+		call := onceCall{bucket: []byte(OnceBucket), key: []byte("slow"), timeout: 50 * time.Millisecond}
+
+		claim, claimed, err := kv.claimOnce(call)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		_, claimed, err = kv.claimOnce(call)
+		require.NoError(t, err)
+		assert.False(t, claimed)
+
+		// Waiters give up once the timeout elapses.
+		_, err = kv.waitOnce(call)
+
+		var kvErr *Error
+		require.ErrorAs(t, err, &kvErr)
+		assert.Equal(t, ErrorName(TimeoutError), kvErr.Name)
+
+		// The next caller claims the call again.
+		reclaim, claimed, err := kv.claimOnce(call)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		assert.NotEqual(t, claim.ClaimedAt, reclaim.ClaimedAt)
+
+		// Releasing an abandoned claim leaves the new one be.
+		require.NoError(t, kv.releaseOnce(call, claim))
+
+		state, found, err := kv.lookupOnce(call)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, reclaim, state)
+	})
+
+	t.Run("claims are released when the VU stops", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		kv := openTestKV(t, Options{})
+		kv.vu = vu
+
+		call := onceCall{bucket: []byte(OnceBucket), key: []byte("hanging"), timeout: time.Hour}
+
+		claim, claimed, err := kv.claimOnce(call)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		hanging, err := vu.runtime.RunString(`() => new Promise(() => {})`)
+		require.NoError(t, err)
+
+		fn, isFunction := sobek.AssertFunction(hanging)
+		require.True(t, isFunction)
+
+		kv.runOnce(call, claim, fn, func(any) {}, func(any) {})
+		vu.cancel()
+
+		assert.Eventually(t, func() bool {
+			_, found, err := kv.lookupOnce(call)
+			return err == nil && !found
+		}, time.Second, pollInterval)
+	})
+}
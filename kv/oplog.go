@@ -0,0 +1,339 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// The operations recorded in the operation log.
+const (
+	// OpLogSet records a key being set to a value.
+	OpLogSet = "set"
+
+	// OpLogDelete records a key being deleted.
+	OpLogDelete = "delete"
+
+	// OpLogClear records a bucket being cleared.
+	OpLogClear = "clear"
+
+	// OpLogDeleteBucket records a bucket being deleted.
+	OpLogDeleteBucket = "deleteBucket"
+
+	// OpLogExpire records a key being set to expire, or no longer expiring
+	// when the entry has no deadline.
+	OpLogExpire = "expire"
+)
+
+// opLogEntry is an entry of the operation log, written as a line of JSON.
+type opLogEntry struct {
+	// Time is when the transaction writing the entry's operation committed.
+	Time time.Time `json:"time"`
+
+	// VU is the ID of the VU which ran the operation, if any.
+	VU uint64 `json:"vu,omitempty"`
+
+	// Op is one of OpLogSet, OpLogDelete, OpLogClear, OpLogDeleteBucket
+	// and OpLogExpire.
+	Op string `json:"op"`
+
+	// Bucket is the name of the bucket the operation applies to.
+	Bucket string `json:"bucket"`
+
+	// Key is the key the operation applies to, if any.
+	Key string `json:"key,omitempty"`
+
+	// Value is the value the key is set to, for OpLogSet operations, in
+	// the same JSON form as exported values.
+	Value json.RawMessage `json:"value,omitempty"`
+
+	// Binary is true when the value is binary, and Value holds its bytes
+	// as a base64 string.
+	Binary bool `json:"binary,omitempty"`
+
+	// ExpiresAt is the deadline the key expires at, for OpLogExpire
+	// operations, if any.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// opLog appends the operations applied to the store to a file, when it is
+// opened with the OpLog option, as an entry object per line.
+type opLog struct {
+	lock sync.Mutex
+	file *os.File
+
+	// err is the first error writing to the file, returned on close.
+	err error
+}
+
+// openOpLog opens the operation log file at path, appending to it.
+func openOpLog(path string) (*opLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) //nolint:forbidigo
+	if err != nil {
+		return nil, fmt.Errorf("failed to open operation log: %w", err)
+	}
+
+	return &opLog{file: file}, nil
+}
+
+// append writes an entry to the log. It is a no-op on a nil log.
+func (l *opLog) append(entry opLogEntry) {
+	if l == nil {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if err == nil {
+		_, err = l.file.Write(append(line, '\n'))
+	}
+
+	if err != nil && l.err == nil {
+		l.err = fmt.Errorf("failed to write to operation log: %w", err)
+	}
+}
+
+// appendOnCommit writes an entry to the log once the transaction is
+// committed. It is a no-op on a nil log.
+func (l *opLog) appendOnCommit(tx *bolt.Tx, entry opLogEntry) {
+	if l == nil {
+		return
+	}
+
+	tx.OnCommit(func() {
+		entry.Time = time.Now()
+		l.append(entry)
+	})
+}
+
+// close closes the log file, and returns the first error writing to it, if
+// any. It is a no-op on a nil log.
+func (l *opLog) close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return errors.Join(l.err, l.file.Close())
+}
+
+// logOp records an operation on a key of the bucket, or on the bucket if
+// key is nil, to the operation log, once the transaction is committed.
+func (k *KV) logOp(tx *bolt.Tx, op string, bucket, key, value []byte) error {
+	if k.db.opLog == nil || k.replaying {
+		return nil
+	}
+
+	entry := opLogEntry{Op: op, Bucket: string(bucket), Key: string(key)}
+
+	if isBinary(value) {
+		// Binary values are kept as such, rather than replayed as the
+		// base64 strings they are exported as.
+		entry.Binary = true
+	}
+
+	if value != nil {
+		exported, err := exportedValue(value)
+		if err != nil {
+			return err
+		}
+
+		entry.Value = bytes.Clone(exported)
+	}
+
+	k.appendOp(tx, entry)
+
+	return nil
+}
+
+// logExpiry records a key of the bucket being set to expire at the
+// deadline, or no longer expiring if it is nil, to the operation log, once
+// the transaction is committed.
+func (k *KV) logExpiry(tx *bolt.Tx, key []byte, deadline *time.Time) {
+	if k.db.opLog == nil || k.replaying {
+		return
+	}
+
+	k.appendOp(tx, opLogEntry{Op: OpLogExpire, Bucket: string(k.bucket), Key: string(key), ExpiresAt: deadline})
+}
+
+// appendOp writes an entry to the operation log, recording the VU running
+// the operation, once the transaction is committed.
+func (k *KV) appendOp(tx *bolt.Tx, entry opLogEntry) {
+	if k.vu != nil {
+		if state := k.vu.State(); state != nil {
+			entry.VU = state.VUID
+		}
+	}
+
+	k.db.opLog.appendOnCommit(tx, entry)
+}
+
+// Replay applies the operations recorded in an operation log file, written
+// with the opLog option, to the store, in order, within a single
+// transaction, and resolves with the number of operations applied.
+//
+// Operations apply to the buckets they were recorded on, which are created
+// if they do not exist, so that replaying the log of a run reconstructs the
+// state of the store it left. They are not recorded to the store's own
+// operation log again.
+func (k *KV) Replay(path sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(path) || path.String() == "" {
+		reject(fmt.Errorf("replay expects the path of the operation log to read"))
+		return promise
+	}
+
+	entries, err := readOpLog(path.String())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		err := k.mutate(mutation{op: "replay"}, func(tx *bolt.Tx) error {
+			for i, entry := range entries {
+				if err := k.replayEntry(tx, entry); err != nil {
+					return fmt.Errorf("failed to replay operation %d of %s: %w", i+1, path.String(), err)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(len(entries))
+	}()
+
+	return promise
+}
+
+// replayEntry applies an operation of the operation log.
+func (k *KV) replayEntry(tx *bolt.Tx, entry opLogEntry) error {
+	if err := validateBucketName(entry.Bucket); err != nil {
+		return err
+	}
+
+	replayed := &KV{bucket: []byte(entry.Bucket), db: k.db, vu: k.vu, options: k.options, replaying: true}
+	key := []byte(entry.Key)
+
+	switch entry.Op {
+	case OpLogSet:
+		if err := replayed.validateKey(key); err != nil {
+			return err
+		}
+
+		value := []byte(entry.Value)
+		if entry.Binary {
+			var data []byte
+			if err := json.Unmarshal(entry.Value, &data); err != nil {
+				return fmt.Errorf("invalid binary value: %w", err)
+			}
+
+			value = encodeBinary(data)
+		}
+
+		if err := replayed.validateValue(key, value); err != nil {
+			return err
+		}
+
+		bucket, err := tx.CreateBucketIfNotExists(replayed.bucket)
+		if err != nil {
+			return err
+		}
+
+		if err := undelay(tx, replayed.bucket, key); err != nil {
+			return err
+		}
+
+		return replayed.storeValue(tx, bucket, key, value)
+	case OpLogDelete:
+		bucket := tx.Bucket(replayed.bucket)
+		if bucket == nil {
+			return nil
+		}
+
+		if err := undelay(tx, replayed.bucket, key); err != nil {
+			return err
+		}
+
+		return replayed.removeValue(tx, bucket, key)
+	case OpLogClear:
+		if tx.Bucket(replayed.bucket) == nil {
+			return nil
+		}
+
+		if err := replayed.dropBucket(tx, replayed.bucket, OpLogClear); err != nil {
+			return err
+		}
+
+		_, err := tx.CreateBucket(replayed.bucket)
+
+		return err
+	case OpLogDeleteBucket:
+		if tx.Bucket(replayed.bucket) == nil {
+			return nil
+		}
+
+		return replayed.dropBucket(tx, replayed.bucket, OpLogDeleteBucket)
+	case OpLogExpire:
+		bucket := tx.Bucket(replayed.bucket)
+		if bucket == nil || bucket.Get(key) == nil {
+			return nil
+		}
+
+		if entry.ExpiresAt == nil {
+			return unexpire(tx, replayed.bucket, key)
+		}
+
+		return expireAt(tx, replayed.bucket, key, *entry.ExpiresAt)
+	default:
+		return fmt.Errorf("unknown operation %q", entry.Op)
+	}
+}
+
+// readOpLog reads the entries of an operation log file.
+func readOpLog(path string) ([]opLogEntry, error) {
+	file, err := os.Open(path) //nolint:forbidigo
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation log: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []opLogEntry
+
+	decoder := json.NewDecoder(file)
+	for {
+		var entry opLogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+
+			return nil, fmt.Errorf("failed to parse operation log %s: %w", path, err)
+		}
+
+		entries = append(entries, entry)
+	}
+}
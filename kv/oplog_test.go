@@ -0,0 +1,184 @@
+package kv
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVOpLogReplay(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	logPath := filepath.Join(tmpDir, "ops.ndjson")
+
+	recorded := newDB()
+	recorded.path = filepath.Join(tmpDir, "recorded.db")
+	require.NoError(t, recorded.open(Options{OpLog: logPath}))
+
+	kv := &KV{bucket: []byte(DefaultKvBucket), db: recorded}
+
+	require.NoError(t, recorded.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		for key, value := range map[string]string{"a": `1`, "b": `{"n":2}`, "c": `"three"`} {
+			if err := kv.storeValue(tx, bucket, []byte(key), []byte(value)); err != nil {
+				return err
+			}
+		}
+
+		return kv.removeValue(tx, bucket, []byte("b"))
+	}))
+
+	// Operations of transactions which are rolled back are not recorded
+	require.Error(t, recorded.handle.Update(func(tx *bolt.Tx) error {
+		if err := kv.storeValue(tx, tx.Bucket(kv.bucket), []byte("d"), []byte(`4`)); err != nil {
+			return err
+		}
+
+		return ErrStop
+	}))
+
+	require.NoError(t, recorded.close())
+
+	entries, err := readOpLog(logPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, opLogEntry{Time: entries[3].Time, Op: OpLogDelete, Bucket: DefaultKvBucket, Key: "b"}, entries[3])
+	assert.False(t, entries[3].Time.IsZero())
+
+	replayed := openTestDB(t, Options{})
+	kv.db = replayed
+
+	require.NoError(t, replayed.handle.Update(func(tx *bolt.Tx) error {
+		// The stale key is cleared by the recorded operations
+		if err := kv.storeValue(tx, tx.Bucket(kv.bucket), []byte("b"), []byte(`0`)); err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := kv.replayEntry(tx, entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	state := map[string]string{}
+	require.NoError(t, replayed.handle.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(kv.bucket).ForEach(func(k, v []byte) error {
+			state[string(k)] = string(v)
+			return nil
+		})
+	}))
+	assert.Equal(t, map[string]string{"a": `1`, "c": `"three"`}, state)
+
+	require.Error(t, replayed.handle.Update(func(tx *bolt.Tx) error {
+		return kv.replayEntry(tx, opLogEntry{Op: "rename", Bucket: DefaultKvBucket, Key: "a"})
+	}))
+}
+
+func TestKVOpLogReplayRestoresState(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	logPath := filepath.Join(tmpDir, "ops.ndjson")
+
+	recorded := newDB()
+	recorded.path = filepath.Join(tmpDir, "recorded.db")
+	require.NoError(t, recorded.open(Options{OpLog: logPath}))
+
+	kv := &KV{bucket: []byte(DefaultKvBucket), db: recorded}
+	users := &KV{bucket: []byte("users"), db: recorded}
+	deadline := time.Now().Add(time.Hour)
+
+	require.NoError(t, recorded.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		if err := kv.storeValue(tx, bucket, []byte("binary"), encodeBinary([]byte{0xff, 0x00})); err != nil {
+			return err
+		}
+
+		if err := kv.storeValue(tx, bucket, []byte("expiring"), []byte(`1`)); err != nil {
+			return err
+		}
+
+		if err := kv.expireKey(tx, []byte("expiring"), deadline); err != nil {
+			return err
+		}
+
+		if err := kv.storeValue(tx, bucket, []byte("expired"), []byte(`2`)); err != nil {
+			return err
+		}
+
+		if err := kv.expireKey(tx, []byte("expired"), time.Now()); err != nil {
+			return err
+		}
+
+		created, err := tx.CreateBucket(users.bucket)
+		if err != nil {
+			return err
+		}
+
+		if err := users.storeValue(tx, created, []byte("alice"), []byte(`"admin"`)); err != nil {
+			return err
+		}
+
+		return users.dropBucket(tx, users.bucket, OpLogDeleteBucket)
+	}))
+
+	// Keys purged by the sweeper are recorded as deleted
+	require.NoError(t, recorded.handle.Update(recorded.purgeExpired))
+	require.NoError(t, recorded.close())
+
+	entries, err := readOpLog(logPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 8)
+	assert.True(t, entries[0].Binary)
+	assert.Equal(t, OpLogExpire, entries[2].Op)
+	require.NotNil(t, entries[2].ExpiresAt)
+	assert.True(t, deadline.Equal(*entries[2].ExpiresAt))
+	assert.Equal(t, OpLogDeleteBucket, entries[6].Op)
+	assert.Equal(t, opLogEntry{Time: entries[7].Time, Op: OpLogDelete, Bucket: DefaultKvBucket, Key: "expired"}, entries[7])
+
+	// Replayed operations are not recorded to the store's own log again
+	replayedLogPath := filepath.Join(tmpDir, "replayed.ndjson")
+	replayed := openTestDB(t, Options{OpLog: replayedLogPath})
+	kv.db = replayed
+
+	require.NoError(t, replayed.handle.Update(func(tx *bolt.Tx) error {
+		for _, entry := range entries {
+			if err := kv.replayEntry(tx, entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	require.NoError(t, replayed.handle.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		assert.Equal(t, encodeBinary([]byte{0xff, 0x00}), bucket.Get([]byte("binary")))
+		assert.Nil(t, bucket.Get([]byte("expired")))
+
+		expiresAt, expires := expiryOf(tx.Bucket(expiryBucket(kv.bucket)), []byte("expiring"))
+		assert.True(t, expires)
+		assert.True(t, deadline.Equal(expiresAt))
+
+		assert.Nil(t, tx.Bucket(users.bucket))
+
+		return nil
+	}))
+
+	replayedEntries, err := readOpLog(replayedLogPath)
+	require.NoError(t, err)
+	assert.Empty(t, replayedEntries)
+}
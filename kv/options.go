@@ -0,0 +1,519 @@
+package kv
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+)
+
+const (
+	// FreelistArray keeps the store's free pages in an array, which is
+	// compact, but slow to search in large stores.
+	FreelistArray = string(bolt.FreelistArrayType)
+
+	// FreelistHashmap keeps the store's free pages in a hashmap, which is
+	// faster to search in large stores.
+	FreelistHashmap = string(bolt.FreelistMapType)
+)
+
+// DefaultListLimit is the default maximum number of entries KV.List returns.
+const DefaultListLimit = 1000
+
+const (
+	// ModeReadWrite opens the store for reading and writing.
+	ModeReadWrite = "readWrite"
+
+	// ModeSharedReadOnly opens a prepared store file read-only. Its contents
+	// are read straight from the memory-mapped file, which the OS shares
+	// between VUs, and every write is rejected with a ReadOnlyError.
+	ModeSharedReadOnly = "sharedReadOnly"
+)
+
+// Options are the options that can be passed to openKv().
+type Options struct {
+	// Resume keeps the progress marked with KV.MarkProgress during previous
+	// test runs, so that the test can resume from it. By default, it is
+	// discarded when the store is opened.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	Resume bool `json:"resume"`
+
+	// ClearOnStart deletes all the keys and buckets of the store when it is
+	// opened, so that data left over by previous test runs does not bleed
+	// into the current one. The progress marked with KV.MarkProgress is kept
+	// when resuming.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	ClearOnStart bool `json:"clearOnStart"`
+
+	// MaxKeyLength is the maximum length, in bytes, of the keys written
+	// to the store. Zero, the default, means no limit.
+	MaxKeyLength int64 `json:"maxKeyLength"`
+
+	// MaxValueSize is the maximum size, in bytes, of the encoded values
+	// written to the store. Writes of larger values are rejected with a
+	// ValueTooLargeError. Zero, the default, means no limit.
+	MaxValueSize int64 `json:"maxValueSize"`
+
+	// KeyPattern is a regular expression that the keys written to the
+	// store must match. Empty, the default, accepts any key.
+	KeyPattern string `json:"keyPattern"`
+
+	// RejectControlCharacters rejects keys containing control characters,
+	// such as newlines or NUL bytes, when written to the store.
+	RejectControlCharacters bool `json:"rejectControlCharacters"`
+
+	// DryRun records the mutations of the store's contents, readable through
+	// KV.DryRunReport, instead of applying them. Reads still see the store's
+	// actual contents.
+	DryRun bool `json:"dryRun"`
+
+	// Deduplicate stores identical values once, in the ContentBucket, and
+	// has the keys they are set to reference them. It saves space when many
+	// keys hold the same large value.
+	Deduplicate bool `json:"deduplicate"`
+
+	// Compression is the algorithm the values written to the store are
+	// compressed with, if any. Only CompressionGzip is supported. Values
+	// are only compressed when it makes them smaller, and compressed values
+	// are read back whatever the option the store is opened with.
+	Compression string `json:"compression"`
+
+	// Serialization is how values are stored: either SerializationJSON, the
+	// default, SerializationBinary, which stores ArrayBuffer and typed
+	// array values as their raw bytes, rather than mangling them through
+	// JSON, and reads them back as ArrayBuffers, or the name of a Serializer
+	// registered with RegisterSerializer.
+	Serialization string `json:"serialization"`
+
+	// UndoPrefix selects the keys whose value before their last mutation is
+	// kept, so that it can be restored with KV.Undo. Empty, the default,
+	// keeps none.
+	UndoPrefix string `json:"undoPrefix"`
+
+	// Verify checks the integrity of the store when it is opened, and fails
+	// with a CorruptedStoreError if it is corrupted, rather than failing
+	// in the middle of the test.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	Verify bool `json:"verify"`
+
+	// Repair checks the integrity of the store when it is opened, like
+	// Verify, and replaces a corrupted store with the entries which could be
+	// read from it. The corrupted file is kept, suffixed with ".corrupted".
+	// Files which cannot be opened at all, such as when their meta pages are
+	// corrupted, are not repaired, but reported with a CorruptedStoreError.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	Repair bool `json:"repair"`
+
+	// ChurnPrefixes are the key prefixes KV.ChurnStats and the churn
+	// metrics break the changes made to keys down by.
+	ChurnPrefixes []string `json:"churnPrefixes"`
+
+	// MaxInFlightOps is the maximum number of operations all VUs can run
+	// concurrently on the store. The operations in excess are queued until
+	// one completes. Zero, the default, means no limit.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	MaxInFlightOps int64 `json:"maxInFlightOps"`
+
+	// Workers runs the get, getOrDefault, set, delete, list, find, exists,
+	// existsMany, keys, clear, size, getSet, getOrSet, setIfAbsent,
+	// setIfPresent, findByIndex, once and memoize operations of all VUs on
+	// this number of long-lived goroutines, rather than on a new goroutine
+	// each. Operations are queued while all the workers are busy. Zero, the
+	// default, runs each of them on a new goroutine, as are the other
+	// operations in any case.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	Workers int64 `json:"workers"`
+
+	// MaxInFlightOpsPerVU is the maximum number of operations a VU can run
+	// concurrently on the store. The operations in excess are queued until
+	// one completes. Zero, the default, means no limit.
+	MaxInFlightOpsPerVU int64 `json:"maxInFlightOpsPerVU"`
+
+	// MaxQueuedOps is the maximum number of operations queued by each of the
+	// limits of in-flight operations, beyond which operations are rejected
+	// with a TooManyOperationsError. Zero, the default, means no limit.
+	MaxQueuedOps int64 `json:"maxQueuedOps"`
+
+	// DefaultListLimit is the maximum number of entries KV.List returns when
+	// no limit option is passed, which protects the event loop from huge
+	// responses. Zero means no limit. It defaults to the DefaultListLimit
+	// constant.
+	DefaultListLimit int64 `json:"defaultListLimit"`
+
+	// OpenTimeout is how long opening the store waits for another process
+	// to release the lock it holds on the store's file, before failing with
+	// a StoreLockedError. Zero, the default, means waiting indefinitely.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	OpenTimeout time.Duration `json:"openTimeout"`
+
+	// Retry retries opening the store a few times, with an increasing
+	// backoff, when OpenTimeout elapses before the lock is released.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	Retry bool `json:"retry"`
+
+	// NoSync skips syncing the store's file to disk after each write, which
+	// greatly speeds writes up, at the cost of losing the latest ones, or
+	// corrupting the store, if the system crashes. Suited to throwaway data.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	NoSync bool `json:"noSync"`
+
+	// InitialMmapSize is the initial size, in bytes, of the store file's
+	// memory map. Sizing it for the expected data avoids remapping the file,
+	// which blocks writes, as the store grows. Zero, the default, lets bolt
+	// size it.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	InitialMmapSize int64 `json:"initialMmapSize"`
+
+	// FreelistType is the type of the free pages list of the store, either
+	// FreelistArray, the default, or FreelistHashmap, which is faster for
+	// large stores.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	FreelistType string `json:"freelistType"`
+
+	// FlushInterval buffers the writes of KV.Set and KV.Delete, and applies
+	// them together, within a single transaction, at this interval, which
+	// greatly speeds writes up. Buffered writes are also applied before
+	// other writes, before reads which could see them, such as gets of a
+	// buffered key, and by KV.Flush. Zero, the default, applies each write
+	// right away.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	FlushInterval time.Duration `json:"flushInterval"`
+
+	// KeyIndex keeps the keys of the store in memory, so that KV.Exists,
+	// KV.Count, KV.Size, KV.RandomKey, KV.Sample and KV.Keys do not scan the
+	// store. A bucket's keys are indexed again after each write to it, so
+	// the index suits read-mostly datasets.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	KeyIndex bool `json:"keyIndex"`
+
+	// Dataset is the path to the store file to open, instead of the default
+	// one. Each dataset is shared by all the VUs opening it.
+	Dataset string `json:"dataset"`
+
+	// Mode is the mode the store is opened in: either ModeReadWrite, the
+	// default, or ModeSharedReadOnly.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	Mode string `json:"mode"`
+
+	// Seed is the path to a JSON or NDJSON file whose entries are loaded
+	// into the store when it is opened, within a single transaction.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	Seed string `json:"seed"`
+
+	// ExportOnClose is the path of a file the entries of the bucket are
+	// written to, like with KV.Export, when the store is closed by the last
+	// KV instance using it.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	ExportOnClose string `json:"exportOnClose"`
+
+	// OpLog is the path of a file every set, delete and expiration applied
+	// to the store is appended to, with its time and VU, as a line of JSON,
+	// so that KV.Replay can reconstruct the state of the store from it.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	OpLog string `json:"opLog"`
+
+	// SnapshotInterval periodically writes a consistent copy of the store
+	// to a new file of SnapshotDir, at this interval, so that the data
+	// collected by long runs survives a crash. Zero, the default, writes
+	// none.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	SnapshotInterval time.Duration `json:"snapshotInterval"`
+
+	// SnapshotDir is the directory the snapshots are written to, created if
+	// it does not exist. It defaults to the directory of the store's file.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	SnapshotDir string `json:"snapshotDir"`
+
+	// SnapshotKeep is the number of the most recent snapshots kept in
+	// SnapshotDir, older ones being deleted as new ones are written. Zero,
+	// the default, keeps them all.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	SnapshotKeep int64 `json:"snapshotKeep"`
+
+	// Bucket is the name of the bucket of the store the KV instance reads
+	// and writes, an isolated keyspace which is created if it does not
+	// exist. It defaults to DefaultKvBucket.
+	Bucket string `json:"bucket"`
+
+	// Indexes declares the secondary indexes maintained on the fields of
+	// the object values of every bucket, which KV.FindByIndex looks keys up
+	// by. They are rebuilt when the store is opened.
+	//
+	// It only applies to the first call to openKv, which opens the store.
+	Indexes []IndexOptions `json:"indexes"`
+
+	// keyPattern is the compiled KeyPattern.
+	keyPattern *regexp.Regexp
+}
+
+// ImportOptions instantiates an Options from a sobek.Value.
+func ImportOptions(rt *sobek.Runtime, options sobek.Value) (Options, error) {
+	openOptions := Options{
+		Mode:             ModeReadWrite,
+		Bucket:           DefaultKvBucket,
+		DefaultListLimit: DefaultListLimit,
+		Serialization:    SerializationJSON,
+	}
+
+	// If no options are passed, return the default options
+	if common.IsNullish(options) {
+		return openOptions, nil
+	}
+
+	// Interpret the options as an object
+	optionsObj := options.ToObject(rt)
+
+	if resume := optionsObj.Get("resume"); !common.IsNullish(resume) {
+		openOptions.Resume = resume.ToBoolean()
+	}
+
+	if maxKeyLength := optionsObj.Get("maxKeyLength"); !common.IsNullish(maxKeyLength) {
+		openOptions.MaxKeyLength = maxKeyLength.ToInteger()
+		if openOptions.MaxKeyLength < 0 {
+			return openOptions, fmt.Errorf("maxKeyLength must not be negative, got %d", openOptions.MaxKeyLength)
+		}
+	}
+
+	if keyPattern := optionsObj.Get("keyPattern"); !common.IsNullish(keyPattern) {
+		compiled, err := regexp.Compile(keyPattern.String())
+		if err != nil {
+			return openOptions, fmt.Errorf("invalid keyPattern: %w", err)
+		}
+
+		openOptions.KeyPattern = keyPattern.String()
+		openOptions.keyPattern = compiled
+	}
+
+	if rejectControl := optionsObj.Get("rejectControlCharacters"); !common.IsNullish(rejectControl) {
+		openOptions.RejectControlCharacters = rejectControl.ToBoolean()
+	}
+
+	if dryRun := optionsObj.Get("dryRun"); !common.IsNullish(dryRun) {
+		openOptions.DryRun = dryRun.ToBoolean()
+	}
+
+	if err := importValueOptions(optionsObj, &openOptions); err != nil {
+		return openOptions, err
+	}
+
+	if verify := optionsObj.Get("verify"); !common.IsNullish(verify) {
+		openOptions.Verify = verify.ToBoolean()
+	}
+
+	if repair := optionsObj.Get("repair"); !common.IsNullish(repair) {
+		openOptions.Repair = repair.ToBoolean()
+	}
+
+	if churnPrefixes := optionsObj.Get("churnPrefixes"); !common.IsNullish(churnPrefixes) {
+		if err := rt.ExportTo(churnPrefixes, &openOptions.ChurnPrefixes); err != nil {
+			return openOptions, fmt.Errorf("invalid churnPrefixes: %w", err)
+		}
+	}
+
+	for name, limit := range map[string]*int64{
+		"maxInFlightOps":      &openOptions.MaxInFlightOps,
+		"maxInFlightOpsPerVU": &openOptions.MaxInFlightOpsPerVU,
+		"maxQueuedOps":        &openOptions.MaxQueuedOps,
+		"defaultListLimit":    &openOptions.DefaultListLimit,
+		"maxValueSize":        &openOptions.MaxValueSize,
+		"workers":             &openOptions.Workers,
+		"snapshotKeep":        &openOptions.SnapshotKeep,
+	} {
+		if value := optionsObj.Get(name); !common.IsNullish(value) {
+			*limit = value.ToInteger()
+			if *limit < 0 {
+				return openOptions, fmt.Errorf("%s must not be negative, got %d", name, *limit)
+			}
+		}
+	}
+
+	if err := importTuningOptions(optionsObj, &openOptions); err != nil {
+		return openOptions, err
+	}
+
+	err := importStoreOptions(optionsObj, &openOptions)
+
+	return openOptions, err
+}
+
+// importStoreOptions imports the options selecting the store, the bucket
+// and the mode to open them in.
+func importStoreOptions(optionsObj *sobek.Object, openOptions *Options) error {
+	if dataset := optionsObj.Get("dataset"); !common.IsNullish(dataset) {
+		openOptions.Dataset = dataset.String()
+	}
+
+	if clearOnStart := optionsObj.Get("clearOnStart"); !common.IsNullish(clearOnStart) {
+		openOptions.ClearOnStart = clearOnStart.ToBoolean()
+	}
+
+	if seed := optionsObj.Get("seed"); !common.IsNullish(seed) {
+		openOptions.Seed = seed.String()
+	}
+
+	if exportOnClose := optionsObj.Get("exportOnClose"); !common.IsNullish(exportOnClose) {
+		openOptions.ExportOnClose = exportOnClose.String()
+	}
+
+	if opLog := optionsObj.Get("opLog"); !common.IsNullish(opLog) {
+		openOptions.OpLog = opLog.String()
+	}
+
+	if snapshotDir := optionsObj.Get("snapshotDir"); !common.IsNullish(snapshotDir) {
+		openOptions.SnapshotDir = snapshotDir.String()
+	}
+
+	if bucket := optionsObj.Get("bucket"); !common.IsNullish(bucket) {
+		if err := validateBucketName(bucket.String()); err != nil {
+			return err
+		}
+
+		openOptions.Bucket = bucket.String()
+	}
+
+	openOptions.Mode = ModeReadWrite
+	if mode := optionsObj.Get("mode"); !common.IsNullish(mode) {
+		openOptions.Mode = mode.String()
+	}
+
+	switch openOptions.Mode {
+	case ModeReadWrite:
+	case ModeSharedReadOnly:
+		if openOptions.Repair {
+			return fmt.Errorf("the repair option cannot be used in the %s mode", ModeSharedReadOnly)
+		}
+
+		if openOptions.Seed != "" {
+			return fmt.Errorf("the seed option cannot be used in the %s mode", ModeSharedReadOnly)
+		}
+
+		if openOptions.ClearOnStart {
+			return fmt.Errorf("the clearOnStart option cannot be used in the %s mode", ModeSharedReadOnly)
+		}
+
+		if openOptions.OpLog != "" {
+			return fmt.Errorf("the opLog option cannot be used in the %s mode", ModeSharedReadOnly)
+		}
+	default:
+		return fmt.Errorf(
+			"mode must be either %q or %q, got %q", ModeReadWrite, ModeSharedReadOnly, openOptions.Mode,
+		)
+	}
+
+	return nil
+}
+
+// importTuningOptions imports the options tuning how the store's file is
+// opened and written to.
+func importTuningOptions(optionsObj *sobek.Object, openOptions *Options) error {
+	for name, duration := range map[string]*time.Duration{
+		"openTimeout":      &openOptions.OpenTimeout,
+		"flushInterval":    &openOptions.FlushInterval,
+		"snapshotInterval": &openOptions.SnapshotInterval,
+	} {
+		value, err := toDuration(optionsObj.Get(name))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+
+		if value < 0 {
+			return fmt.Errorf("%s must not be negative, got %s", name, value)
+		}
+
+		*duration = value
+	}
+
+	if keyIndex := optionsObj.Get("keyIndex"); !common.IsNullish(keyIndex) {
+		openOptions.KeyIndex = keyIndex.ToBoolean()
+	}
+
+	if retry := optionsObj.Get("retry"); !common.IsNullish(retry) {
+		openOptions.Retry = retry.ToBoolean()
+	}
+
+	if noSync := optionsObj.Get("noSync"); !common.IsNullish(noSync) {
+		openOptions.NoSync = noSync.ToBoolean()
+	}
+
+	if mmapSize := optionsObj.Get("initialMmapSize"); !common.IsNullish(mmapSize) {
+		openOptions.InitialMmapSize = mmapSize.ToInteger()
+		if openOptions.InitialMmapSize < 0 {
+			return fmt.Errorf("initialMmapSize must not be negative, got %d", openOptions.InitialMmapSize)
+		}
+	}
+
+	if freelistType := optionsObj.Get("freelistType"); !common.IsNullish(freelistType) {
+		openOptions.FreelistType = freelistType.String()
+		if openOptions.FreelistType != FreelistArray && openOptions.FreelistType != FreelistHashmap {
+			return fmt.Errorf(
+				"freelistType must be either %q or %q, got %q", FreelistArray, FreelistHashmap, openOptions.FreelistType,
+			)
+		}
+	}
+
+	return nil
+}
+
+// importValueOptions imports the options affecting how values are stored.
+func importValueOptions(optionsObj *sobek.Object, openOptions *Options) error {
+	if deduplicate := optionsObj.Get("deduplicate"); !common.IsNullish(deduplicate) {
+		openOptions.Deduplicate = deduplicate.ToBoolean()
+	}
+
+	if compression := optionsObj.Get("compression"); !common.IsNullish(compression) {
+		if err := validateCompression(compression.String()); err != nil {
+			return err
+		}
+
+		openOptions.Compression = compression.String()
+	}
+
+	if serialization := optionsObj.Get("serialization"); !common.IsNullish(serialization) {
+		openOptions.Serialization = serialization.String()
+		if _, registered := lookupSerializer(openOptions.Serialization); !registered &&
+			openOptions.Serialization != SerializationJSON && openOptions.Serialization != SerializationBinary {
+			return fmt.Errorf(
+				"serialization must be either %q, %q or the name of a registered serializer, got %q",
+				SerializationJSON, SerializationBinary, openOptions.Serialization,
+			)
+		}
+	}
+
+	if undoPrefix := optionsObj.Get("undoPrefix"); !common.IsNullish(undoPrefix) {
+		openOptions.UndoPrefix = undoPrefix.String()
+	}
+
+	if indexes := optionsObj.Get("indexes"); !common.IsNullish(indexes) {
+		imported, err := importIndexOptions(indexes)
+		if err != nil {
+			return err
+		}
+
+		openOptions.Indexes = imported
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/base64"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ListPage is a page of entries returned by KV.List() when the cursor option is set.
+type ListPage struct {
+	// Entries are the entries of the page.
+	Entries []ListEntry `json:"entries" js:"entries"`
+
+	// Cursor is the cursor to pass to KV.List() to read the next page.
+	Cursor string `json:"cursor" js:"cursor"`
+
+	// Done is true when there are no entries left after this page.
+	Done bool `json:"done" js:"done"`
+}
+
+// encodeCursor returns the cursor resuming a listing after the given key.
+func encodeCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeCursor returns the key a listing resumes after, given its cursor.
+func decodeCursor(cursor string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(cursor)
+}
+
+// listEntries reads the visible entries of the bucket matching the list
+// options, in key order, from the key after the options' cursor, if any.
+//
+// Rather than walking the whole bucket, it seeks to the first key of the
+// page, and stops at the last one. It reports whether entries ran out before
+// reaching the limit.
+func (k *KV) listEntries(tx *bolt.Tx, bucket *bolt.Bucket, options ListOptions) ([]ListEntry, bool, error) {
+	var entries []ListEntry
+
+	visible := newVisibility(tx, k.bucket)
+	prefix := []byte(options.Prefix)
+	cursor := bucket.Cursor()
+
+	key, raw := cursor.Seek(prefix)
+	if options.after != nil && bytes.Compare(options.after, prefix) >= 0 {
+		key, raw = cursor.Seek(options.after)
+		if bytes.Equal(key, options.after) {
+			key, raw = cursor.Next()
+		}
+	}
+
+	for ; key != nil && bytes.HasPrefix(key, prefix); key, raw = cursor.Next() {
+		if visible.hidden(key) || !options.matches(key) {
+			continue
+		}
+
+		if options.Limit > 0 && int64(len(entries)) >= options.Limit {
+			return entries, false, nil
+		}
+
+		jsonValue, err := loadValue(tx, raw)
+		if err != nil {
+			return nil, false, err
+		}
+
+		k.db.stats.bytesRead.Add(int64(len(jsonValue)))
+
+		if options.where != nil {
+			matches, err := options.where.matches(jsonValue)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if !matches {
+				continue
+			}
+		}
+
+		value, err := decodeFields(jsonValue, options.Fields)
+		if err != nil {
+			return nil, false, err
+		}
+
+		entries = append(entries, ListEntry{string(key), value})
+	}
+
+	return entries, true, nil
+}
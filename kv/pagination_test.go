@@ -0,0 +1,71 @@
+package kv
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVListEntries(t *testing.T) {
+	t.Parallel()
+
+	dbInstance := openTestDB(t, Options{})
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(DefaultKvBucket))
+		for _, key := range []string{"a", "user:1", "user:2", "user:3", "z"} {
+			if err := bucket.Put([]byte(key), []byte(`"`+key+`"`)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	kv := &KV{bucket: []byte(DefaultKvBucket), db: dbInstance}
+	page := func(options ListOptions) ([]string, bool) {
+		var (
+			keys []string
+			done bool
+		)
+
+		require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			entries, ok, err := kv.listEntries(tx, tx.Bucket([]byte(DefaultKvBucket)), options)
+			for _, entry := range entries {
+				keys = append(keys, entry.Key)
+			}
+			done = ok
+
+			return err
+		}))
+
+		return keys, done
+	}
+
+	keys, done := page(ListOptions{Prefix: "user:", Limit: 2, limitSet: true})
+	assert.Equal(t, []string{"user:1", "user:2"}, keys)
+	assert.False(t, done)
+
+	after, err := decodeCursor(encodeCursor("user:2"))
+	require.NoError(t, err)
+
+	keys, done = page(ListOptions{Prefix: "user:", Limit: 2, limitSet: true, after: after})
+	assert.Equal(t, []string{"user:3"}, keys)
+	assert.True(t, done)
+
+	keys, done = page(ListOptions{})
+	assert.Equal(t, []string{"a", "user:1", "user:2", "user:3", "z"}, keys)
+	assert.True(t, done)
+
+	glob, err := compileGlob("user:[!2]")
+	require.NoError(t, err)
+
+	keys, done = page(ListOptions{Prefix: "user:", Limit: 1, limitSet: true, filters: []*regexp.Regexp{glob}})
+	assert.Equal(t, []string{"user:1"}, keys)
+	assert.False(t, done)
+
+	keys, _ = page(ListOptions{filters: []*regexp.Regexp{glob, regexp.MustCompile(`[2-9]$`)}})
+	assert.Equal(t, []string{"user:3"}, keys)
+}
@@ -0,0 +1,315 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// MergePatch atomically applies a JSON merge patch (RFC 7386) to the value of
+// a key, treated as null if the key does not exist, and resolves with the
+// patched value.
+//
+// Fields of the patch are set recursively on the value, and fields set to
+// null are deleted from it, so that only the fields which change need to be
+// sent.
+func (k *KV) MergePatch(key sobek.Value, patch sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonPatch, err := json.Marshal(patch.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var patched any
+
+		err := k.mutate(mutation{op: "mergePatch", key: keyBytes}, func(tx *bolt.Tx) error {
+			document, err := k.loadDocument(tx, keyBytes)
+			if err != nil {
+				return err
+			}
+
+			decodedPatch, err := decodeDocument(jsonPatch)
+			if err != nil {
+				return err
+			}
+
+			jsonPatched, err := k.storeDocument(tx, keyBytes, mergePatch(document, decodedPatch))
+			if err != nil {
+				return err
+			}
+
+			return json.Unmarshal(jsonPatched, &patched)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(patched)
+	}()
+
+	return promise
+}
+
+// SetPath atomically sets the field at a dot-separated path, such as
+// "a.b.c", of the value of a key, and resolves with the value set.
+//
+// Missing objects along the path are created, as is the key if it does not
+// exist. Numeric segments index arrays.
+func (k *KV) SetPath(key sobek.Value, path string, value sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonValue, err := json.Marshal(value.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		err := k.mutate(mutation{op: "setPath", key: keyBytes}, func(tx *bolt.Tx) error {
+			document, err := k.loadDocument(tx, keyBytes)
+			if err != nil {
+				return err
+			}
+
+			decodedValue, err := decodeDocument(jsonValue)
+			if err != nil {
+				return err
+			}
+
+			document, err = setPath(document, splitPath(path), decodedValue)
+			if err != nil {
+				return NewError(TypeMismatchError, "cannot set path "+path+" of key "+string(keyBytes)+": "+err.Error())
+			}
+
+			_, err = k.storeDocument(tx, keyBytes, document)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(value)
+	}()
+
+	return promise
+}
+
+// GetPath resolves with the field at a dot-separated path, such as "a.b.c",
+// of the value of a key, or null if either does not exist.
+func (k *KV) GetPath(key sobek.Value, path string) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var field any
+
+		err := k.view(func(tx *bolt.Tx) error {
+			var document any
+			if err := k.loadTyped(tx, keyBytes, "a JSON value", &document); err != nil {
+				return err
+			}
+
+			field, _ = getPath(document, splitPath(path))
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(field)
+	}()
+
+	return promise
+}
+
+// loadDocument decodes the JSON value of a visible key of the bucket, with
+// numbers kept as json.Number, so that they are written back unchanged.
+// It returns nil if the key does not exist.
+func (k *KV) loadDocument(tx *bolt.Tx, key []byte) (any, error) {
+	var raw json.RawMessage
+	if err := k.loadTyped(tx, key, "a JSON value", &raw); err != nil || raw == nil {
+		return nil, err
+	}
+
+	return decodeDocument(raw)
+}
+
+// storeDocument stores the JSON encoding of a document in a key of the
+// bucket, and returns it.
+func (k *KV) storeDocument(tx *bolt.Tx, key []byte, document any) ([]byte, error) {
+	jsonValue, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := k.validateValue(key, jsonValue); err != nil {
+		return nil, err
+	}
+
+	if err := undelay(tx, k.bucket, key); err != nil {
+		return nil, err
+	}
+
+	return jsonValue, k.storeValue(tx, tx.Bucket(k.bucket), key, jsonValue)
+}
+
+// decodeDocument decodes a JSON value, keeping numbers as json.Number.
+func decodeDocument(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// mergePatch returns the target with the merge patch applied to it, as
+// described by RFC 7386. The target is modified in place.
+func mergePatch(target, patch any) any {
+	patchObject, isObject := patch.(map[string]any)
+	if !isObject {
+		return patch
+	}
+
+	targetObject, isObject := target.(map[string]any)
+	if !isObject {
+		targetObject = make(map[string]any, len(patchObject))
+	}
+
+	for field, value := range patchObject {
+		if value == nil {
+			delete(targetObject, field)
+			continue
+		}
+
+		targetObject[field] = mergePatch(targetObject[field], value)
+	}
+
+	return targetObject
+}
+
+// splitPath splits a dot-separated path into its segments.
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, ".")
+}
+
+// getPath returns the field at the path of the value, and whether it exists.
+func getPath(value any, path []string) (any, bool) {
+	for _, segment := range path {
+		switch container := value.(type) {
+		case map[string]any:
+			field, found := container[segment]
+			if !found {
+				return nil, false
+			}
+
+			value = field
+		case []any:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(container) {
+				return nil, false
+			}
+
+			value = container[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// setPath returns the value with the field at the path set, creating the
+// missing objects along it. The value is modified in place. It fails if the
+// path goes through a scalar, or out of the bounds of an array.
+func setPath(value any, path []string, field any) (any, error) {
+	if len(path) == 0 {
+		return field, nil
+	}
+
+	segment := path[0]
+
+	switch container := value.(type) {
+	case nil:
+		nested, err := setPath(nil, path[1:], field)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{segment: nested}, nil
+	case map[string]any:
+		nested, err := setPath(container[segment], path[1:], field)
+		if err != nil {
+			return nil, err
+		}
+
+		container[segment] = nested
+
+		return container, nil
+	case []any:
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < 0 || i >= len(container) {
+			return nil, errors.New("no index " + segment + " in array")
+		}
+
+		nested, err := setPath(container[i], path[1:], field)
+		if err != nil {
+			return nil, err
+		}
+
+		container[i] = nested
+
+		return container, nil
+	default:
+		return nil, errors.New("field " + segment + " is not in an object")
+	}
+}
@@ -0,0 +1,91 @@
+package kv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+
+	// Examples from RFC 7386, appendix A
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"big":9007199254740993}`, `{"a":1}`, `{"a":1,"big":9007199254740993}`},
+	}
+
+	for _, tt := range tests {
+		target, err := decodeDocument([]byte(tt.target))
+		require.NoError(t, err)
+
+		patch, err := decodeDocument([]byte(tt.patch))
+		require.NoError(t, err)
+
+		got, err := json.Marshal(mergePatch(target, patch))
+		require.NoError(t, err)
+		assert.JSONEq(t, tt.want, string(got), "patching %s with %s", tt.target, tt.patch)
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	t.Parallel()
+
+	document, err := decodeDocument([]byte(`{"a":{"b":[{"c":1}]}}`))
+	require.NoError(t, err)
+
+	field, found := getPath(document, splitPath("a.b.0.c"))
+	assert.True(t, found)
+	assert.Equal(t, json.Number("1"), field)
+
+	for _, path := range []string{"a.x", "a.b.1", "a.b.c", "a.b.0.c.d"} {
+		_, found = getPath(document, splitPath(path))
+		assert.False(t, found, path)
+	}
+
+	field, found = getPath(document, splitPath(""))
+	assert.True(t, found)
+	assert.Equal(t, document, field)
+}
+
+func TestSetPath(t *testing.T) {
+	t.Parallel()
+
+	document, err := setPath(nil, splitPath("a.b.c"), "x")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": map[string]any{"b": map[string]any{"c": "x"}}}, document)
+
+	document, err = decodeDocument([]byte(`{"a":{"b":[{"c":1}]},"d":true}`))
+	require.NoError(t, err)
+
+	document, err = setPath(document, splitPath("a.b.0.c"), "y")
+	require.NoError(t, err)
+
+	got, err := json.Marshal(document)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":{"b":[{"c":"y"}]},"d":true}`, string(got))
+
+	_, err = setPath(document, splitPath("a.b.1"), "z")
+	assert.Error(t, err)
+
+	_, err = setPath(document, splitPath("d.e"), "z")
+	assert.Error(t, err)
+}
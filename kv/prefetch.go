@@ -0,0 +1,106 @@
+package kv
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// Prefetch reads the given keys, or the keys starting with a prefix when
+// passed { prefix }, in the background, so that the pages of the store file
+// holding them are in the OS page cache before they are needed. It resolves
+// with the number of keys read.
+//
+// It is meant to warm up the store ahead of a measured phase of a test, so
+// that its first iterations are not penalized by cold reads.
+func (k *KV) Prefetch(keys sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(keys) {
+		reject(fmt.Errorf("prefetch expects an array of keys or a { prefix } object, got %v", keys))
+		return promise
+	}
+
+	var (
+		keyList [][]byte
+		prefix  []byte
+	)
+
+	rt := k.vu.Runtime()
+	if exported, isArray := keys.Export().([]any); isArray {
+		for _, key := range exported {
+			keyBytes, err := common.ToBytes(key)
+			if err != nil {
+				reject(err)
+				return promise
+			}
+
+			keyList = append(keyList, keyBytes)
+		}
+	} else {
+		prefix = []byte(keys.ToObject(rt).Get("prefix").String())
+	}
+
+	go func() {
+		var read int64
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			touch := func(raw []byte) error {
+				value, err := loadValue(tx, raw)
+				if err != nil {
+					return err
+				}
+
+				// Reading every byte of the value pages it in, even when it
+				// spans overflow pages that looking the key up does not touch.
+				var sum byte
+				for _, b := range value {
+					sum ^= b
+				}
+				_ = sum
+
+				read++
+
+				return nil
+			}
+
+			if keyList != nil {
+				for _, key := range keyList {
+					if raw := bucket.Get(key); raw != nil {
+						if err := touch(raw); err != nil {
+							return err
+						}
+					}
+				}
+
+				return nil
+			}
+
+			cursor := bucket.Cursor()
+			for key, raw := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, raw = cursor.Next() {
+				if err := touch(raw); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(read)
+	}()
+
+	return promise
+}
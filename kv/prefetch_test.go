@@ -0,0 +1,56 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVPrefetch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("prefetching resolves with the number of keys read", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			Promise.all([
+				store.set("users:1", "alice"),
+				store.set("users:2", "x".repeat(64 * 1024)),
+				store.set("orders:1", "book"),
+			])
+				.then(() => Promise.all([
+					store.prefetch(["users:1", "users:2", "missing"]),
+					store.prefetch({ prefix: "users:" }),
+					store.prefetch({ prefix: "" }),
+				]))
+				.then(([listed, prefixed, all]) => {
+					if (listed !== 2 || prefixed !== 2 || all !== 3) {
+						throw new Error("expected 2, 2 and 3 keys to be read, got " + [listed, prefixed, all]);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("prefetching without keys is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			kv.openKv().prefetch().then(
+				() => { throw new Error("expected prefetch to reject"); },
+				(err) => {
+					if (!String(err).includes("prefetch expects an array of keys")) {
+						throw err;
+					}
+				},
+			);
+		`)
+		require.NoError(t, err)
+	})
+}
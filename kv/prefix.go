@@ -0,0 +1,150 @@
+package kv
+
+import (
+	"bytes"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// Count resolves with the number of keys in the store starting with the
+// prefix option, or all of them, without reading their values.
+func (k *KV) Count(options sobek.Value) *sobek.Promise {
+	return k.count(k.prefixOption(options))
+}
+
+// count resolves with the number of keys in the store starting with the prefix.
+func (k *KV) count(prefix []byte) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	go func() {
+		var count int64
+
+		err := k.viewKeys(string(prefix), func(keys []string) error {
+			count = int64(len(keys))
+			return nil
+		}, func(tx *bolt.Tx) error {
+			var err error
+			count, err = k.countPrefix(tx, prefix)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(count)
+	}()
+
+	return promise
+}
+
+// countPrefix returns the number of visible keys of the bucket starting with the prefix.
+func (k *KV) countPrefix(tx *bolt.Tx, prefix []byte) (int64, error) {
+	bucket := tx.Bucket(k.bucket)
+	if bucket == nil {
+		return 0, NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+	}
+
+	visible := newVisibility(tx, k.bucket)
+
+	var count int64
+
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		if !visible.hidden(key) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// DeleteByPrefix deletes all the keys starting with the given prefix
+// within a single transaction, and resolves with the number of keys deleted.
+func (k *KV) DeleteByPrefix(prefix sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(prefix) || prefix.String() == "" {
+		reject(NewError(KeyRequiredError, "prefix must not be empty, use clear() to delete all the keys"))
+		return promise
+	}
+
+	prefixBytes := []byte(prefix.String())
+
+	go func() {
+		var deleted int64
+
+		err := k.mutate(mutation{op: "deleteByPrefix"}, func(tx *bolt.Tx) error {
+			var err error
+			deleted, err = k.deletePrefix(tx, prefixBytes)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(deleted)
+	}()
+
+	return promise
+}
+
+// deletePrefix deletes the keys of the bucket starting with the prefix, and
+// returns how many were deleted.
+//
+// The matching keys are found with a cursor seeking to the first of them,
+// rather than walking the whole bucket.
+func (k *KV) deletePrefix(tx *bolt.Tx, prefix []byte) (int64, error) {
+	bucket := tx.Bucket(k.bucket)
+	if bucket == nil {
+		return 0, NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+	}
+
+	// Collect the keys first, as deleting keys while
+	// iterating with a cursor may skip some of them.
+	var keys [][]byte
+
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		keys = append(keys, bytes.Clone(key))
+	}
+
+	for _, key := range keys {
+		if err := undelay(tx, k.bucket, key); err != nil {
+			return 0, err
+		}
+
+		if err := k.removeValue(tx, bucket, key); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(keys)), nil
+}
+
+// clearPrefix deletes all the keys starting with the prefix, and resolves
+// with true, as KV.Clear does.
+func (k *KV) clearPrefix(prefix []byte) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	k.db.workers.Load().run(func() {
+		err := k.mutate(mutation{op: "clear"}, func(tx *bolt.Tx) error {
+			_, err := k.deletePrefix(tx, prefix)
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(true)
+	})
+
+	return promise
+}
@@ -0,0 +1,56 @@
+package kv
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVDeletePrefix(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, kv.storeValue(tx, bucket, []byte("session:"+strconv.Itoa(i)), []byte(`1`)))
+		}
+
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("sessions"), []byte(`1`)))
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("user:1"), []byte(`1`)))
+
+		return nil
+	}))
+
+	require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+		count, err := kv.countPrefix(tx, []byte("session:"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000), count)
+
+		count, err = kv.countPrefix(tx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1002), count)
+
+		return nil
+	}))
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		deleted, err := kv.deletePrefix(tx, []byte("session:"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000), deleted)
+
+		var remaining []string
+		_ = tx.Bucket(kv.bucket).ForEach(func(k, _ []byte) error {
+			remaining = append(remaining, string(k))
+			return nil
+		})
+		assert.Equal(t, []string{"sessions", "user:1"}, remaining)
+
+		return nil
+	}))
+}
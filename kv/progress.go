@@ -0,0 +1,97 @@
+package kv
+
+import (
+	"encoding/json"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// ProgressBucket is the name of the internal bucket holding the
+// progress marked through KV.MarkProgress.
+//
+// Unless the store is opened with the resume option, it is
+// discarded when the store is opened.
+const ProgressBucket = "k6/progress"
+
+// MarkProgress durably records the cursor a test reached for the named task,
+// so that a later run opened with the resume option can pick up from it.
+//
+// The cursor can be any JSON-serializable value, such as the index of the
+// last processed record, or the last processed key.
+func (k *KV) MarkProgress(name sobek.Value, cursor sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(name) || name.String() == "" {
+		reject(NewError(KeyRequiredError, "progress name is required"))
+		return promise
+	}
+
+	jsonCursor, err := json.Marshal(cursor.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	key := []byte(name.String())
+
+	go func() {
+		err := k.mutate(mutation{op: "markProgress", key: key, value: jsonCursor, internal: true}, func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(ProgressBucket))
+			if err != nil {
+				return err
+			}
+
+			return bucket.Put(key, jsonCursor)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(cursor)
+	}()
+
+	return promise
+}
+
+// ResumeFrom resolves with the cursor last recorded for the named task
+// through KV.MarkProgress, or null if no progress was recorded.
+func (k *KV) ResumeFrom(name sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(name) || name.String() == "" {
+		reject(NewError(KeyRequiredError, "progress name is required"))
+		return promise
+	}
+
+	key := []byte(name.String())
+
+	go func() {
+		var cursor any
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(ProgressBucket))
+			if bucket == nil {
+				return nil
+			}
+
+			jsonCursor := bucket.Get(key)
+			if jsonCursor == nil {
+				return nil
+			}
+
+			return json.Unmarshal(jsonCursor, &cursor)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(cursor)
+	}()
+
+	return promise
+}
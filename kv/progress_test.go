@@ -0,0 +1,56 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVProgress(t *testing.T) {
+	t.Parallel()
+
+	t.Run("the last recorded cursor is resumed from", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.markProgress("import", { offset: 10 })
+				.then(() => store.markProgress("import", { offset: 20 }))
+				.then((cursor) => {
+					if (cursor.offset !== 20) {
+						throw new Error("expected markProgress to resolve with the cursor, got " + JSON.stringify(cursor));
+					}
+
+					return store.resumeFrom("import");
+				})
+				.then((cursor) => {
+					if (cursor === null || cursor.offset !== 20) {
+						throw new Error("expected to resume from the last cursor, got " + JSON.stringify(cursor));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("resuming without recorded progress resolves with null", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.markProgress("other", 1)
+				.then(() => store.resumeFrom("import"))
+				.then((cursor) => {
+					if (cursor !== null) {
+						throw new Error("expected null, got " + JSON.stringify(cursor));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+}
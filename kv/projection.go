@@ -0,0 +1,42 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// decodeFields decodes the given fields of a JSON-encoded object, without
+// decoding the others. Missing fields are omitted.
+//
+// Values which are not objects are decoded as a whole. Without fields, the
+// whole value is decoded.
+func decodeFields(jsonValue []byte, fields []string) (any, error) {
+	if len(fields) == 0 || !bytes.HasPrefix(bytes.TrimSpace(jsonValue), []byte("{")) {
+		var value any
+		err := decodeValue(jsonValue, &value)
+
+		return value, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(jsonValue, &raw); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]any, len(fields))
+	for _, field := range fields {
+		rawField, found := raw[field]
+		if !found {
+			continue
+		}
+
+		var value any
+		if err := json.Unmarshal(rawField, &value); err != nil {
+			return nil, err
+		}
+
+		projected[field] = value
+	}
+
+	return projected, nil
+}
@@ -0,0 +1,26 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeFields(t *testing.T) {
+	t.Parallel()
+
+	user := []byte(`{"id": 1, "token": "abc", "profile": {"bio": "a long bio"}}`)
+
+	value, err := decodeFields(user, []string{"id", "token", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": 1.0, "token": "abc"}, value)
+
+	value, err = decodeFields(user, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": 1.0, "token": "abc", "profile": map[string]any{"bio": "a long bio"}}, value)
+
+	value, err = decodeFields([]byte(`[1, 2]`), []string{"id"})
+	require.NoError(t, err)
+	assert.Equal(t, []any{1.0, 2.0}, value)
+}
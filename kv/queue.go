@@ -0,0 +1,159 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// QueuesBucket is the name of the internal bucket holding the work queues
+// used through KV.Enqueue and KV.Dequeue, each in its own nested bucket.
+//
+// The items of a queue are keyed by the 8-byte big-endian sequence number
+// they were enqueued with, so that they are ordered first-in, first-out.
+const QueuesBucket = "k6/queues"
+
+// Enqueue appends a value to the named queue, and resolves with the number
+// of items in the queue.
+//
+// Queues are updated in dry-run mode as well, as they hand work over
+// between VUs.
+func (k *KV) Enqueue(queue sobek.Value, value sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(queue) || queue.String() == "" {
+		reject(NewError(KeyRequiredError, "queue name is required"))
+		return promise
+	}
+
+	name := []byte(queue.String())
+
+	jsonValue, err := k.marshal(name, value)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var length int64
+
+		err := k.coordinate(func(tx *bolt.Tx) error {
+			var err error
+			length, err = pushQueue(tx, name, jsonValue)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(length)
+	}()
+
+	return promise
+}
+
+// Dequeue removes the oldest value of the named queue, and resolves with it,
+// or with null if the queue is empty.
+//
+// Each value is dequeued exactly once, even when several VUs dequeue from
+// the same queue concurrently.
+func (k *KV) Dequeue(queue sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(queue) || queue.String() == "" {
+		reject(NewError(KeyRequiredError, "queue name is required"))
+		return promise
+	}
+
+	name := []byte(queue.String())
+	settle := k.reviveLater(resolve, reject)
+
+	go func() {
+		var jsonValue []byte
+
+		err := k.coordinate(func(tx *bolt.Tx) error {
+			var err error
+			jsonValue, err = popQueue(tx, name)
+
+			return err
+		})
+
+		settle(func() (any, error) {
+			if err != nil || jsonValue == nil {
+				return nil, err
+			}
+
+			var value any
+			if err := decodeValue(jsonValue, &value); err != nil {
+				return nil, err
+			}
+
+			return k.revive(name, value)
+		})
+	}()
+
+	return promise
+}
+
+// pushQueue appends a value to the named queue, and returns the number of
+// items in the queue.
+func pushQueue(tx *bolt.Tx, name, value []byte) (int64, error) {
+	root, err := tx.CreateBucketIfNotExists([]byte(QueuesBucket))
+	if err != nil {
+		return 0, err
+	}
+
+	bucket, err := root.CreateBucketIfNotExists(name)
+	if err != nil {
+		return 0, err
+	}
+
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+
+	if err := bucket.Put(key, value); err != nil {
+		return 0, err
+	}
+
+	// Items are only ever removed from the front of the queue, so
+	// that their sequence numbers are contiguous.
+	first, _ := bucket.Cursor().First()
+
+	return int64(seq-binary.BigEndian.Uint64(first)) + 1, nil
+}
+
+// popQueue removes the oldest value of the named queue, and returns a copy
+// of it, or nil if the queue is empty.
+func popQueue(tx *bolt.Tx, name []byte) ([]byte, error) {
+	root := tx.Bucket([]byte(QueuesBucket))
+	if root == nil {
+		return nil, nil
+	}
+
+	bucket := root.Bucket(name)
+	if bucket == nil {
+		return nil, nil
+	}
+
+	cursor := bucket.Cursor()
+
+	key, value := cursor.First()
+	if key == nil {
+		return nil, nil
+	}
+
+	value = bytes.Clone(value)
+
+	return value, cursor.Delete()
+}
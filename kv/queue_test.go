@@ -0,0 +1,65 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestQueuePushPop(t *testing.T) {
+	t.Parallel()
+
+	dbInstance := openTestDB(t, Options{})
+	jobs := []byte("jobs")
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		value, err := popQueue(tx, jobs)
+		require.NoError(t, err)
+		assert.Nil(t, value)
+
+		// More than 256 items make sure they are ordered numerically.
+		for i := 1; i <= 300; i++ {
+			length, err := pushQueue(tx, jobs, []byte{byte(i)})
+			require.NoError(t, err)
+			assert.Equal(t, int64(i), length)
+		}
+
+		for i := 1; i <= 299; i++ {
+			value, err := popQueue(tx, jobs)
+			require.NoError(t, err)
+			assert.Equal(t, []byte{byte(i)}, value)
+		}
+
+		length, err := pushQueue(tx, jobs, []byte("last"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), length)
+
+		return nil
+	}))
+}
+
+func TestKVQueueDryRun(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	err := vu.run(`
+		const store = kv.openKv({ dryRun: true });
+
+		store.enqueue("jobs", { id: 1 })
+			.then(() => store.dequeue("jobs"))
+			.then((job) => {
+				if (job === null || job.id !== 1) {
+					throw new Error("expected the job to be dequeued in dry-run mode, got " + JSON.stringify(job));
+				}
+
+				const report = store.dryRunReport();
+				if (report.length !== 0) {
+					throw new Error("expected no mutation to be reported, got " + JSON.stringify(report));
+				}
+			});
+	`)
+	require.NoError(t, err)
+}
@@ -0,0 +1,167 @@
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// RateLimitsBucket is the name of the internal bucket holding the state
+// of the rate limiters used through KV.RateLimit, by name.
+const RateLimitsBucket = "k6/ratelimits"
+
+// RateLimitOptions are the options of a rate limiter.
+type RateLimitOptions struct {
+	// Rate is the number of calls allowed per period.
+	Rate int64
+
+	// Per is the period the rate applies to. Defaults to one second.
+	Per time.Duration
+
+	// Burst is the number of calls allowed at once, after the limiter
+	// was left unused. Defaults to the rate.
+	Burst int64
+}
+
+// ImportRateLimitOptions instantiates a RateLimitOptions from a sobek.Value.
+func ImportRateLimitOptions(rt *sobek.Runtime, options sobek.Value) (RateLimitOptions, error) {
+	limitOptions := RateLimitOptions{Per: time.Second}
+
+	if common.IsNullish(options) {
+		return limitOptions, fmt.Errorf("rate limit options are required")
+	}
+
+	obj := options.ToObject(rt)
+
+	if v := obj.Get("rate"); !common.IsNullish(v) {
+		limitOptions.Rate = v.ToInteger()
+	}
+
+	if limitOptions.Rate <= 0 {
+		return limitOptions, fmt.Errorf("rate must be a positive integer, got %v", obj.Get("rate"))
+	}
+
+	if v := obj.Get("per"); !common.IsNullish(v) {
+		per, err := toDuration(v)
+		if err != nil {
+			return limitOptions, fmt.Errorf("invalid per: %w", err)
+		}
+
+		if per <= 0 {
+			return limitOptions, fmt.Errorf("per must be positive, got %s", per)
+		}
+
+		limitOptions.Per = per
+	}
+
+	limitOptions.Burst = limitOptions.Rate
+	if v := obj.Get("burst"); !common.IsNullish(v) {
+		limitOptions.Burst = v.ToInteger()
+	}
+
+	if limitOptions.Burst <= 0 {
+		return limitOptions, fmt.Errorf("burst must be a positive integer, got %v", obj.Get("burst"))
+	}
+
+	return limitOptions, nil
+}
+
+// tokenBucket is the state of a rate limiter, as stored in the
+// RateLimitsBucket.
+type tokenBucket struct {
+	// Tokens is the number of calls allowed as of Updated.
+	Tokens float64 `json:"tokens"`
+
+	// Updated is when the tokens were last counted, in Unix nanoseconds.
+	Updated int64 `json:"updated"`
+}
+
+// take refills the bucket with the tokens accrued since it was last
+// updated, up to the burst, and takes one, if any is left. It reports
+// whether a token was taken.
+func (b *tokenBucket) take(now time.Time, options RateLimitOptions) bool {
+	elapsed := now.UnixNano() - b.Updated
+	if elapsed > 0 {
+		b.Tokens += float64(elapsed) * float64(options.Rate) / float64(options.Per)
+		b.Updated = now.UnixNano()
+	}
+
+	if burst := float64(options.Burst); b.Tokens > burst {
+		b.Tokens = burst
+	}
+
+	if b.Tokens < 1 {
+		return false
+	}
+
+	b.Tokens--
+
+	return true
+}
+
+// RateLimit resolves with true if a call is allowed by the named rate
+// limiter, shared by all VUs, or false if the rate is exceeded.
+//
+// The limiter is a token bucket, holding up to burst tokens, refilled at
+// the given rate, and of which each allowed call takes one. Its state is
+// updated atomically in the store, and created full the first time it is
+// used. Subsequent calls with the same name should pass the same options.
+// It is updated in dry-run mode as well, so that the rate is still limited.
+func (k *KV) RateLimit(name sobek.Value, options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(name) || name.String() == "" {
+		reject(NewError(KeyRequiredError, "rate limiter name is required"))
+		return promise
+	}
+
+	limitOptions, err := ImportRateLimitOptions(k.vu.Runtime(), options)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	nameBytes := []byte(name.String())
+
+	go func() {
+		var allowed bool
+
+		err := k.coordinate(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(RateLimitsBucket))
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			state := tokenBucket{Tokens: float64(limitOptions.Burst), Updated: now.UnixNano()}
+
+			if raw := bucket.Get(nameBytes); raw != nil {
+				if err := json.Unmarshal(raw, &state); err != nil {
+					return err
+				}
+			}
+
+			allowed = state.take(now, limitOptions)
+
+			raw, err := json.Marshal(state)
+			if err != nil {
+				return err
+			}
+
+			return bucket.Put(nameBytes, raw)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(allowed)
+	}()
+
+	return promise
+}
@@ -0,0 +1,58 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	t.Parallel()
+
+	options := RateLimitOptions{Rate: 10, Per: time.Second, Burst: 2}
+	start := time.Unix(0, 0)
+	state := tokenBucket{Tokens: 2, Updated: start.UnixNano()}
+
+	// The burst is allowed at once
+	assert.True(t, state.take(start, options))
+	assert.True(t, state.take(start, options))
+	assert.False(t, state.take(start, options))
+
+	// A token is accrued every 100ms
+	assert.False(t, state.take(start.Add(50*time.Millisecond), options))
+	assert.True(t, state.take(start.Add(100*time.Millisecond), options))
+	assert.False(t, state.take(start.Add(100*time.Millisecond), options))
+
+	// Tokens accrue up to the burst only
+	later := start.Add(time.Hour)
+	assert.True(t, state.take(later, options))
+	assert.True(t, state.take(later, options))
+	assert.False(t, state.take(later, options))
+}
+
+func TestKVRateLimitDryRun(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	err := vu.run(`
+		const store = kv.openKv({ dryRun: true });
+		const options = { rate: 1, per: "1h" };
+
+		store.rateLimit("api", options)
+			.then(() => store.rateLimit("api", options))
+			.then((allowed) => {
+				if (allowed) {
+					throw new Error("expected the rate to be limited in dry-run mode");
+				}
+
+				const report = store.dryRunReport();
+				if (report.length !== 0) {
+					throw new Error("expected no mutation to be reported, got " + JSON.stringify(report));
+				}
+			});
+	`)
+	require.NoError(t, err)
+}
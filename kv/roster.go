@@ -0,0 +1,330 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// RosterBucket is the name of the internal bucket holding the participants
+// registered through KV.Register.
+const RosterBucket = "k6/roster"
+
+// DefaultHeartbeatTTL is the default duration a participant is considered
+// live for after its last heartbeat.
+const DefaultHeartbeatTTL = 30 * time.Second
+
+// Participant is a VU registered in the roster through KV.Register.
+type Participant struct {
+	// ID uniquely identifies the participant, across instances.
+	ID string `json:"id" js:"id"`
+
+	// Instance identifies the k6 instance the participant runs in.
+	Instance string `json:"instance" js:"instance"`
+
+	// VU is the participant's VU ID, within its instance.
+	VU uint64 `json:"vu" js:"vu"`
+
+	// Metadata holds the metadata the participant registered with, if any.
+	Metadata any `json:"metadata,omitempty" js:"metadata"`
+
+	// RegisteredAt is the time the participant registered at, in
+	// milliseconds since the Unix epoch.
+	RegisteredAt int64 `json:"registeredAt" js:"registeredAt"`
+
+	// LastSeen is the time of the participant's last heartbeat, in
+	// milliseconds since the Unix epoch.
+	LastSeen int64 `json:"lastSeen" js:"lastSeen"`
+
+	// ExpiresAt is the time after which the participant is no longer
+	// considered live, in milliseconds since the Unix epoch.
+	ExpiresAt int64 `json:"expiresAt" js:"expiresAt"`
+}
+
+// RegisterOptions are the options that can be passed to KV.Register().
+type RegisterOptions struct {
+	// TTL is how long the participant is considered live for after each
+	// heartbeat. Heartbeats are sent every half TTL until the VU stops.
+	TTL time.Duration `json:"ttl"`
+
+	// Metadata is any JSON-serializable value describing the participant.
+	Metadata any `json:"metadata"`
+}
+
+// heartbeat holds the state of a KV instance's roster registration.
+type heartbeat struct {
+	lock sync.Mutex
+
+	// participant and ttl are those of the latest registration, which the
+	// heartbeats are sent for.
+	participant Participant
+	ttl         time.Duration
+
+	// ticker paces the heartbeats, and stop stops them. Both are nil when
+	// no heartbeats are sent.
+	ticker *time.Ticker
+	stop   chan struct{}
+}
+
+// Register records the calling VU in the roster of participants shared by
+// every VU and instance using the store, and resolves with its ID.
+//
+// The registration is kept alive by heartbeats sent in the background until
+// the VU stops; a participant which misses its heartbeats for longer than
+// its TTL is no longer listed by KV.Roster. Registering again updates the
+// participant's metadata and TTL.
+func (k *KV) Register(options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	state := k.vu.State()
+	if state == nil {
+		reject(errors.New("register can only be called from a VU, outside of the init context"))
+		return promise
+	}
+
+	registerOptions, err := ImportRegisterOptions(k.vu.Runtime(), options)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	instance := instanceID()
+	participant := Participant{
+		ID:       instance + "/" + strconv.FormatUint(state.VUID, 10),
+		Instance: instance,
+		VU:       state.VUID,
+		Metadata: registerOptions.Metadata,
+	}
+	ctx := k.vu.Context()
+
+	go func() {
+		if err := k.beat(participant, registerOptions.TTL); err != nil {
+			reject(err)
+			return
+		}
+
+		k.heartbeat.lock.Lock()
+		defer k.heartbeat.lock.Unlock()
+
+		k.heartbeat.participant = participant
+		k.heartbeat.ttl = registerOptions.TTL
+
+		if k.heartbeat.stop != nil {
+			k.heartbeat.ticker.Reset(registerOptions.TTL / 2)
+		} else {
+			k.heartbeat.ticker = time.NewTicker(registerOptions.TTL / 2)
+			k.heartbeat.stop = make(chan struct{})
+
+			go k.sendHeartbeats(ctx, k.heartbeat.ticker, k.heartbeat.stop)
+		}
+
+		resolve(participant.ID)
+	}()
+
+	return promise
+}
+
+// sendHeartbeats records a heartbeat of the latest registration on every
+// tick, until the VU stops or stop is closed.
+func (k *KV) sendHeartbeats(ctx context.Context, ticker *time.Ticker, stop chan struct{}) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			k.heartbeat.lock.Lock()
+			if k.heartbeat.stop == stop {
+				k.heartbeat.ticker = nil
+				k.heartbeat.stop = nil
+			}
+			k.heartbeat.lock.Unlock()
+
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+			// Hold the lock while beating, so that a heartbeat never
+			// registers the participant again once it deregistered.
+			k.heartbeat.lock.Lock()
+			if k.heartbeat.stop != stop {
+				k.heartbeat.lock.Unlock()
+				return
+			}
+
+			_ = k.beat(k.heartbeat.participant, k.heartbeat.ttl)
+			k.heartbeat.lock.Unlock()
+		}
+	}
+}
+
+// Deregister removes the calling VU from the roster of participants, and
+// stops sending its heartbeats.
+func (k *KV) Deregister() *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	state := k.vu.State()
+	if state == nil {
+		reject(errors.New("deregister can only be called from a VU, outside of the init context"))
+		return promise
+	}
+
+	id := []byte(instanceID() + "/" + strconv.FormatUint(state.VUID, 10))
+
+	go func() {
+		k.heartbeat.lock.Lock()
+		if k.heartbeat.stop != nil {
+			close(k.heartbeat.stop)
+			k.heartbeat.ticker = nil
+			k.heartbeat.stop = nil
+		}
+		k.heartbeat.lock.Unlock()
+
+		err := k.mutate(mutation{op: "deregister", key: id, internal: true}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(RosterBucket))
+			if bucket == nil {
+				return nil
+			}
+
+			return bucket.Delete(id)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(true)
+	}()
+
+	return promise
+}
+
+// Roster resolves with the live participants registered through KV.Register,
+// ordered by ID.
+func (k *KV) Roster() *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	go func() {
+		participants := make([]Participant, 0)
+		now := time.Now().UnixMilli()
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(RosterBucket))
+			if bucket == nil {
+				return nil
+			}
+
+			return bucket.ForEach(func(_, v []byte) error {
+				var participant Participant
+				if err := json.Unmarshal(v, &participant); err != nil {
+					return err
+				}
+
+				if participant.ExpiresAt > now {
+					participants = append(participants, participant)
+				}
+
+				return nil
+			})
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(participants)
+	}()
+
+	return promise
+}
+
+// beat records a heartbeat of the participant in the roster.
+func (k *KV) beat(participant Participant, ttl time.Duration) error {
+	id := []byte(participant.ID)
+
+	return k.mutate(mutation{op: "register", key: id, internal: true}, func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(RosterBucket))
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		participant.RegisteredAt = now.UnixMilli()
+
+		if raw := bucket.Get(id); raw != nil {
+			var registered Participant
+			if err := json.Unmarshal(raw, &registered); err != nil {
+				return err
+			}
+
+			// Keep the registration time of participants which are still live.
+			if registered.ExpiresAt > now.UnixMilli() {
+				participant.RegisteredAt = registered.RegisteredAt
+			}
+		}
+
+		participant.LastSeen = now.UnixMilli()
+		participant.ExpiresAt = now.Add(ttl).UnixMilli()
+
+		raw, err := json.Marshal(participant)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(id, raw)
+	})
+}
+
+// ImportRegisterOptions instantiates a RegisterOptions from a sobek.Value.
+func ImportRegisterOptions(rt *sobek.Runtime, options sobek.Value) (RegisterOptions, error) {
+	registerOptions := RegisterOptions{TTL: DefaultHeartbeatTTL}
+
+	// If no options are passed, return the default options
+	if common.IsNullish(options) {
+		return registerOptions, nil
+	}
+
+	// Interpret the options as an object
+	optionsObj := options.ToObject(rt)
+
+	if ttlValue := optionsObj.Get("ttl"); !common.IsNullish(ttlValue) {
+		ttl, err := toDuration(ttlValue)
+		if err != nil {
+			return registerOptions, fmt.Errorf("invalid ttl: %w", err)
+		}
+
+		if ttl <= 0 {
+			return registerOptions, fmt.Errorf("ttl must be positive, got %s", ttl)
+		}
+
+		registerOptions.TTL = ttl
+	}
+
+	if metadata := optionsObj.Get("metadata"); !common.IsNullish(metadata) {
+		registerOptions.Metadata = metadata.Export()
+	}
+
+	return registerOptions, nil
+}
+
+// instanceID returns an identifier of the current k6 instance,
+// made of its host name and process ID.
+//
+//nolint:forbidigo
+func instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return hostname + "-" + strconv.Itoa(os.Getpid())
+}
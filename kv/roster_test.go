@@ -0,0 +1,162 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVRoster(t *testing.T) {
+	t.Parallel()
+
+	t.Run("registered VUs are listed until they deregister", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+		vu.enterVUContext(3)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.register({ metadata: { role: "producer" } })
+				.then((id) => Promise.all([id, store.roster()]))
+				.then(([id, participants]) => {
+					if (participants.length !== 1) {
+						throw new Error("expected a single participant, got " + JSON.stringify(participants));
+					}
+
+					const [participant] = participants;
+					if (participant.id !== id || participant.vu !== 3 || participant.metadata.role !== "producer") {
+						throw new Error("expected the registered participant, got " + JSON.stringify(participant));
+					}
+
+					if (participant.expiresAt <= participant.lastSeen) {
+						throw new Error("expected the participant to expire after its last heartbeat");
+					}
+
+					return store.deregister();
+				})
+				.then(() => store.roster())
+				.then((participants) => {
+					if (participants.length !== 0) {
+						throw new Error("expected the participant to be removed, got " + JSON.stringify(participants));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("deregistered VUs stay out of the roster", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+		vu.enterVUContext(1)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.register({ ttl: 50 })
+				.then(() => store.deregister())
+				.then(() => {
+					// Let a heartbeat interval pass.
+					const until = Date.now() + 100;
+					while (Date.now() < until) {}
+
+					return store.roster();
+				})
+				.then((participants) => {
+					if (participants.length !== 0) {
+						throw new Error("expected no heartbeats after deregistering, got " + JSON.stringify(participants));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("registering again updates the metadata and TTL of heartbeats", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+		vu.enterVUContext(1)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.register({ ttl: 50, metadata: { role: "producer" } })
+				.then(() => store.register({ ttl: 60000, metadata: { role: "consumer" } }))
+				.then(() => {
+					// Let a heartbeat interval of the first registration pass.
+					const until = Date.now() + 100;
+					while (Date.now() < until) {}
+
+					return store.roster();
+				})
+				.then((participants) => {
+					if (participants.length !== 1) {
+						throw new Error("expected a single participant, got " + JSON.stringify(participants));
+					}
+
+					const [participant] = participants;
+					if (participant.metadata.role !== "consumer" || participant.expiresAt - participant.lastSeen !== 60000) {
+						throw new Error("expected the latest registration, got " + JSON.stringify(participant));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("participants expire once their VU stops sending heartbeats", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+		vu.enterVUContext(1)
+
+		require.NoError(t, vu.run(`
+			const store = kv.openKv();
+
+			store.register({ ttl: 50 })
+				.then(() => {
+					// Heartbeats keep the participant live past its TTL.
+					const until = Date.now() + 100;
+					while (Date.now() < until) {}
+
+					return store.roster();
+				})
+				.then((participants) => {
+					if (participants.length !== 1) {
+						throw new Error("expected the participant to be kept live, got " + JSON.stringify(participants));
+					}
+				});
+		`))
+
+		vu.cancel()
+		time.Sleep(100 * time.Millisecond)
+
+		require.NoError(t, vu.run(`
+			store.roster().then((participants) => {
+				if (participants.length !== 0) {
+					throw new Error("expected the participant to expire, got " + JSON.stringify(participants));
+				}
+			});
+		`))
+	})
+
+	t.Run("registering is rejected in the init context", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			kv.openKv().register().then(
+				() => { throw new Error("expected register to reject"); },
+				(err) => {
+					if (!String(err).includes("outside of the init context")) {
+						throw err;
+					}
+				},
+			);
+		`)
+		require.NoError(t, err)
+	})
+}
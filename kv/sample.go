@@ -0,0 +1,144 @@
+package kv
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// RandomKey resolves with a random key of the store starting with the prefix
+// option, or any key, or null if there is none. The key is picked by seeking
+// to a random position, without reading the other keys or any value, or
+// among the indexed keys when the store is opened with the keyIndex option.
+func (k *KV) RandomKey(options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	prefix := k.prefixOption(options)
+
+	go func() {
+		var picked any
+
+		err := k.viewKeys(string(prefix), func(keys []string) error {
+			if len(keys) > 0 {
+				//nolint:gosec // Picking keys does not need a cryptographically secure source.
+				picked = keys[rand.Intn(len(keys))]
+			}
+
+			return nil
+		}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			if key := randomKey(bucket, newVisibility(tx, k.bucket), prefix); key != nil {
+				picked = string(key)
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(picked)
+	}()
+
+	return promise
+}
+
+// Sample resolves with n distinct keys of the store starting with the prefix
+// option, or any keys, picked uniformly at random, in no particular order.
+// Fewer keys are returned if there are not as many.
+//
+// The keys are sampled with a single pass of a cursor over the matching keys,
+// without reading any value.
+func (k *KV) Sample(n sobek.Value, options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	size := n.ToInteger()
+	if size < 0 {
+		reject(fmt.Errorf("the number of keys to sample must not be negative, got %d", size))
+		return promise
+	}
+
+	prefix := k.prefixOption(options)
+
+	go func() {
+		var sampled []string
+
+		err := k.viewKeys(string(prefix), func(keys []string) error {
+			sampled = sampleIndexed(keys, int(size))
+			return nil
+		}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			sampled = sampleKeys(bucket, newVisibility(tx, k.bucket), prefix, int(size))
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(sampled)
+	}()
+
+	return promise
+}
+
+// prefixOption returns the prefix option of the options, if set.
+func (k *KV) prefixOption(options sobek.Value) []byte {
+	if common.IsNullish(options) {
+		return nil
+	}
+
+	prefix := options.ToObject(k.vu.Runtime()).Get("prefix")
+	if common.IsNullish(prefix) {
+		return nil
+	}
+
+	return []byte(prefix.String())
+}
+
+// sampleKeys returns up to n visible keys of the bucket starting with the
+// prefix, picked uniformly at random by reservoir sampling.
+func sampleKeys(bucket *bolt.Bucket, visible visibility, prefix []byte, n int) []string {
+	sampled := make([]string, 0, n)
+	if n == 0 {
+		return sampled
+	}
+
+	seen := 0
+
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		if visible.hidden(key) {
+			continue
+		}
+
+		seen++
+
+		if len(sampled) < n {
+			sampled = append(sampled, string(key))
+			continue
+		}
+
+		//nolint:gosec // Sampling keys does not need a cryptographically secure source.
+		if i := rand.Intn(seen); i < n {
+			sampled[i] = string(key)
+		}
+	}
+
+	return sampled
+}
@@ -0,0 +1,46 @@
+package kv
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestSampleKeys(t *testing.T) {
+	t.Parallel()
+
+	dbInstance := openTestDB(t, Options{})
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(DefaultKvBucket))
+		for i := 0; i < 100; i++ {
+			if err := bucket.Put([]byte("user:"+strconv.Itoa(i)), []byte(`1`)); err != nil {
+				return err
+			}
+		}
+
+		return bucket.Put([]byte("order:1"), []byte(`1`))
+	}))
+
+	assert.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(DefaultKvBucket))
+		visible := newVisibility(tx, []byte(DefaultKvBucket))
+
+		sampled := sampleKeys(bucket, visible, []byte("user:"), 10)
+		assert.Len(t, sampled, 10)
+
+		distinct := make(map[string]bool)
+		for _, key := range sampled {
+			assert.Regexp(t, `^user:\d+$`, key)
+			distinct[key] = true
+		}
+		assert.Len(t, distinct, 10)
+
+		assert.Equal(t, []string{"order:1"}, sampleKeys(bucket, visible, []byte("order:"), 10))
+		assert.Empty(t, sampleKeys(bucket, visible, []byte("user:"), 0))
+
+		return nil
+	}))
+}
@@ -0,0 +1,203 @@
+package kv
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+	"go.k6.io/k6/lib"
+)
+
+const (
+	// ScopeScenario scopes keys to the scenario the VU runs.
+	ScopeScenario = "scenario"
+
+	// ScopeVU scopes keys to the VU.
+	ScopeVU = "vu"
+
+	// ScopeIteration scopes keys to the VU's current iteration. They are
+	// deleted at the end of every iteration.
+	ScopeIteration = "iteration"
+)
+
+// ScopedKV is a view of the keys of a KV store starting with a prefix. Its
+// methods apply the prefix to the keys they are passed, and remove it from
+// the keys they return, so that the view behaves like a store of its own.
+type ScopedKV struct {
+	// prefix is the prefix of the keys of the view.
+	prefix string
+
+	// kv is the KV instance the view's keys are stored in.
+	kv *KV
+}
+
+// Scoped returns a view of the store whose keys are prefixed with the current
+// scenario's name, with the VU's ID, or with the VU's ID for the iteration
+// scope, whose keys are deleted at the end of every iteration.
+//
+// It must be called from the VU context, as the scope is resolved when it is.
+func (k *KV) Scoped(scope sobek.Value) *sobek.Object {
+	rt := k.vu.Runtime()
+
+	state := k.vu.State()
+	if state == nil {
+		common.Throw(rt, fmt.Errorf("scoped views can only be created in the VU context"))
+		return nil
+	}
+
+	vuID := strconv.FormatUint(state.VUIDGlobal, 10)
+
+	var prefix string
+	switch scope.String() {
+	case ScopeScenario:
+		scenario := lib.GetScenarioState(k.vu.Context())
+		if scenario == nil {
+			common.Throw(rt, fmt.Errorf("the %s scope is only available while running a scenario", ScopeScenario))
+			return nil
+		}
+
+		prefix = "scenario:" + scenario.Name + ":"
+	case ScopeVU:
+		prefix = "vu:" + vuID + ":"
+	case ScopeIteration:
+		prefix = "iteration:" + vuID + ":"
+		k.clearEachIteration.Do(func() {
+			k.clearOnIterationEnd([]byte(prefix))
+		})
+	default:
+		common.Throw(rt, fmt.Errorf(
+			"scope must be one of %q, %q or %q, got %q", ScopeScenario, ScopeVU, ScopeIteration, scope.String(),
+		))
+		return nil
+	}
+
+	return rt.ToValue(&ScopedKV{prefix: prefix, kv: k}).ToObject(rt)
+}
+
+// WithPrefix returns a view of the store whose keys are prefixed with the
+// given prefix, so that sub-namespaces can be handed around without
+// concatenating the prefix to every key.
+func (k *KV) WithPrefix(prefix sobek.Value) *sobek.Object {
+	return k.withPrefix("", prefix)
+}
+
+// withPrefix returns a view of the keys starting with the base prefix
+// followed by the given prefix.
+func (k *KV) withPrefix(base string, prefix sobek.Value) *sobek.Object {
+	rt := k.vu.Runtime()
+
+	if common.IsNullish(prefix) || prefix.String() == "" {
+		common.Throw(rt, NewError(KeyRequiredError, "prefix must not be empty"))
+		return nil
+	}
+
+	return rt.ToValue(&ScopedKV{prefix: base + prefix.String(), kv: k}).ToObject(rt)
+}
+
+// clearOnIterationEnd deletes the keys starting with the prefix at the end
+// of every iteration of the VU, before the next one starts.
+func (k *KV) clearOnIterationEnd(prefix []byte) {
+	events := k.vu.Events().Local
+	subID, eventsCh := events.Subscribe(event.IterEnd, event.Exit)
+
+	go func() {
+		for e := range eventsCh {
+			if e.Type == event.IterEnd {
+				_ = k.mutate(mutation{op: "clear"}, func(tx *bolt.Tx) error {
+					_, err := k.deletePrefix(tx, prefix)
+					return err
+				})
+			}
+
+			e.Done()
+
+			if e.Type == event.Exit {
+				events.Unsubscribe(subID)
+				return
+			}
+		}
+	}()
+}
+
+// WithPrefix returns a view of the keys of the view starting with the
+// given prefix.
+func (s *ScopedKV) WithPrefix(prefix sobek.Value) *sobek.Object {
+	return s.kv.withPrefix(s.prefix, prefix)
+}
+
+// key returns the key of the store the view's key stands for. Null and
+// undefined keys are rejected, rather than standing for the "null" and
+// "undefined" keys of the view.
+func (s *ScopedKV) key(key sobek.Value) (sobek.Value, error) {
+	if common.IsNullish(key) {
+		return nil, NewError(KeyRequiredError, "key must not be null or undefined")
+	}
+
+	return s.kv.vu.Runtime().ToValue(s.prefix + key.String()), nil
+}
+
+// reject returns a promise rejected with err.
+func (s *ScopedKV) reject(err error) *sobek.Promise {
+	promise, _, reject := promises.New(s.kv.vu)
+	reject(err)
+
+	return promise
+}
+
+// Set sets the value of a key of the view, as KV.Set does.
+func (s *ScopedKV) Set(key sobek.Value, value sobek.Value, options sobek.Value) *sobek.Promise {
+	scopedKey, err := s.key(key)
+	if err != nil {
+		return s.reject(err)
+	}
+
+	return s.kv.Set(scopedKey, value, options)
+}
+
+// Get returns the value of a key of the view, as KV.Get does.
+func (s *ScopedKV) Get(key sobek.Value) *sobek.Promise {
+	scopedKey, err := s.key(key)
+	if err != nil {
+		return s.reject(err)
+	}
+
+	return s.kv.Get(scopedKey)
+}
+
+// Delete deletes a key of the view, as KV.Delete does.
+func (s *ScopedKV) Delete(key sobek.Value) *sobek.Promise {
+	scopedKey, err := s.key(key)
+	if err != nil {
+		return s.reject(err)
+	}
+
+	return s.kv.Delete(scopedKey)
+}
+
+// List returns the key-value pairs of the view, as KV.List does, without
+// the view's prefix.
+func (s *ScopedKV) List(options sobek.Value) *sobek.Promise {
+	listOptions, err := ImportListOptions(s.kv.vu.Runtime(), options)
+	if err != nil {
+		return s.reject(err)
+	}
+
+	listOptions.Prefix = s.prefix + listOptions.Prefix
+	listOptions.scope = s.prefix
+
+	return s.kv.list(listOptions)
+}
+
+// Size returns the number of keys of the view.
+func (s *ScopedKV) Size() *sobek.Promise {
+	return s.kv.count([]byte(s.prefix))
+}
+
+// Clear deletes all the keys of the view.
+func (s *ScopedKV) Clear() *sobek.Promise {
+	return s.kv.clearPrefix([]byte(s.prefix))
+}
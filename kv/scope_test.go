@@ -0,0 +1,150 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.k6.io/k6/event"
+)
+
+func TestKVScoped(t *testing.T) {
+	t.Parallel()
+
+	t.Run("scoped views are isolated from each other", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+		vu.enterVUContext(1)
+
+		err := vu.run(`
+			const store = kv.openKv();
+			const users = store.withPrefix("users:");
+			const orders = store.withPrefix("orders:");
+
+			Promise.all([users.set("1", "alice"), orders.set("1", "book"), store.scoped("vu").set("1", "mine")])
+				.then(() => Promise.all([users.get("1"), orders.get("1"), users.list(), users.size()]))
+				.then(([user, order, entries, size]) => {
+					if (user !== "alice" || order !== "book") {
+						throw new Error("expected each view to hold its own value, got " + user + " and " + order);
+					}
+
+					if (size !== 1 || entries.length !== 1 || entries[0].key !== "1") {
+						throw new Error("expected the view's keys without their prefix, got " + JSON.stringify(entries));
+					}
+
+					return store.get("vu:1:1");
+				})
+				.then((value) => {
+					if (value !== "mine") {
+						throw new Error("expected the VU scope to prefix keys with the VU's ID, got " + value);
+					}
+
+					return users.clear();
+				})
+				.then(() => store.size())
+				.then((size) => {
+					if (size !== 2) {
+						throw new Error("expected clearing a view to leave the other keys, got " + size);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("nested views apply both prefixes", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+			const carts = store.withPrefix("users:").withPrefix("carts:");
+
+			carts.set("1", ["book"])
+				.then(() => store.get("users:carts:1"))
+				.then((value) => {
+					if (value[0] !== "book") {
+						throw new Error("expected both prefixes to be applied, got " + JSON.stringify(value));
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("null and undefined keys and prefixes are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+			const users = store.withPrefix("users:");
+
+			const rejected = (promise) => promise.then(
+				() => { throw new Error("expected the key to be rejected"); },
+				(err) => {
+					if (String(err.name) !== "KeyRequiredError") {
+						throw err;
+					}
+				},
+			);
+
+			for (const prefix of [null, undefined, ""]) {
+				try {
+					users.withPrefix(prefix);
+					throw new Error("expected the prefix to be rejected");
+				} catch (err) {
+					if (!String(err).includes("KeyRequiredError")) {
+						throw err;
+					}
+				}
+			}
+
+			Promise.all([rejected(users.set(null, 1)), rejected(users.get(undefined)), rejected(users.delete(null))])
+				.then(() => store.size())
+				.then((size) => {
+					if (size !== 0) {
+						throw new Error("expected nothing to be written, got " + size + " keys");
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("iteration scoped keys are deleted at the end of every iteration", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+		vu.enterVUContext(1)
+
+		require.NoError(t, vu.run(`
+			const store = kv.openKv();
+			const iteration = store.scoped("iteration");
+
+			iteration.set("cart", ["book"]).then(() => store.set("kept", true));
+		`))
+
+		vu.emit(t, event.IterEnd)
+
+		require.NoError(t, vu.run(`
+			store.size().then((size) => {
+				if (size !== 1) {
+					throw new Error("expected the iteration's keys to be deleted, got " + size + " keys");
+				}
+
+				return iteration.set("cart", ["pen"]);
+			});
+		`))
+
+		vu.emit(t, event.Exit)
+		vu.emit(t, event.IterEnd)
+
+		require.NoError(t, vu.run(`
+			iteration.get("cart").then((value) => {
+				if (value[0] !== "pen") {
+					throw new Error("expected the keys to be kept once the VU exits, got " + JSON.stringify(value));
+				}
+			});
+		`))
+	})
+}
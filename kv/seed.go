@@ -0,0 +1,150 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// seedEntry is an entry of a seed file, in the array and NDJSON formats.
+type seedEntry struct {
+	Key   *string         `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// seed bulk-loads the entries of the options' seed file into the store,
+// within a single transaction, as if they were set with KV.SetMany.
+func (db *db) seed(options Options) error {
+	entries, err := readSeed(options.Seed)
+	if err != nil {
+		return err
+	}
+
+	bucketName := options.Bucket
+	if bucketName == "" {
+		bucketName = DefaultKvBucket
+	}
+
+	k := &KV{bucket: []byte(bucketName), db: db, options: options}
+
+	mutations := make([]mutation, 0, len(entries))
+	for _, entry := range entries {
+		if err := k.validateKey([]byte(*entry.Key)); err != nil {
+			return fmt.Errorf("invalid seed entry: %w", err)
+		}
+
+		var value bytes.Buffer
+		if err := json.Compact(&value, entry.Value); err != nil {
+			return fmt.Errorf("invalid seed value for key %q: %w", *entry.Key, err)
+		}
+
+		if err := k.validateValue([]byte(*entry.Key), value.Bytes()); err != nil {
+			return fmt.Errorf("invalid seed entry: %w", err)
+		}
+
+		mutations = append(mutations, mutation{op: "seed", key: []byte(*entry.Key), value: value.Bytes()})
+	}
+
+	return k.mutateMany(mutations, func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(k.bucket)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range mutations {
+			if err := undelay(tx, k.bucket, m.key); err != nil {
+				return err
+			}
+
+			if err := k.storeValue(tx, bucket, m.key, m.value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// readSeed reads the entries of a seed file.
+//
+// Files with the .ndjson or .jsonl extension hold an entry object, with
+// key and value properties, per line. Other files hold either an object
+// mapping keys to values, or an array of entry objects.
+func readSeed(path string) ([]seedEntry, error) {
+	data, err := os.ReadFile(path) //nolint:forbidigo
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file: %w", err)
+	}
+
+	var entries []seedEntry
+
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case ext == ".ndjson" || ext == ".jsonl":
+		entries, err = decodeSeedLines(data)
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")):
+		err = json.Unmarshal(data, &entries)
+	default:
+		entries, err = decodeSeedObject(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse seed file %s: %w", path, err)
+	}
+
+	for i, entry := range entries {
+		if entry.Key == nil {
+			return nil, fmt.Errorf("seed file %s: entry %d has no key", path, i)
+		}
+	}
+
+	return entries, nil
+}
+
+// decodeSeedLines decodes a seed file holding an entry object per line.
+func decodeSeedLines(data []byte) ([]seedEntry, error) {
+	var entries []seedEntry
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var entry seedEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+}
+
+// decodeSeedObject decodes a seed file holding an object mapping keys
+// to values. The entries are returned in key order.
+func decodeSeedObject(data []byte) ([]seedEntry, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]seedEntry, 0, len(keys))
+	for _, key := range keys {
+		key := key
+		entries = append(entries, seedEntry{Key: &key, Value: object[key]})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,78 @@
+package kv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+//nolint:forbidigo
+func TestDBSeed(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	seeds := map[string]string{
+		"users.json":   `{"user:2": {"name": "bob"}, "user:1": {"name": "alice"}}`,
+		"entries.json": `[{"key": "user:1", "value": {"name": "alice"}}, {"key": "user:2", "value": {"name": "bob"}}]`,
+		"users.ndjson": "{\"key\": \"user:1\", \"value\": {\"name\": \"alice\"}}\n" +
+			"{\"key\": \"user:2\", \"value\": {\"name\": \"bob\"}}\n",
+	}
+
+	for name, contents := range seeds {
+		name, contents := name, contents
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			seedPath := filepath.Join(tmpDir, name)
+			require.NoError(t, os.WriteFile(seedPath, []byte(contents), 0o600))
+
+			dbInstance := newDB()
+			dbInstance.path = filepath.Join(tmpDir, name+".db")
+			require.NoError(t, dbInstance.open(Options{Seed: seedPath}))
+			t.Cleanup(func() {
+				require.NoError(t, dbInstance.close())
+			})
+
+			assert.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+				bucket := tx.Bucket([]byte(DefaultKvBucket))
+				assert.Equal(t, []byte(`{"name":"alice"}`), bucket.Get([]byte("user:1")))
+				assert.Equal(t, []byte(`{"name":"bob"}`), bucket.Get([]byte("user:2")))
+				return nil
+			}))
+		})
+	}
+
+	t.Run("invalid seed files fail to open the store", func(t *testing.T) {
+		t.Parallel()
+
+		seedPath := filepath.Join(tmpDir, "invalid.json")
+		require.NoError(t, os.WriteFile(seedPath, []byte(`[{"value": 1}]`), 0o600))
+
+		dbInstance := newDB()
+		dbInstance.path = filepath.Join(tmpDir, "invalid.db")
+		assert.Error(t, dbInstance.open(Options{Seed: seedPath}))
+		assert.False(t, dbInstance.opened.Load())
+	})
+
+	t.Run("seed values larger than maxValueSize fail to open the store", func(t *testing.T) {
+		t.Parallel()
+
+		seedPath := filepath.Join(tmpDir, "large.json")
+		require.NoError(t, os.WriteFile(seedPath, []byte(`{"small": 1, "large": "a value longer than the maximum"}`), 0o600))
+
+		dbInstance := newDB()
+		dbInstance.path = filepath.Join(tmpDir, "large.db")
+		err := dbInstance.open(Options{Seed: seedPath, MaxValueSize: 16})
+
+		var kvErr *Error
+		require.ErrorAs(t, err, &kvErr)
+		assert.Equal(t, ErrorName(ValueTooLargeError), kvErr.Name)
+		assert.False(t, dbInstance.opened.Load())
+	})
+}
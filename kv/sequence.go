@@ -0,0 +1,63 @@
+package kv
+
+import (
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// SequencesBucket is the name of the internal bucket holding the sequences
+// used through KV.NextSequence, each in its own nested bucket, whose
+// sequence number is the sequence's last value.
+const SequencesBucket = "k6/sequences"
+
+// NextSequence resolves with the next value of the named sequence, shared by
+// all VUs, starting at 1.
+//
+// Values are strictly increasing, and each is handed out exactly once, even
+// when several VUs call it concurrently, and in dry-run mode as well.
+func (k *KV) NextSequence(name sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(name) || name.String() == "" {
+		reject(NewError(KeyRequiredError, "sequence name is required"))
+		return promise
+	}
+
+	nameBytes := []byte(name.String())
+
+	go func() {
+		var value uint64
+
+		err := k.coordinate(func(tx *bolt.Tx) error {
+			var err error
+			value, err = nextSequence(tx, nameBytes)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(value)
+	}()
+
+	return promise
+}
+
+// nextSequence increments the named sequence, and returns its new value.
+func nextSequence(tx *bolt.Tx, name []byte) (uint64, error) {
+	root, err := tx.CreateBucketIfNotExists([]byte(SequencesBucket))
+	if err != nil {
+		return 0, err
+	}
+
+	bucket, err := root.CreateBucketIfNotExists(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return bucket.NextSequence()
+}
@@ -0,0 +1,79 @@
+package kv
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestNextSequence(t *testing.T) {
+	t.Parallel()
+
+	dbInstance := openTestDB(t, Options{})
+	const callers = 50
+
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		values = make(map[uint64]bool)
+	)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+				value, err := nextSequence(tx, []byte("orders"))
+
+				lock.Lock()
+				values[value] = true
+				lock.Unlock()
+
+				return err
+			}))
+		}()
+	}
+	wg.Wait()
+
+	// Each value is handed out exactly once
+	assert.Len(t, values, callers)
+	for i := uint64(1); i <= callers; i++ {
+		assert.True(t, values[i])
+	}
+
+	// Sequences are independent
+	assert.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		value, err := nextSequence(tx, []byte("users"))
+		assert.Equal(t, uint64(1), value)
+
+		return err
+	}))
+}
+
+func TestKVNextSequenceDryRun(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	err := vu.run(`
+		const store = kv.openKv({ dryRun: true });
+
+		store.nextSequence("orders")
+			.then(() => store.nextSequence("orders"))
+			.then((value) => {
+				if (value !== 2) {
+					throw new Error("expected values to be handed out in dry-run mode, got " + value);
+				}
+
+				const report = store.dryRunReport();
+				if (report.length !== 0) {
+					throw new Error("expected no mutation to be reported, got " + JSON.stringify(report));
+				}
+			});
+	`)
+	require.NoError(t, err)
+}
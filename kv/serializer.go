@@ -0,0 +1,103 @@
+package kv
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Serializer encodes values to the bytes they are stored as, and back.
+//
+// Serializers are registered with RegisterSerializer, typically by other
+// extensions compiled into the same k6 binary, and selected by name with
+// the serialization option.
+type Serializer interface {
+	// Marshal encodes a value, as exported from the JS runtime.
+	Marshal(value any) ([]byte, error)
+
+	// Unmarshal decodes a value encoded by Marshal, to a value which
+	// can be converted to a JS value. The data is only valid until it
+	// returns, and must be copied to be retained.
+	Unmarshal(data []byte) (any, error)
+}
+
+// serializedMarker prefixes the stored form of values encoded by a
+// registered Serializer, followed by the length of its name, on one byte,
+// its name, and the encoded value. No JSON value starts with it.
+//
+// Naming the serializer within each value lets values be read back whatever
+// the serialization option the store is opened with.
+const serializedMarker = 0x02
+
+//nolint:gochecknoglobals
+var serializers = struct {
+	sync.RWMutex
+	byName map[string]Serializer
+}{byName: make(map[string]Serializer)}
+
+// RegisterSerializer registers a Serializer under the given name, so that
+// scripts can select it with the serialization option.
+//
+// It is meant to be called from an init function, and panics if the name
+// is already in use, or longer than 255 bytes.
+func RegisterSerializer(name string, s Serializer) {
+	serializers.Lock()
+	defer serializers.Unlock()
+
+	if name == SerializationJSON || name == SerializationBinary {
+		panic(fmt.Sprintf("kv: serializer name %q is reserved", name))
+	}
+
+	if name == "" || len(name) > 255 {
+		panic(fmt.Sprintf("kv: serializer name %q must be between 1 and 255 bytes long", name))
+	}
+
+	if _, dup := serializers.byName[name]; dup {
+		panic(fmt.Sprintf("kv: serializer %q is already registered", name))
+	}
+
+	serializers.byName[name] = s
+}
+
+// lookupSerializer returns the Serializer registered under the given name.
+func lookupSerializer(name string) (Serializer, bool) {
+	serializers.RLock()
+	defer serializers.RUnlock()
+
+	s, found := serializers.byName[name]
+
+	return s, found
+}
+
+// isSerialized reports whether a stored value was encoded by a registered
+// Serializer.
+func isSerialized(value []byte) bool {
+	return len(value) > 1 && value[0] == serializedMarker
+}
+
+// encodeSerialized returns the stored form of a value encoded by the named
+// Serializer.
+func encodeSerialized(name string, data []byte) []byte {
+	encoded := make([]byte, 0, 2+len(name)+len(data))
+	encoded = append(encoded, serializedMarker, byte(len(name)))
+	encoded = append(encoded, name...)
+
+	return append(encoded, data...)
+}
+
+// decodeSerialized decodes a value encoded by a registered Serializer, with
+// the Serializer it names.
+func decodeSerialized(value []byte) (any, error) {
+	nameLen := int(value[1])
+	if len(value) < 2+nameLen {
+		return nil, fmt.Errorf("malformed serialized value")
+	}
+
+	name := string(value[2 : 2+nameLen])
+
+	s, found := lookupSerializer(name)
+	if !found {
+		return nil, fmt.Errorf("the value was encoded by the %q serializer, which is not registered", name)
+	}
+
+	return s.Unmarshal(value[2+nameLen:])
+}
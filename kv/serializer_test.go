@@ -0,0 +1,63 @@
+package kv
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseSerializer stores strings reversed.
+type reverseSerializer struct{}
+
+func (reverseSerializer) Marshal(value any) ([]byte, error) {
+	s, isString := value.(string)
+	if !isString {
+		return nil, fmt.Errorf("expected a string, got %T", value)
+	}
+
+	reversed := []byte(s)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+
+	return reversed, nil
+}
+
+func (r reverseSerializer) Unmarshal(data []byte) (any, error) {
+	reversed, err := r.Marshal(string(data))
+	return string(reversed), err
+}
+
+// Serializers can only be registered once, so register the test's when the
+// package is, rather than on every run of the test.
+func init() {
+	RegisterSerializer("test-reverse", reverseSerializer{})
+}
+
+func TestRegisterSerializer(t *testing.T) {
+	t.Parallel()
+
+	s, found := lookupSerializer("test-reverse")
+	require.True(t, found)
+
+	data, err := s.Marshal("hello")
+	require.NoError(t, err)
+
+	stored := encodeSerialized("test-reverse", data)
+	assert.True(t, isSerialized(stored))
+	assert.False(t, isBinary(stored))
+	assert.True(t, bytes.HasSuffix(stored, []byte("olleh")))
+
+	var value any
+	require.NoError(t, decodeValue(stored, &value))
+	assert.Equal(t, "hello", value)
+
+	assert.Error(t, decodeValue(encodeSerialized("test-unknown", data), &value))
+
+	assert.Panics(t, func() { RegisterSerializer("test-reverse", reverseSerializer{}) })
+	assert.Panics(t, func() { RegisterSerializer(SerializationJSON, reverseSerializer{}) })
+	assert.Panics(t, func() { RegisterSerializer("", reverseSerializer{}) })
+}
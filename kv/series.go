@@ -0,0 +1,270 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// SeriesBucket is the name of the internal bucket holding the time series
+// recorded through KV.RecordSample, each in its own nested bucket.
+const SeriesBucket = "k6/series"
+
+// SeriesSample is a sample of a time series, as returned by KV.QuerySamples().
+type SeriesSample struct {
+	// Time is the time the sample was recorded at, in milliseconds since the Unix epoch.
+	Time int64 `json:"time"`
+
+	// Value is the sample's value.
+	Value float64 `json:"value"`
+}
+
+// RecordSample appends a sample with the given numeric value, timestamped
+// with the current time, to the named time series.
+func (k *KV) RecordSample(series sobek.Value, value sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(series) || series.String() == "" {
+		reject(NewError(KeyRequiredError, "series name is required"))
+		return promise
+	}
+
+	if common.IsNullish(value) {
+		reject(fmt.Errorf("sample value must be a number, got %v", value))
+		return promise
+	}
+
+	sampleValue := value.ToFloat()
+	if math.IsNaN(sampleValue) {
+		reject(fmt.Errorf("sample value must be a number, got %v", value))
+		return promise
+	}
+
+	name := []byte(series.String())
+	jsonValue, err := json.Marshal(sampleValue)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		now := time.Now()
+
+		err := k.mutate(mutation{op: "recordSample", key: name, value: jsonValue, internal: true}, func(tx *bolt.Tx) error {
+			root, err := tx.CreateBucketIfNotExists([]byte(SeriesBucket))
+			if err != nil {
+				return err
+			}
+
+			bucket, err := root.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+
+			// Suffix the timestamp with a sequence number, so that samples
+			// recorded within the same nanosecond do not overwrite each other.
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			key := make([]byte, 16)
+			binary.BigEndian.PutUint64(key, uint64(now.UnixNano()))
+			binary.BigEndian.PutUint64(key[8:], seq)
+
+			return bucket.Put(key, jsonValue)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(sampleValue)
+	}()
+
+	return promise
+}
+
+// QuerySamples resolves with the samples of the named time series, ordered by time.
+//
+// See [QuerySamplesOptions] for how to select a time range, and downsample the results.
+func (k *KV) QuerySamples(series sobek.Value, options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	if common.IsNullish(series) || series.String() == "" {
+		reject(NewError(KeyRequiredError, "series name is required"))
+		return promise
+	}
+
+	queryOptions, err := ImportQuerySamplesOptions(k.vu.Runtime(), options)
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	name := []byte(series.String())
+
+	go func() {
+		samples := make([]SeriesSample, 0)
+
+		err := k.view(func(tx *bolt.Tx) error {
+			root := tx.Bucket([]byte(SeriesBucket))
+			if root == nil {
+				return nil
+			}
+
+			bucket := root.Bucket(name)
+			if bucket == nil {
+				return nil
+			}
+
+			from := make([]byte, 8)
+			binary.BigEndian.PutUint64(from, uint64(queryOptions.From.UnixNano()))
+
+			to := make([]byte, 8)
+			binary.BigEndian.PutUint64(to, uint64(queryOptions.To.UnixNano()))
+
+			cursor := bucket.Cursor()
+			for key, value := cursor.Seek(from); key != nil; key, value = cursor.Next() {
+				if bytes.Compare(key[:8], to) > 0 {
+					break
+				}
+
+				var sampleValue float64
+				if err := json.Unmarshal(value, &sampleValue); err != nil {
+					return err
+				}
+
+				timestamp := int64(binary.BigEndian.Uint64(key[:8]))
+				samples = append(samples, SeriesSample{
+					Time:  time.Unix(0, timestamp).UnixMilli(),
+					Value: sampleValue,
+				})
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		if queryOptions.Downsample > 0 {
+			samples = downsample(samples, queryOptions.Downsample)
+		}
+
+		resolve(samples)
+	}()
+
+	return promise
+}
+
+// downsample averages the samples falling within each interval, and returns
+// one sample per non-empty interval, timestamped with the interval's start.
+//
+// The samples are expected to be ordered by time.
+func downsample(samples []SeriesSample, interval time.Duration) []SeriesSample {
+	step := interval.Milliseconds()
+	if step <= 0 {
+		return samples
+	}
+
+	downsampled := make([]SeriesSample, 0)
+
+	var sum, count float64
+	for i, sample := range samples {
+		start := sample.Time - sample.Time%step
+
+		sum += sample.Value
+		count++
+
+		// Flush the interval when the next sample falls outside of it.
+		if i == len(samples)-1 || samples[i+1].Time-samples[i+1].Time%step != start {
+			downsampled = append(downsampled, SeriesSample{Time: start, Value: sum / count})
+			sum, count = 0, 0
+		}
+	}
+
+	return downsampled
+}
+
+// QuerySamplesOptions are the options that can be passed to KV.QuerySamples().
+type QuerySamplesOptions struct {
+	// From selects the samples recorded at or after the given time,
+	// given in milliseconds since the Unix epoch, or as a Date.
+	From time.Time `json:"from"`
+
+	// To selects the samples recorded at or before the given time,
+	// given in milliseconds since the Unix epoch, or as a Date.
+	To time.Time `json:"to"`
+
+	// Downsample averages the samples over intervals of the given
+	// duration, returning one sample per interval.
+	Downsample time.Duration `json:"downsample"`
+}
+
+// ImportQuerySamplesOptions instantiates a QuerySamplesOptions from a sobek.Value.
+func ImportQuerySamplesOptions(rt *sobek.Runtime, options sobek.Value) (QuerySamplesOptions, error) {
+	queryOptions := QuerySamplesOptions{
+		From: time.Unix(0, 0),
+		To:   time.Unix(0, math.MaxInt64),
+	}
+
+	// If no options are passed, return the default options
+	if common.IsNullish(options) {
+		return queryOptions, nil
+	}
+
+	// Interpret the options as an object
+	optionsObj := options.ToObject(rt)
+
+	if from := optionsObj.Get("from"); !common.IsNullish(from) {
+		fromTime, err := toTime(from)
+		if err != nil {
+			return queryOptions, fmt.Errorf("invalid from: %w", err)
+		}
+
+		queryOptions.From = fromTime
+	}
+
+	if to := optionsObj.Get("to"); !common.IsNullish(to) {
+		until, err := toTime(to)
+		if err != nil {
+			return queryOptions, fmt.Errorf("invalid to: %w", err)
+		}
+
+		queryOptions.To = until
+	}
+
+	downsampleInterval, err := toDuration(optionsObj.Get("downsample"))
+	if err != nil {
+		return queryOptions, fmt.Errorf("invalid downsample: %w", err)
+	}
+
+	queryOptions.Downsample = downsampleInterval
+
+	return queryOptions, nil
+}
+
+// toTime converts a JS value, either a Date or a number of milliseconds
+// since the Unix epoch, to a time.Time.
+func toTime(v sobek.Value) (time.Time, error) {
+	switch exported := v.Export().(type) {
+	case time.Time:
+		return exported, nil
+	case int64:
+		return time.UnixMilli(exported), nil
+	case float64:
+		return time.UnixMilli(int64(exported)), nil
+	default:
+		return time.Time{}, fmt.Errorf("expected a Date or a number of milliseconds, got %v", v)
+	}
+}
@@ -0,0 +1,63 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownsample(t *testing.T) {
+	t.Parallel()
+
+	samples := []SeriesSample{
+		{Time: 1000, Value: 1},
+		{Time: 1500, Value: 3},
+		{Time: 2100, Value: 10},
+		{Time: 4000, Value: 4},
+		{Time: 4999, Value: 6},
+	}
+
+	assert.Equal(t, []SeriesSample{
+		{Time: 1000, Value: 2},
+		{Time: 2000, Value: 10},
+		{Time: 4000, Value: 5},
+	}, downsample(samples, time.Second))
+
+	assert.Equal(t, samples, downsample(samples, 0))
+	assert.Equal(t, []SeriesSample{}, downsample(nil, time.Second))
+}
+
+func TestKVRecordSample(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	err := vu.run(`
+		const store = kv.openKv();
+
+		const rejects = (promise, what) => promise.then(
+			() => { throw new Error("expected " + what + " to be rejected"); },
+			(err) => {
+				if (!String(err).includes("sample value must be a number")) {
+					throw err;
+				}
+			},
+		);
+
+		Promise.all([
+			rejects(store.recordSample("latency"), "a missing value"),
+			rejects(store.recordSample("latency", null), "a null value"),
+			rejects(store.recordSample("latency", "fast"), "a non-numeric value"),
+		])
+			.then(() => store.recordSample("latency", 12))
+			.then(() => store.querySamples("latency"))
+			.then((samples) => {
+				if (samples.length !== 1 || samples[0].value !== 12) {
+					throw new Error("expected a single sample of 12, got " + JSON.stringify(samples));
+				}
+			});
+	`)
+	require.NoError(t, err)
+}
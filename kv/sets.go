@@ -0,0 +1,246 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// Sadd atomically adds a member to the set held by a key, creating it if the
+// key does not exist, and resolves with true if the member was not in the
+// set already.
+//
+// Sets are stored as JSON arrays of distinct members, in the order they
+// were added, so that KV.Get returns them as arrays. Members are compared
+// by their JSON encoding.
+func (k *KV) Sadd(key sobek.Value, member sobek.Value) *sobek.Promise {
+	return k.modifySet("sadd", key, member, func(members []json.RawMessage, member []byte) ([]json.RawMessage, bool) {
+		if memberIndex(members, member) >= 0 {
+			return members, false
+		}
+
+		return append(members, member), true
+	})
+}
+
+// Srem atomically removes a member from the set held by a key, deleting the
+// key once the set is empty, and resolves with true if the member was in
+// the set.
+func (k *KV) Srem(key sobek.Value, member sobek.Value) *sobek.Promise {
+	return k.modifySet("srem", key, member, func(members []json.RawMessage, member []byte) ([]json.RawMessage, bool) {
+		i := memberIndex(members, member)
+		if i < 0 {
+			return members, false
+		}
+
+		return append(members[:i:i], members[i+1:]...), true
+	})
+}
+
+// Smembers resolves with the members of the set held by a key, in the order
+// they were added, or an empty array if the key does not exist.
+func (k *KV) Smembers(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		members := make([]any, 0)
+
+		err := k.view(func(tx *bolt.Tx) error {
+			set, err := k.loadArray(tx, keyBytes)
+			if err != nil {
+				return err
+			}
+
+			for _, raw := range set {
+				var member any
+				if err := json.Unmarshal(raw, &member); err != nil {
+					return err
+				}
+
+				members = append(members, member)
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(members)
+	}()
+
+	return promise
+}
+
+// Sismember resolves with true if the member is in the set held by a key.
+func (k *KV) Sismember(key sobek.Value, member sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonMember, err := json.Marshal(member.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var found bool
+
+		err := k.view(func(tx *bolt.Tx) error {
+			set, err := k.loadArray(tx, keyBytes)
+			found = memberIndex(set, jsonMember) >= 0
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(found)
+	}()
+
+	return promise
+}
+
+// modifySet atomically replaces the set held by a key with the one modify
+// returns, given its members and the JSON-encoded member. The set is only
+// written, and the write counted, if modify reports a change, which the
+// promise resolves with.
+func (k *KV) modifySet(
+	op string, key, member sobek.Value, modify func(members []json.RawMessage, member []byte) ([]json.RawMessage, bool),
+) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	jsonMember, err := json.Marshal(member.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var changed bool
+
+		err := k.mutate(mutation{op: op, key: keyBytes}, func(tx *bolt.Tx) error {
+			set, err := k.loadArray(tx, keyBytes)
+			if err != nil {
+				return err
+			}
+
+			set, changed = modify(set, jsonMember)
+			if !changed {
+				return errUnchanged
+			}
+
+			return k.replaceValue(tx, keyBytes, set)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(changed)
+	}()
+
+	return promise
+}
+
+// loadArray returns the JSON-encoded items of the array, such as a set or a
+// list, held by a visible key of the bucket, or none if it does not exist.
+func (k *KV) loadArray(tx *bolt.Tx, key []byte) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	if err := k.loadTyped(tx, key, "an array", &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// loadTyped decodes the JSON value of a visible key of the bucket into
+// target, leaving it untouched if the key does not exist. It fails with a
+// TypeMismatchError if the value is not of the described type.
+func (k *KV) loadTyped(tx *bolt.Tx, key []byte, kind string, target any) error {
+	bucket := tx.Bucket(k.bucket)
+	if bucket == nil {
+		return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+	}
+
+	if newVisibility(tx, k.bucket).hidden(key) {
+		return nil
+	}
+
+	jsonValue, err := loadValue(tx, bucket.Get(key))
+	if err != nil || jsonValue == nil {
+		return err
+	}
+
+	if isBinary(jsonValue) || json.Unmarshal(jsonValue, target) != nil {
+		return NewError(TypeMismatchError, "the value of key "+string(key)+" is not "+kind)
+	}
+
+	return nil
+}
+
+// replaceValue stores the JSON encoding of value in a key of the bucket, or
+// deletes the key if value is empty.
+func (k *KV) replaceValue(tx *bolt.Tx, key []byte, value any) error {
+	bucket := tx.Bucket(k.bucket)
+
+	if err := undelay(tx, k.bucket, key); err != nil {
+		return err
+	}
+
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(jsonValue, []byte("[]")) || bytes.Equal(jsonValue, []byte("{}")) {
+		return k.removeValue(tx, bucket, key)
+	}
+
+	if err := k.validateValue(key, jsonValue); err != nil {
+		return err
+	}
+
+	return k.storeValue(tx, bucket, key, jsonValue)
+}
+
+// memberIndex returns the index of the JSON-encoded member in the set, or
+// -1 if it is not in it.
+func memberIndex(set []json.RawMessage, member []byte) int {
+	for i, m := range set {
+		if bytes.Equal(m, member) {
+			return i
+		}
+	}
+
+	return -1
+}
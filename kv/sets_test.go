@@ -0,0 +1,98 @@
+package kv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVSets(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+	seen := []byte("seen")
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		set, err := kv.loadArray(tx, seen)
+		require.NoError(t, err)
+		assert.Empty(t, set)
+
+		require.NoError(t, kv.replaceValue(tx, seen, []json.RawMessage{[]byte(`1`), []byte(`"two"`)}))
+		assert.Equal(t, []byte(`[1,"two"]`), tx.Bucket(kv.bucket).Get(seen))
+
+		set, err = kv.loadArray(tx, seen)
+		require.NoError(t, err)
+		assert.Equal(t, 1, memberIndex(set, []byte(`"two"`)))
+		assert.Equal(t, -1, memberIndex(set, []byte(`2`)))
+
+		// Empty sets are deleted
+		require.NoError(t, kv.replaceValue(tx, seen, []json.RawMessage{}))
+		assert.Nil(t, tx.Bucket(kv.bucket).Get(seen))
+
+		// Keys holding other types are rejected
+		require.NoError(t, tx.Bucket(kv.bucket).Put(seen, []byte(`{"foo":"bar"}`)))
+		_, err = kv.loadArray(tx, seen)
+
+		var kvErr *Error
+		require.ErrorAs(t, err, &kvErr)
+		assert.Equal(t, ErrorName(TypeMismatchError), kvErr.Name)
+
+		return nil
+	}))
+}
+
+func TestKVSetsUnchanged(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unchanged sets are not counted as written", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv();
+
+			store.sadd("seen", 1)
+				.then(() => Promise.all([store.sadd("seen", 1), store.srem("seen", 2)]))
+				.then(([added, removed]) => {
+					if (added || removed) {
+						throw new Error("expected the set to be left unchanged");
+					}
+
+					return store.stats();
+				})
+				.then((stats) => {
+					if (stats.writes !== 1) {
+						throw new Error("expected a single write to be counted, got " + stats.writes);
+					}
+				});
+		`)
+		require.NoError(t, err)
+	})
+
+	t.Run("unchanged sets are not reported in dry-run mode", func(t *testing.T) {
+		t.Parallel()
+
+		vu := newTestVU(t)
+
+		err := vu.run(`
+			const store = kv.openKv({ dryRun: true });
+
+			store.srem("seen", 1).then((removed) => {
+				if (removed) {
+					throw new Error("expected the set to be left unchanged");
+				}
+
+				const report = store.dryRunReport();
+				if (report.length !== 0) {
+					throw new Error("expected no mutation to be reported, got " + JSON.stringify(report));
+				}
+			});
+		`)
+		require.NoError(t, err)
+	})
+}
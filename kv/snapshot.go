@@ -0,0 +1,117 @@
+package kv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// snapshotTimeFormat is the layout of the timestamp suffixing the names of
+// the snapshot files, which sorts them chronologically.
+const snapshotTimeFormat = "20060102T150405Z"
+
+// snapshotEvery periodically writes a snapshot of the store to the
+// snapshotDir option's directory, at the snapshotInterval option's interval,
+// until done is closed.
+func (db *db) snapshotEvery(handle *bolt.DB, done <-chan struct{}) {
+	ticker := time.NewTicker(db.options.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			db.takeSnapshot(handle, now)
+		}
+	}
+}
+
+// takeSnapshot writes a snapshot of the store, and deletes the snapshots
+// beyond the snapshotKeep option's number. As it runs in the background,
+// its failures are counted in the store's stats rather than returned.
+func (db *db) takeSnapshot(handle *bolt.DB, now time.Time) {
+	path, err := writeSnapshot(handle, db.options.SnapshotDir, now)
+	if err == nil && db.options.SnapshotKeep > 0 {
+		err = pruneSnapshots(path, db.options.SnapshotKeep)
+	}
+
+	if err != nil {
+		db.stats.snapshotFailed(err)
+	}
+}
+
+// writeSnapshot writes a consistent copy of the store to a new file of the
+// directory, named after the store's file and the given time, and returns
+// its path. The directory is created if it does not exist.
+//
+// The copy is written within a read-only transaction, so that writes carry
+// on meanwhile, and to a temporary file first, so that a crash never leaves
+// a partial snapshot behind.
+func writeSnapshot(handle *bolt.DB, dir string, now time.Time) (string, error) {
+	if dir == "" {
+		dir = filepath.Dir(handle.Path())
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil { //nolint:forbidigo
+		return "", fmt.Errorf("failed to create the snapshot directory: %w", err)
+	}
+
+	path := filepath.Join(dir, filepath.Base(handle.Path())+"."+now.UTC().Format(snapshotTimeFormat))
+	tmp := path + ".tmp"
+
+	if err := handle.View(func(tx *bolt.Tx) error { return tx.CopyFile(tmp, 0o600) }); err != nil {
+		_ = os.Remove(tmp) //nolint:forbidigo
+		return "", fmt.Errorf("failed to write the snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil { //nolint:forbidigo
+		return "", fmt.Errorf("failed to write the snapshot: %w", err)
+	}
+
+	return path, nil
+}
+
+// pruneSnapshots deletes the snapshots of the same store as the one at path,
+// but the keep most recent ones.
+//
+//nolint:forbidigo
+func pruneSnapshots(path string, keep int64) error {
+	dir := filepath.Dir(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + "."
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to prune the snapshots: %w", err)
+	}
+
+	// The timestamps of the names sort chronologically, as do the entries.
+	var snapshots []string
+	for _, entry := range entries {
+		name := entry.Name()
+		timestamp, found := strings.CutPrefix(name, prefix)
+		if !found || entry.IsDir() {
+			continue
+		}
+
+		if _, err := time.Parse(snapshotTimeFormat, timestamp); err != nil {
+			continue
+		}
+
+		snapshots = append(snapshots, name)
+	}
+
+	for len(snapshots) > int(keep) {
+		if err := os.Remove(filepath.Join(dir, snapshots[0])); err != nil {
+			return fmt.Errorf("failed to prune the snapshots: %w", err)
+		}
+
+		snapshots = snapshots[1:]
+	}
+
+	return nil
+}
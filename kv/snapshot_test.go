@@ -0,0 +1,95 @@
+package kv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+//nolint:forbidigo
+func TestWriteSnapshot(t *testing.T) {
+	t.Parallel()
+
+	dbInstance := openTestDB(t, Options{})
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(DefaultKvBucket)).Put([]byte("foo"), []byte(`"bar"`))
+	}))
+
+	dir := filepath.Join(t.TempDir(), "snaps")
+	path, err := writeSnapshot(dbInstance.handle, dir, time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "test.db.20240501T123000Z"), path)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	snapshot, err := bolt.Open(path, 0o600, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, snapshot.Close())
+	})
+
+	assert.NoError(t, snapshot.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, []byte(`"bar"`), tx.Bucket([]byte(DefaultKvBucket)).Get([]byte("foo")))
+		return nil
+	}))
+}
+
+//nolint:forbidigo
+func TestTakeSnapshot(t *testing.T) {
+	t.Parallel()
+
+	t.Run("snapshots beyond the number kept are deleted", func(t *testing.T) {
+		t.Parallel()
+
+		dir := filepath.Join(t.TempDir(), "snaps")
+		dbInstance := openTestDB(t, Options{SnapshotDir: dir, SnapshotKeep: 2})
+
+		// Files which are not snapshots of the store are left alone.
+		require.NoError(t, os.MkdirAll(dir, 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "other.db.20240501T000000Z"), nil, 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "test.db.notes"), nil, 0o600))
+
+		start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		for i := 0; i < 4; i++ {
+			dbInstance.takeSnapshot(dbInstance.handle, start.Add(time.Duration(i)*time.Minute))
+		}
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+
+		assert.Equal(t, []string{
+			"other.db.20240501T000000Z",
+			"test.db.20240501T120200Z",
+			"test.db.20240501T120300Z",
+			"test.db.notes",
+		}, names)
+		assert.Zero(t, dbInstance.stats.snapshot().SnapshotErrors)
+	})
+
+	t.Run("failures are counted in the stats", func(t *testing.T) {
+		t.Parallel()
+
+		// The snapshot directory can't be created under a file.
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+		dbInstance := openTestDB(t, Options{SnapshotDir: filepath.Join(file, "snaps")})
+		dbInstance.takeSnapshot(dbInstance.handle, time.Now())
+
+		stats := dbInstance.stats.snapshot()
+		assert.Equal(t, int64(1), stats.SnapshotErrors)
+		assert.Contains(t, stats.LastSnapshotError, "failed to create the snapshot directory")
+	})
+}
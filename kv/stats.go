@@ -0,0 +1,138 @@
+package kv
+
+import (
+	"sync/atomic"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/promises"
+)
+
+// StoreStats are the runtime statistics of the store, shared by all VUs, as
+// returned by KV.Stats().
+type StoreStats struct {
+	// Reads is the number of read operations run on the store.
+	Reads int64 `json:"reads" js:"reads"`
+
+	// Writes is the number of write operations applied to the store.
+	Writes int64 `json:"writes" js:"writes"`
+
+	// Hits is the number of keys looked up with KV.Get, KV.GetOrDefault
+	// or KV.GetMany which existed.
+	Hits int64 `json:"hits" js:"hits"`
+
+	// Misses is the number of keys looked up with KV.Get, KV.GetOrDefault
+	// or KV.GetMany which did not exist.
+	Misses int64 `json:"misses" js:"misses"`
+
+	// HitRatio is the ratio of the lookups which were hits, or zero if no
+	// key was looked up.
+	HitRatio float64 `json:"hitRatio" js:"hitRatio"`
+
+	// BytesRead is the size of the encoded values read by lookups and listings.
+	BytesRead int64 `json:"bytesRead" js:"bytesRead"`
+
+	// BytesWritten is the size of the encoded values written.
+	BytesWritten int64 `json:"bytesWritten" js:"bytesWritten"`
+
+	// Entries is the current number of keys of the KV instance's bucket.
+	Entries int64 `json:"entries" js:"entries"`
+
+	// SnapshotErrors is the number of periodic snapshots, written with the
+	// snapshotInterval option, which failed.
+	SnapshotErrors int64 `json:"snapshotErrors" js:"snapshotErrors"`
+
+	// LastSnapshotError describes the last failure of a periodic snapshot,
+	// if any.
+	LastSnapshotError string `json:"lastSnapshotError" js:"lastSnapshotError"`
+}
+
+// statsLog counts the operations run on the store, shared by all VUs.
+type statsLog struct {
+	reads        atomic.Int64
+	writes       atomic.Int64
+	hits         atomic.Int64
+	misses       atomic.Int64
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+
+	snapshotErrors    atomic.Int64
+	lastSnapshotError atomic.Pointer[string]
+}
+
+// lookup counts the lookup of a key, which is a miss if its value is nil.
+func (l *statsLog) lookup(value []byte) {
+	if value == nil {
+		l.misses.Add(1)
+		return
+	}
+
+	l.hits.Add(1)
+	l.bytesRead.Add(int64(len(value)))
+}
+
+// snapshotFailed counts a failure of a periodic snapshot.
+func (l *statsLog) snapshotFailed(err error) {
+	message := err.Error()
+	l.lastSnapshotError.Store(&message)
+	l.snapshotErrors.Add(1)
+}
+
+// snapshot returns the current statistics, but the number of entries.
+func (l *statsLog) snapshot() StoreStats {
+	stats := StoreStats{
+		Reads:        l.reads.Load(),
+		Writes:       l.writes.Load(),
+		Hits:         l.hits.Load(),
+		Misses:       l.misses.Load(),
+		BytesRead:    l.bytesRead.Load(),
+		BytesWritten: l.bytesWritten.Load(),
+
+		SnapshotErrors: l.snapshotErrors.Load(),
+	}
+
+	if message := l.lastSnapshotError.Load(); message != nil {
+		stats.LastSnapshotError = *message
+	}
+
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+
+	return stats
+}
+
+// trackWrite counts the bytes of a value written, once the transaction is committed.
+func (k *KV) trackWrite(tx *bolt.Tx, value []byte) {
+	tx.OnCommit(func() {
+		k.db.stats.bytesWritten.Add(int64(len(value)))
+	})
+}
+
+// Stats resolves with the runtime statistics of the store, counted since it
+// was opened, along with the current number of keys of the bucket.
+func (k *KV) Stats() *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	go func() {
+		var entries int64
+
+		err := k.view(func(tx *bolt.Tx) error {
+			var err error
+			entries, err = k.countPrefix(tx, nil)
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		stats := k.db.stats.snapshot()
+		stats.Entries = entries
+
+		resolve(stats)
+	}()
+
+	return promise
+}
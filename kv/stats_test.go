@@ -0,0 +1,43 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestStatsLogSnapshot(t *testing.T) {
+	t.Parallel()
+
+	var log statsLog
+	assert.Zero(t, log.snapshot().HitRatio)
+
+	log.lookup([]byte(`"bar"`))
+	log.lookup([]byte(`1`))
+	log.lookup([]byte(`2`))
+	log.lookup(nil)
+
+	stats := log.snapshot()
+	assert.Equal(t, int64(3), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, 0.75, stats.HitRatio)
+	assert.Equal(t, int64(7), stats.BytesRead)
+}
+
+func TestKVStats(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+	require.NoError(t, kv.mutate(mutation{op: "set", key: []byte("foo")}, func(tx *bolt.Tx) error {
+		return kv.storeValue(tx, tx.Bucket(kv.bucket), []byte("foo"), []byte(`"bar"`))
+	}))
+	require.NoError(t, kv.view(func(*bolt.Tx) error { return nil }))
+
+	stats := dbInstance.stats.snapshot()
+	assert.Equal(t, int64(1), stats.Writes)
+	assert.Equal(t, int64(1), stats.Reads)
+	assert.Equal(t, int64(5), stats.BytesWritten)
+}
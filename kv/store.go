@@ -0,0 +1,174 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store gives Go code, such as other extensions compiled into the same k6
+// binary, access to the same store the scripts use, without a VU.
+//
+// Values are exchanged as Go values, which are JSON-encoded the same way as
+// the scripts' values. Codecs registered by scripts do not apply.
+type Store struct {
+	kv *KV
+}
+
+// OpenStore opens the store the scripts use, or the dataset selected by the
+// options, and returns a handle on it, which must be closed once done.
+//
+// As with openKv, options affecting how the store itself is opened are only
+// taken into account if it is not open already.
+func (rm *RootModule) OpenStore(options Options) (*Store, error) {
+	if options.Bucket == "" {
+		options.Bucket = DefaultKvBucket
+	}
+
+	if err := validateBucketName(options.Bucket); err != nil {
+		return nil, err
+	}
+
+	store := rm.dataset(options.Dataset)
+	if err := store.open(options); err != nil {
+		return nil, err
+	}
+
+	if err := store.ensureBucket([]byte(options.Bucket)); err != nil {
+		_ = store.close()
+		return nil, err
+	}
+
+	return &Store{kv: &KV{bucket: []byte(options.Bucket), db: store, options: options}}, nil
+}
+
+// Get returns the value of a key, decoded from JSON, and whether it exists.
+func (s *Store) Get(key string) (any, bool, error) {
+	var value any
+
+	found := false
+	err := s.kv.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(s.kv.bucket)
+		if bucket == nil {
+			return NewError(BucketNotFoundError, "bucket "+string(s.kv.bucket)+" not found")
+		}
+
+		if newVisibility(tx, s.kv.bucket).hidden([]byte(key)) {
+			return nil
+		}
+
+		jsonValue, err := loadValue(tx, bucket.Get([]byte(key)))
+		if err != nil || jsonValue == nil {
+			return err
+		}
+
+		found = true
+
+		return decodeValue(jsonValue, &value)
+	})
+
+	return value, found, err
+}
+
+// Set sets the value of a key, encoded to JSON.
+func (s *Store) Set(key string, value any) error {
+	keyBytes := []byte(key)
+	if err := s.kv.validateKey(keyBytes); err != nil {
+		return err
+	}
+
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return s.kv.mutate(mutation{op: "set", key: keyBytes, value: jsonValue}, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(s.kv.bucket)
+		if bucket == nil {
+			return NewError(BucketNotFoundError, "bucket "+string(s.kv.bucket)+" not found")
+		}
+
+		if err := undelay(tx, s.kv.bucket, keyBytes); err != nil {
+			return err
+		}
+
+		return s.kv.storeValue(tx, bucket, keyBytes, jsonValue)
+	})
+}
+
+// Delete deletes a key.
+func (s *Store) Delete(key string) error {
+	keyBytes := []byte(key)
+
+	return s.kv.mutate(mutation{op: "delete", key: keyBytes}, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(s.kv.bucket)
+		if bucket == nil {
+			return NewError(BucketNotFoundError, "bucket "+string(s.kv.bucket)+" not found")
+		}
+
+		if err := undelay(tx, s.kv.bucket, keyBytes); err != nil {
+			return err
+		}
+
+		return s.kv.removeValue(tx, bucket, keyBytes)
+	})
+}
+
+// List returns the entries whose key starts with the prefix, ordered by key.
+func (s *Store) List(prefix string) ([]ListEntry, error) {
+	var entries []ListEntry
+
+	err := s.kv.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(s.kv.bucket)
+		if bucket == nil {
+			return NewError(BucketNotFoundError, "bucket "+string(s.kv.bucket)+" not found")
+		}
+
+		var err error
+		entries, _, err = s.kv.listEntries(tx, bucket, ListOptions{Prefix: prefix})
+
+		return err
+	})
+
+	return entries, err
+}
+
+// ForEach calls fn with the JSON-encoded value of each key starting with
+// the prefix, ordered by key, until fn returns an error. The value is only
+// valid until fn returns.
+func (s *Store) ForEach(prefix string, fn func(key string, value []byte) error) error {
+	return s.kv.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(s.kv.bucket)
+		if bucket == nil {
+			return NewError(BucketNotFoundError, "bucket "+string(s.kv.bucket)+" not found")
+		}
+
+		visible := newVisibility(tx, s.kv.bucket)
+		prefixBytes := []byte(prefix)
+		cursor := bucket.Cursor()
+
+		for key, raw := cursor.Seek(prefixBytes); key != nil && bytes.HasPrefix(key, prefixBytes); key, raw = cursor.Next() {
+			if visible.hidden(key) {
+				continue
+			}
+
+			value, err := loadValue(tx, raw)
+			if err != nil {
+				return err
+			}
+
+			if err := fn(string(key), value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Close releases the handle on the store, which is closed once no script
+// or Go code uses it anymore.
+func (s *Store) Close() error {
+	return s.kv.db.close()
+}
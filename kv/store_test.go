@@ -0,0 +1,53 @@
+package kv
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootModuleOpenStore(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	rm := New()
+
+	store, err := rm.OpenStore(Options{Dataset: filepath.Join(tmpDir, "store.db")})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Set("resource:1", map[string]any{"id": "a"}))
+	require.NoError(t, store.Set("resource:2", map[string]any{"id": "b"}))
+	require.NoError(t, store.Set("other", 1))
+
+	value, found, err := store.Get("resource:1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, map[string]any{"id": "a"}, value)
+
+	entries, err := store.List("resource:")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	var keys []string
+	require.NoError(t, store.ForEach("", func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"other", "resource:1", "resource:2"}, keys)
+
+	require.NoError(t, store.Delete("resource:1"))
+
+	_, found, err = store.Get("resource:1")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// The same dataset is shared with the scripts opening it.
+	shared := rm.dataset(filepath.Join(tmpDir, "store.db"))
+	assert.Equal(t, int64(1), shared.refCount.Load())
+
+	require.NoError(t, store.Close())
+	assert.False(t, shared.opened.Load())
+}
@@ -0,0 +1,104 @@
+package kv
+
+import (
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+// The synchronous variants of the KV methods run on the VU's event loop,
+// which they block, timers and the settling of promises included, for as
+// long as the store's file is read or written. Reads of pages missing from
+// the OS page cache, and writes, which wait for the file to be synced
+// unless the store is opened with the noSync or flushInterval options, thus
+// stall the VU on disk I/O.
+
+// GetSync returns the value of a key in the store, like KV.Get, but blocks
+// the VU until it is read, rather than returning a promise, for hot paths
+// where awaiting each operation costs more than the read itself.
+//
+// It throws a KeyNotFoundError if the key does not exist.
+func (k *KV) GetSync(key sobek.Value) sobek.Value {
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return nil
+	}
+
+	jsonValue, err := k.lookup(keyBytes)
+	if err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return nil
+	}
+
+	if jsonValue == nil {
+		common.Throw(k.vu.Runtime(), NewError(KeyNotFoundError, "key "+string(keyBytes)+" not found"))
+		return nil
+	}
+
+	var value any
+	if err := decodeValue(jsonValue, &value); err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return nil
+	}
+
+	revived, err := k.revive(keyBytes, value)
+	if err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return nil
+	}
+
+	return k.vu.Runtime().ToValue(revived)
+}
+
+// SetSync sets the value of a key in the store, like KV.Set, but blocks the
+// VU until it is written, rather than returning a promise, and returns the
+// value.
+func (k *KV) SetSync(key sobek.Value, value sobek.Value, options sobek.Value) sobek.Value {
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return nil
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return nil
+	}
+
+	jsonValue, err := k.marshal(keyBytes, value)
+	if err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return nil
+	}
+
+	setOptions, err := ImportSetOptions(k.vu.Runtime(), options)
+	if err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return nil
+	}
+
+	if err := k.set(keyBytes, jsonValue, setOptions); err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return nil
+	}
+
+	return value
+}
+
+// ExistsSync returns whether a key exists in the store, like KV.Exists, but
+// blocks the VU until it is known, rather than returning a promise.
+func (k *KV) ExistsSync(key sobek.Value) bool {
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return false
+	}
+
+	exists, err := k.exists(keyBytes)
+	if err != nil {
+		common.Throw(k.vu.Runtime(), err)
+		return false
+	}
+
+	return exists
+}
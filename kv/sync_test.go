@@ -0,0 +1,56 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVSync(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	err := vu.run(`
+		const store = kv.openKv();
+
+		if (store.existsSync("foo")) {
+			throw new Error("expected foo not to exist");
+		}
+
+		const set = store.setSync("foo", { bar: 1 });
+		if (set.bar !== 1) {
+			throw new Error("expected setSync to return the value, got " + JSON.stringify(set));
+		}
+
+		if (!store.existsSync("foo")) {
+			throw new Error("expected foo to exist");
+		}
+
+		const value = store.getSync("foo");
+		if (value.bar !== 1) {
+			throw new Error("expected getSync to return the value, got " + JSON.stringify(value));
+		}
+
+		let thrown;
+		try {
+			store.getSync("missing");
+		} catch (err) {
+			thrown = err;
+		}
+
+		if (!String(thrown).includes("KeyNotFoundError")) {
+			throw new Error("expected getSync to throw a KeyNotFoundError, got " + thrown);
+		}
+
+		try {
+			store.setSync("", 1);
+			throw new Error("expected setSync to throw on empty keys");
+		} catch (err) {
+			if (!String(err).includes("KeyRequiredError")) {
+				throw err;
+			}
+		}
+	`)
+	require.NoError(t, err)
+}
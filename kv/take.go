@@ -0,0 +1,171 @@
+package kv
+
+import (
+	"bytes"
+	"crypto/rand"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// TakenEntry is a key-value pair returned by KV.TakeRandom().
+type TakenEntry struct {
+	Key   string `json:"key" js:"key"`
+	Value any    `json:"value" js:"value"`
+}
+
+// Take atomically deletes a key from the store, and resolves with the value
+// it held, or null if it did not exist.
+//
+// Each key is taken exactly once, even when several VUs take it concurrently,
+// which makes it suitable to claim unique test records.
+func (k *KV) Take(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	settle := k.reviveLater(resolve, reject)
+
+	go func() {
+		var taken any
+
+		err := k.mutate(mutation{op: "take", key: keyBytes}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			if newVisibility(tx, k.bucket).hidden(keyBytes) {
+				return nil
+			}
+
+			var err error
+			taken, err = k.takeValue(tx, bucket, keyBytes)
+
+			return err
+		})
+		settle(func() (any, error) {
+			if err != nil || taken == nil {
+				return nil, err
+			}
+
+			return k.revive(keyBytes, taken)
+		})
+	}()
+
+	return promise
+}
+
+// TakeRandom atomically deletes a key starting with the given prefix, picked
+// at random, and resolves with a TakenEntry holding it and the value it held,
+// or null if there is no such key.
+//
+// Keys are picked by seeking to a random position among the matching keys,
+// which is cheap whatever their number, but not uniformly random when keys
+// are unevenly distributed.
+func (k *KV) TakeRandom(prefix sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	var prefixBytes []byte
+	if !common.IsNullish(prefix) {
+		prefixBytes = []byte(prefix.String())
+	}
+
+	settle := k.reviveLater(resolve, reject)
+
+	go func() {
+		var taken *TakenEntry
+
+		err := k.mutate(mutation{op: "takeRandom"}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			key := randomKey(bucket, newVisibility(tx, k.bucket), prefixBytes)
+			if key == nil {
+				return nil
+			}
+
+			key = bytes.Clone(key)
+
+			value, err := k.takeValue(tx, bucket, key)
+			if err != nil {
+				return err
+			}
+
+			taken = &TakenEntry{Key: string(key), Value: value}
+
+			return nil
+		})
+		settle(func() (any, error) {
+			if err != nil || taken == nil {
+				return nil, err
+			}
+
+			value, err := k.revive([]byte(taken.Key), taken.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			taken.Value = value
+
+			return taken, nil
+		})
+	}()
+
+	return promise
+}
+
+// takeValue deletes a key of the bucket, and returns the decoded value it
+// held, or nil if it did not exist.
+func (k *KV) takeValue(tx *bolt.Tx, bucket *bolt.Bucket, key []byte) (any, error) {
+	jsonValue, err := loadValue(tx, bucket.Get(key))
+	if err != nil || jsonValue == nil {
+		return nil, err
+	}
+
+	// Decode the value before it is deleted, as the memory it
+	// points to is only valid until then.
+	var value any
+	if err := decodeValue(jsonValue, &value); err != nil {
+		return nil, err
+	}
+
+	if err := undelay(tx, k.bucket, key); err != nil {
+		return nil, err
+	}
+
+	return value, k.removeValue(tx, bucket, key)
+}
+
+// randomKey returns a visible key of the bucket starting with the prefix,
+// picked by seeking to a random key after the prefix, or nil if there is
+// none. The returned key is only valid for the life of the transaction.
+func randomKey(bucket *bolt.Bucket, visible visibility, prefix []byte) []byte {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil
+	}
+
+	cursor := bucket.Cursor()
+	target := append(bytes.Clone(prefix), suffix...)
+
+	// Look for a visible key from the random position to the last matching
+	// key, then wrap around from the first one.
+	for _, start := range [][]byte{target, prefix} {
+		for key, _ := cursor.Seek(start); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			if !visible.hidden(key) {
+				return key
+			}
+		}
+	}
+
+	return nil
+}
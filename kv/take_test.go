@@ -0,0 +1,58 @@
+package kv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVTakeValue(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+		for _, key := range []string{"account:1", "account:2", "account:3", "user:1"} {
+			require.NoError(t, kv.storeValue(tx, bucket, []byte(key), []byte(`"`+key+`"`)))
+		}
+
+		return nil
+	}))
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		value, err := kv.takeValue(tx, bucket, []byte("user:1"))
+		require.NoError(t, err)
+		assert.Equal(t, "user:1", value)
+		assert.Nil(t, bucket.Get([]byte("user:1")))
+
+		value, err = kv.takeValue(tx, bucket, []byte("user:1"))
+		require.NoError(t, err)
+		assert.Nil(t, value)
+
+		// Every matching key is eventually picked, exactly once.
+		taken := map[string]bool{}
+		for i := 0; i < 3; i++ {
+			key := randomKey(bucket, newVisibility(tx, kv.bucket), []byte("account:"))
+			require.NotNil(t, key)
+			assert.True(t, strings.HasPrefix(string(key), "account:"))
+
+			key = append([]byte(nil), key...)
+			_, err := kv.takeValue(tx, bucket, key)
+			require.NoError(t, err)
+
+			assert.False(t, taken[string(key)])
+			taken[string(key)] = true
+		}
+
+		assert.Nil(t, randomKey(bucket, newVisibility(tx, kv.bucket), []byte("account:")))
+
+		return nil
+	}))
+}
@@ -0,0 +1,347 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// expirySweepInterval is the interval at which expired keys are purged
+// from an open store.
+const expirySweepInterval = time.Second
+
+// SetOptions are the options that can be passed to KV.Set().
+type SetOptions struct {
+	// TTL is how long the key lives for, after which it is treated as
+	// absent, and purged. Zero, the default, means forever.
+	TTL time.Duration `json:"ttl"`
+}
+
+// ImportSetOptions instantiates a SetOptions from a sobek.Value.
+func ImportSetOptions(rt *sobek.Runtime, options sobek.Value) (SetOptions, error) {
+	setOptions := SetOptions{}
+
+	// If no options are passed, return the default options
+	if common.IsNullish(options) {
+		return setOptions, nil
+	}
+
+	ttl, err := toDuration(options.ToObject(rt).Get("ttl"))
+	if err != nil {
+		return setOptions, fmt.Errorf("invalid ttl: %w", err)
+	}
+
+	if ttl < 0 {
+		return setOptions, fmt.Errorf("ttl must not be negative, got %s", ttl)
+	}
+
+	setOptions.TTL = ttl
+
+	return setOptions, nil
+}
+
+// Ttl resolves with the number of milliseconds the key has left to live,
+// or null if it lives forever. It rejects with a KeyNotFoundError if the
+// key does not exist.
+func (k *KV) Ttl(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var remaining any
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			visible := newVisibility(tx, k.bucket)
+			if bucket.Get(keyBytes) == nil || visible.hidden(keyBytes) {
+				return NewError(KeyNotFoundError, "key "+string(keyBytes)+" not found")
+			}
+
+			if deadline, expires := expiryOf(visible.expiry, keyBytes); expires {
+				remaining = deadline.Sub(visible.now).Milliseconds()
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(remaining)
+	}()
+
+	return promise
+}
+
+// Persist removes the TTL of the key, so that it lives forever, and resolves
+// with true if it had one. It rejects with a KeyNotFoundError if the key does
+// not exist.
+func (k *KV) Persist(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		persisted := false
+
+		err := k.mutate(mutation{op: "persist", key: keyBytes}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			visible := newVisibility(tx, k.bucket)
+			if bucket.Get(keyBytes) == nil || visible.hidden(keyBytes) {
+				return NewError(KeyNotFoundError, "key "+string(keyBytes)+" not found")
+			}
+
+			_, persisted = expiryOf(visible.expiry, keyBytes)
+			if persisted {
+				k.logExpiry(tx, keyBytes, nil)
+			}
+
+			return unexpire(tx, k.bucket, keyBytes)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(persisted)
+	}()
+
+	return promise
+}
+
+// expiryBucket returns the name of the internal bucket holding the
+// expiration deadlines of the keys of the given bucket.
+func expiryBucket(bucket []byte) []byte {
+	return []byte(string(bucket) + "/expiry")
+}
+
+// expire sets the key to expire once the TTL has elapsed.
+func expire(tx *bolt.Tx, bucket []byte, key []byte, ttl time.Duration) error {
+	return expireAt(tx, bucket, key, time.Now().Add(ttl))
+}
+
+// expireAt sets the key to expire at the given deadline.
+func expireAt(tx *bolt.Tx, bucket []byte, key []byte, deadline time.Time) error {
+	expiry, err := tx.CreateBucketIfNotExists(expiryBucket(bucket))
+	if err != nil {
+		return err
+	}
+
+	encoded := make([]byte, 8)
+	binary.BigEndian.PutUint64(encoded, uint64(deadline.UnixNano()))
+
+	return expiry.Put(key, encoded)
+}
+
+// expireKey sets a key of the bucket to expire at the given deadline, and
+// records it to the operation log.
+func (k *KV) expireKey(tx *bolt.Tx, key []byte, deadline time.Time) error {
+	if err := expireAt(tx, k.bucket, key, deadline); err != nil {
+		return err
+	}
+
+	k.logExpiry(tx, key, &deadline)
+
+	return nil
+}
+
+// unexpire removes any expiration deadline set on the key.
+func unexpire(tx *bolt.Tx, bucket []byte, key []byte) error {
+	expiry := tx.Bucket(expiryBucket(bucket))
+	if expiry == nil {
+		return nil
+	}
+
+	return expiry.Delete(key)
+}
+
+// expiryOf returns the expiration deadline of the key, if it has one.
+//
+// The expiry bucket is nil when no TTL was ever set.
+func expiryOf(expiry *bolt.Bucket, key []byte) (time.Time, bool) {
+	if expiry == nil {
+		return time.Time{}, false
+	}
+
+	deadline := expiry.Get(key)
+	if len(deadline) != 8 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, int64(binary.BigEndian.Uint64(deadline))), true
+}
+
+// isExpired reports whether the key has expired.
+func isExpired(expiry *bolt.Bucket, key []byte, now time.Time) bool {
+	deadline, expires := expiryOf(expiry, key)
+
+	return expires && !now.Before(deadline)
+}
+
+// visibility tells which keys of a bucket are hidden from reads, either
+// because they are delayed and not visible yet, or because they expired.
+type visibility struct {
+	delayed *bolt.Bucket
+	expiry  *bolt.Bucket
+	now     time.Time
+}
+
+// newVisibility returns the visibility of the keys of the given bucket.
+func newVisibility(tx *bolt.Tx, bucket []byte) visibility {
+	return visibility{
+		delayed: tx.Bucket(delayedBucket(bucket)),
+		expiry:  tx.Bucket(expiryBucket(bucket)),
+		now:     time.Now(),
+	}
+}
+
+// hidden reports whether the key is hidden from reads.
+func (v visibility) hidden(key []byte) bool {
+	return isPending(v.delayed, key, v.now) || isExpired(v.expiry, key, v.now)
+}
+
+// countHidden returns the number of keys hidden from reads.
+func (v visibility) countHidden() int64 {
+	hidden := countPending(v.delayed, v.now)
+
+	if v.expiry != nil {
+		_ = v.expiry.ForEach(func(k, _ []byte) error {
+			if isExpired(v.expiry, k, v.now) && !isPending(v.delayed, k, v.now) {
+				hidden++
+			}
+
+			return nil
+		})
+	}
+
+	return hidden
+}
+
+// sweepExpired periodically purges the expired keys of the store,
+// until done is closed.
+func (db *db) sweepExpired(handle *bolt.DB, done <-chan struct{}) {
+	ticker := time.NewTicker(expirySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			// Only start a write transaction when there is something to purge,
+			// as committing one syncs the file to disk.
+			found := false
+			_ = handle.View(func(tx *bolt.Tx) error {
+				found = len(findExpired(tx, time.Now())) > 0
+				return nil
+			})
+
+			if found {
+				// Hold swap, so that the store isn't closed, along with its
+				// operation log, while the keys are purged.
+				db.swap.RLock()
+				_ = handle.Update(db.purgeExpired)
+				db.swap.RUnlock()
+			}
+		}
+	}
+}
+
+// findExpired returns the expired keys of every bucket of the store,
+// by bucket name.
+func findExpired(tx *bolt.Tx, now time.Time) map[string][][]byte {
+	found := make(map[string][][]byte)
+	suffix := []byte("/expiry")
+
+	_ = tx.ForEach(func(name []byte, expiry *bolt.Bucket) error {
+		if !bytes.HasSuffix(name, suffix) {
+			return nil
+		}
+
+		return expiry.ForEach(func(k, _ []byte) error {
+			if isExpired(expiry, k, now) {
+				bucket := string(bytes.TrimSuffix(name, suffix))
+				found[bucket] = append(found[bucket], bytes.Clone(k))
+			}
+
+			return nil
+		})
+	})
+
+	return found
+}
+
+// purgeExpired deletes the expired keys of every bucket of the store, and
+// records their deletion to the operation log, and to the churn counts by
+// the store's churnPrefixes.
+func (db *db) purgeExpired(tx *bolt.Tx) error {
+	deleted := make(map[string]int64)
+	tx.OnCommit(func() {
+		for prefix, n := range deleted {
+			db.churn.record(prefix, churnDeleted, n)
+		}
+	})
+
+	for bucketName, expired := range findExpired(tx, time.Now()) {
+		name := []byte(bucketName)
+		expiry := tx.Bucket(expiryBucket(name))
+
+		bucket := tx.Bucket(name)
+		for _, key := range expired {
+			if bucket != nil && bucket.Get(key) != nil {
+				if err := releaseValue(tx, bucket.Get(key)); err != nil {
+					return err
+				}
+
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+
+				db.opLog.appendOnCommit(tx, opLogEntry{Op: OpLogDelete, Bucket: bucketName, Key: string(key)})
+				deleted[longestPrefix(db.options.ChurnPrefixes, key)]++
+			}
+
+			if err := unindexValue(tx, name, key); err != nil {
+				return err
+			}
+
+			if err := dropVersion(tx, name, key); err != nil {
+				return err
+			}
+
+			if err := expiry.Delete(key); err != nil {
+				return err
+			}
+
+			if err := undelay(tx, name, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
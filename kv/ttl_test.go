@@ -0,0 +1,96 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestExpiry(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		for _, key := range []string{"expired", "expiring", "persistent"} {
+			require.NoError(t, kv.storeValue(tx, bucket, []byte(key), []byte(`1`)))
+		}
+
+		require.NoError(t, expire(tx, kv.bucket, []byte("expired"), time.Nanosecond))
+		require.NoError(t, expire(tx, kv.bucket, []byte("expiring"), time.Hour))
+
+		return nil
+	}))
+
+	time.Sleep(time.Millisecond)
+
+	require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+		visible := newVisibility(tx, kv.bucket)
+
+		assert.True(t, visible.hidden([]byte("expired")))
+		assert.False(t, visible.hidden([]byte("expiring")))
+		assert.False(t, visible.hidden([]byte("persistent")))
+		assert.Equal(t, int64(1), visible.countHidden())
+
+		deadline, expires := expiryOf(visible.expiry, []byte("expiring"))
+		assert.True(t, expires)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+
+		return nil
+	}))
+
+	require.NoError(t, dbInstance.handle.Update(dbInstance.purgeExpired))
+
+	require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		assert.Nil(t, bucket.Get([]byte("expired")))
+		assert.NotNil(t, bucket.Get([]byte("expiring")))
+		assert.Empty(t, findExpired(tx, time.Now()))
+
+		return nil
+	}))
+
+	// Writing a key removes its TTL.
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		require.NoError(t, kv.storeValue(tx, tx.Bucket(kv.bucket), []byte("expiring"), []byte(`2`)))
+
+		_, expires := expiryOf(tx.Bucket(expiryBucket(kv.bucket)), []byte("expiring"))
+		assert.False(t, expires)
+
+		return nil
+	}))
+}
+
+func TestKVTtl(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	err := vu.run(`
+		const store = kv.openKv();
+
+		store.set("expiring", 1, { ttl: "1h" })
+			.then(() => store.set("persistent", 2))
+			.then(() => store.ttl("expiring"))
+			.then((remaining) => {
+				if (!(remaining > 0 && remaining <= 3600000)) {
+					throw new Error("expected expiring to have at most an hour left, got " + remaining);
+				}
+
+				return store.ttl("persistent");
+			})
+			.then((remaining) => {
+				if (remaining !== null) {
+					throw new Error("expected persistent to have no TTL, got " + remaining);
+				}
+			});
+	`)
+	require.NoError(t, err)
+}
@@ -0,0 +1,111 @@
+package kv
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// undoAbsentMarker is recorded as the previous value of keys which did not
+// exist before their last mutation. Neither JSON values, nor the markers of
+// binary and serialized values, start with it.
+const undoAbsentMarker = 0x00
+
+// Undo reverts the last mutation of a key, restoring the value it held
+// before, or deleting it if it did not exist.
+//
+// Only the keys starting with the UndoPrefix the store was opened with have
+// their previous value kept. Undoing is itself a mutation of the key: undoing
+// twice in a row restores the value the key held before the first undo.
+func (k *KV) Undo(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if !k.undoable(keyBytes) {
+		reject(NewError(InvalidKeyError, "key "+key.String()+" does not start with the undo prefix"))
+		return promise
+	}
+
+	go func() {
+		err := k.mutate(mutation{op: "undo", key: keyBytes}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			previous := tx.Bucket(undoBucket(k.bucket))
+			if previous == nil || previous.Get(keyBytes) == nil {
+				return NewError(KeyNotFoundError, "no mutation of key "+key.String()+" to undo")
+			}
+
+			// Copy the previous value, as it is overwritten once the undo
+			// itself is recorded.
+			value := bytes.Clone(previous.Get(keyBytes))
+
+			if err := undelay(tx, k.bucket, keyBytes); err != nil {
+				return err
+			}
+
+			if len(value) == 1 && value[0] == undoAbsentMarker {
+				return k.removeValue(tx, bucket, keyBytes)
+			}
+
+			return k.storeValue(tx, bucket, keyBytes, value)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(true)
+	}()
+
+	return promise
+}
+
+// undoBucket returns the name of the internal bucket holding the values
+// the undoable keys of the given bucket held before their last mutation.
+func undoBucket(bucket []byte) []byte {
+	return []byte(string(bucket) + "/undo")
+}
+
+// undoable reports whether the previous value of the key is kept, so that
+// its last mutation can be undone.
+func (k *KV) undoable(key []byte) bool {
+	return k.options.UndoPrefix != "" && strings.HasPrefix(string(key), k.options.UndoPrefix)
+}
+
+// currentValue returns a copy of the value a key of the bucket holds,
+// or the undoAbsentMarker if it does not exist.
+func (k *KV) currentValue(tx *bolt.Tx, key []byte) ([]byte, error) {
+	bucket := tx.Bucket(k.bucket)
+	if bucket == nil {
+		return []byte{undoAbsentMarker}, nil
+	}
+
+	value, err := loadValue(tx, bucket.Get(key))
+	if err != nil || value == nil {
+		return []byte{undoAbsentMarker}, err
+	}
+
+	return bytes.Clone(value), nil
+}
+
+// recordUndo keeps the value a key held before its last mutation.
+func (k *KV) recordUndo(tx *bolt.Tx, key, previous []byte) error {
+	bucket, err := tx.CreateBucketIfNotExists(undoBucket(k.bucket))
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put(key, previous)
+}
@@ -0,0 +1,93 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVMutateKeepsUndoableValues(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{UndoPrefix: "undoable:"})
+	dbInstance := kv.db
+
+	set := func(key, value string) error {
+		return kv.mutate(mutation{op: "set", key: []byte(key), value: []byte(value)}, func(tx *bolt.Tx) error {
+			return kv.storeValue(tx, tx.Bucket(kv.bucket), []byte(key), []byte(value))
+		})
+	}
+
+	previous := func(key string) []byte {
+		var value []byte
+
+		require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			if bucket := tx.Bucket(undoBucket(kv.bucket)); bucket != nil {
+				value = bucket.Get([]byte(key))
+			}
+
+			return nil
+		}))
+
+		return value
+	}
+
+	require.NoError(t, set("undoable:foo", `1`))
+	assert.Equal(t, []byte{undoAbsentMarker}, previous("undoable:foo"))
+
+	require.NoError(t, set("undoable:foo", `2`))
+	assert.Equal(t, []byte(`1`), previous("undoable:foo"))
+
+	// Mutations leaving the value unchanged keep the undo record.
+	require.NoError(t, kv.mutate(mutation{op: "setIfAbsent", key: []byte("undoable:foo")}, func(*bolt.Tx) error {
+		return nil
+	}))
+	assert.Equal(t, []byte(`1`), previous("undoable:foo"))
+
+	require.NoError(t, set("undoable:foo", `2`))
+	assert.Equal(t, []byte(`1`), previous("undoable:foo"))
+
+	require.NoError(t, set("other", `1`))
+	require.NoError(t, set("other", `2`))
+	assert.Nil(t, previous("other"))
+}
+
+func TestKVUndo(t *testing.T) {
+	t.Parallel()
+
+	vu := newTestVU(t)
+
+	err := vu.run(`
+		const store = kv.openKv({ undoPrefix: "undoable:", serialization: "binary" });
+
+		// Empty binary values are told apart from absent keys.
+		store.set("undoable:bin", new ArrayBuffer(0))
+			.then(() => store.set("undoable:bin", 1))
+			.then(() => store.undo("undoable:bin"))
+			.then(() => store.get("undoable:bin"))
+			.then((value) => {
+				if (!(value instanceof ArrayBuffer) || value.byteLength !== 0) {
+					throw new Error("expected an empty ArrayBuffer, got " + value);
+				}
+
+				return store.undo("undoable:bin");
+			})
+			.then(() => store.get("undoable:bin"))
+			.then((value) => {
+				if (value !== 1) {
+					throw new Error("expected undoing twice to restore 1, got " + value);
+				}
+			})
+			.then(() => store.set("undoable:new", 1))
+			.then(() => store.undo("undoable:new"))
+			.then(() => store.exists("undoable:new"))
+			.then((exists) => {
+				if (exists) {
+					throw new Error("expected undoing the creation of a key to delete it");
+				}
+			});
+	`)
+	require.NoError(t, err)
+}
@@ -0,0 +1,222 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// keyLocks holds the locks KV.Update takes on keys, shared by all VUs.
+type keyLocks struct {
+	lock sync.Mutex
+	held map[string]*keyLock
+}
+
+// keyLock is the lock of a key, released once its last user is done.
+type keyLock struct {
+	// slot holds a value while the lock is held.
+	slot chan struct{}
+
+	// users is the number of callers holding or waiting for the lock.
+	users int
+}
+
+// acquire waits for the lock of the key to be available, and takes it. It
+// returns the function releasing it, or the context's error if it is done
+// first.
+func (l *keyLocks) acquire(ctx context.Context, key string) (func(), error) {
+	l.lock.Lock()
+	if l.held == nil {
+		l.held = make(map[string]*keyLock)
+	}
+
+	kl, found := l.held[key]
+	if !found {
+		kl = &keyLock{slot: make(chan struct{}, 1)}
+		l.held[key] = kl
+	}
+	kl.users++
+	l.lock.Unlock()
+
+	done := func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		kl.users--
+		if kl.users == 0 {
+			delete(l.held, key)
+		}
+	}
+
+	select {
+	case kl.slot <- struct{}{}:
+		return func() {
+			<-kl.slot
+			done()
+		}, nil
+	case <-ctx.Done():
+		done()
+		return nil, ctx.Err()
+	}
+}
+
+// Update replaces the value of a key with the result of calling fn with its
+// current value, or null if it does not exist, and resolves with the new
+// value. When fn returns undefined, the key is left unchanged.
+//
+// Calls to Update on the same key are serialized across VUs, so that the
+// read-modify-write of shared values is safe against other calls to Update.
+// It is only atomic against them: a write to the key by any other method,
+// such as KV.Set, between the read and the write is overwritten. The
+// function must be synchronous.
+func (k *KV) Update(key sobek.Value, fn sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	if err := k.validateKey(keyBytes); err != nil {
+		reject(err)
+		return promise
+	}
+
+	update, isFunction := sobek.AssertFunction(fn)
+	if !isFunction {
+		reject(fmt.Errorf("update %s: expected a function, got %v", keyBytes, fn))
+		return promise
+	}
+
+	callback := k.vu.RegisterCallback()
+
+	go func() {
+		release, err := k.db.keyLocks.acquire(k.vu.Context(), string(k.bucket)+"\x00"+string(keyBytes))
+		if err != nil {
+			callback(func() error { return nil })
+			reject(err)
+			return
+		}
+
+		current, err := k.currentJSON(keyBytes)
+
+		callback(func() error {
+			if err != nil {
+				release()
+				reject(err)
+				return nil
+			}
+
+			k.applyUpdate(keyBytes, current, update, release, resolve, reject)
+			return nil
+		})
+	}()
+
+	return promise
+}
+
+// currentJSON returns a copy of the JSON value of a visible key of the
+// bucket, or nil if it does not exist.
+func (k *KV) currentJSON(key []byte) ([]byte, error) {
+	var current []byte
+
+	err := k.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(k.bucket)
+		if bucket == nil {
+			return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+		}
+
+		if newVisibility(tx, k.bucket).hidden(key) {
+			return nil
+		}
+
+		value, err := loadValue(tx, bucket.Get(key))
+		current = bytes.Clone(value)
+
+		return err
+	})
+
+	return current, err
+}
+
+// applyUpdate calls the update function with the current value of the key,
+// and writes its result, releasing the key's lock once done.
+//
+// It must be called from the event loop.
+func (k *KV) applyUpdate(
+	key, current []byte, update sobek.Callable, release func(), resolve func(any), reject func(any),
+) {
+	rt := k.vu.Runtime()
+
+	var value any
+	if current != nil {
+		if err := decodeValue(current, &value); err != nil {
+			release()
+			reject(err)
+			return
+		}
+	}
+
+	revived, err := k.revive(key, value)
+	if err != nil {
+		release()
+		reject(err)
+		return
+	}
+
+	result, err := update(sobek.Undefined(), rt.ToValue(revived))
+	if err != nil {
+		release()
+		reject(err)
+		return
+	}
+
+	if _, isPromise := result.Export().(*sobek.Promise); isPromise {
+		release()
+		reject(fmt.Errorf("update %s: the function must be synchronous, but returned a promise", key))
+		return
+	}
+
+	if sobek.IsUndefined(result) {
+		release()
+		resolve(revived)
+		return
+	}
+
+	jsonValue, err := k.marshal(key, result)
+	if err != nil {
+		release()
+		reject(err)
+		return
+	}
+
+	go func() {
+		defer release()
+
+		err := k.mutate(mutation{op: "update", key: key, value: jsonValue}, func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			if err := undelay(tx, k.bucket, key); err != nil {
+				return err
+			}
+
+			return k.storeValue(tx, bucket, key, jsonValue)
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(result)
+	}()
+}
@@ -0,0 +1,56 @@
+package kv
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyLocks(t *testing.T) {
+	t.Parallel()
+
+	var locks keyLocks
+
+	t.Run("holders of a key's lock are serialized", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			wg      sync.WaitGroup
+			counter int
+		)
+
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				release, err := locks.acquire(context.Background(), "counter")
+				require.NoError(t, err)
+				defer release()
+
+				current := counter
+				counter = current + 1
+			}()
+		}
+
+		wg.Wait()
+		assert.Equal(t, 50, counter)
+	})
+
+	t.Run("waiting for a key's lock stops with the context", func(t *testing.T) {
+		t.Parallel()
+
+		release, err := locks.acquire(context.Background(), "held")
+		require.NoError(t, err)
+		defer release()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = locks.acquire(ctx, "held")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,222 @@
+package kv
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/promises"
+)
+
+// DiskUsage describes the space the store's file takes on disk, as returned
+// by KV.DiskUsage().
+type DiskUsage struct {
+	// FileSize is the size of the store's file, in bytes.
+	FileSize int64 `json:"fileSize" js:"fileSize"`
+
+	// DataSize is the size of the pages in use, in bytes.
+	DataSize int64 `json:"dataSize" js:"dataSize"`
+
+	// PageSize is the size of a page of the store's file, in bytes.
+	PageSize int `json:"pageSize" js:"pageSize"`
+
+	// FreePages is the number of pages free to be reused.
+	FreePages int `json:"freePages" js:"freePages"`
+
+	// PendingPages is the number of pages which become free once the
+	// transactions reading them end.
+	PendingPages int `json:"pendingPages" js:"pendingPages"`
+
+	// FreeBytes is the size of the free pages, in bytes, which KV.Compact
+	// returns to the file system.
+	FreeBytes int `json:"freeBytes" js:"freeBytes"`
+}
+
+// KeySize is the size of the value of a key, as returned by KV.TopKeysBySize().
+type KeySize struct {
+	Key  string `json:"key" js:"key"`
+	Size int64  `json:"size" js:"size"`
+}
+
+// DiskUsage resolves with the space the store's file takes on disk, and how
+// much of it is free.
+func (k *KV) DiskUsage() *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	go func() {
+		var usage DiskUsage
+
+		err := k.view(func(tx *bolt.Tx) error {
+			info, err := os.Stat(k.db.handle.Path()) //nolint:forbidigo
+			if err != nil {
+				return err
+			}
+
+			stats := k.db.handle.Stats()
+			usage = DiskUsage{
+				FileSize:     info.Size(),
+				DataSize:     tx.Size(),
+				PageSize:     k.db.handle.Info().PageSize,
+				FreePages:    stats.FreePageN,
+				PendingPages: stats.PendingPageN,
+				FreeBytes:    stats.FreeAlloc,
+			}
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(usage)
+	}()
+
+	return promise
+}
+
+// SizeOf resolves with the size of the encoded value of a key, in bytes, as
+// counted by the maxValueSize option, or null if the key does not exist.
+func (k *KV) SizeOf(key sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	keyBytes, err := common.ToBytes(key.Export())
+	if err != nil {
+		reject(err)
+		return promise
+	}
+
+	go func() {
+		var size any
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			if newVisibility(tx, k.bucket).hidden(keyBytes) {
+				return nil
+			}
+
+			value, err := loadValue(tx, bucket.Get(keyBytes))
+			if err != nil || value == nil {
+				return err
+			}
+
+			size = len(value)
+
+			return nil
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(size)
+	}()
+
+	return promise
+}
+
+// TopKeysBySize resolves with the n keys starting with the prefix option, or
+// any keys, whose encoded values are the largest, from the largest down.
+func (k *KV) TopKeysBySize(n sobek.Value, options sobek.Value) *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	size := n.ToInteger()
+	if size <= 0 {
+		reject(fmt.Errorf("the number of keys to rank must be positive, got %d", size))
+		return promise
+	}
+
+	prefix := k.prefixOption(options)
+
+	go func() {
+		var top []KeySize
+
+		err := k.view(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(k.bucket)
+			if bucket == nil {
+				return NewError(BucketNotFoundError, "bucket "+string(k.bucket)+" not found")
+			}
+
+			var err error
+			top, err = largestKeys(tx, bucket, newVisibility(tx, k.bucket), prefix, int(size))
+
+			return err
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(top)
+	}()
+
+	return promise
+}
+
+// largestKeys returns the n visible keys of the bucket starting with the
+// prefix whose encoded values are the largest, from the largest down.
+//
+// Only the n largest keys seen so far are kept, in a min-heap, so that any
+// number of keys can be ranked.
+func largestKeys(tx *bolt.Tx, bucket *bolt.Bucket, visible visibility, prefix []byte, n int) ([]KeySize, error) {
+	top := keySizeHeap{}
+
+	cursor := bucket.Cursor()
+	for key, raw := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, raw = cursor.Next() {
+		if visible.hidden(key) {
+			continue
+		}
+
+		value, err := loadValue(tx, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		size := int64(len(value))
+		if len(top) == n && size <= top[0].Size {
+			continue
+		}
+
+		entry := KeySize{Key: string(key), Size: size}
+		if len(top) < n {
+			heap.Push(&top, entry)
+		} else {
+			top[0] = entry
+			heap.Fix(&top, 0)
+		}
+	}
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Size != top[j].Size {
+			return top[i].Size > top[j].Size
+		}
+
+		return top[i].Key < top[j].Key
+	})
+
+	return top, nil
+}
+
+// keySizeHeap is a min-heap of key sizes.
+type keySizeHeap []KeySize
+
+func (h keySizeHeap) Len() int           { return len(h) }
+func (h keySizeHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h keySizeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *keySizeHeap) Push(x any)        { *h = append(*h, x.(KeySize)) } //nolint:forcetypeassert
+func (h *keySizeHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return last
+}
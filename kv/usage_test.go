@@ -0,0 +1,38 @@
+package kv
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestLargestKeys(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+		for i, size := range []int{5, 50, 20, 100, 1, 50} {
+			value := `"` + strings.Repeat("x", size) + `"`
+			require.NoError(t, bucket.Put([]byte(fmt.Sprintf("blob:%d", i)), []byte(value)))
+		}
+		require.NoError(t, bucket.Put([]byte("other"), []byte(`"`+strings.Repeat("x", 1000)+`"`)))
+
+		top, err := largestKeys(tx, bucket, newVisibility(tx, kv.bucket), []byte("blob:"), 3)
+		require.NoError(t, err)
+		assert.Equal(t, []KeySize{{"blob:3", 102}, {"blob:1", 52}, {"blob:5", 52}}, top)
+
+		top, err = largestKeys(tx, bucket, newVisibility(tx, kv.bucket), nil, 10)
+		require.NoError(t, err)
+		assert.Len(t, top, 7)
+		assert.Equal(t, KeySize{"other", 1002}, top[0])
+
+		return nil
+	}))
+}
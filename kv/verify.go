@@ -0,0 +1,207 @@
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"runtime/debug"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openStore opens the store's file, verifying its integrity first when the
+// Verify or Repair options are set.
+//
+// A corrupted store is either repaired, when the Repair option is set, or
+// reported with a CorruptedStoreError. Stores opened in the sharedReadOnly
+// mode are opened read-only, and are never repaired.
+//
+// Neither are stores whose file cannot be opened at all, such as when both
+// of its meta pages are corrupted: bolt needs them to find its entries, so
+// nothing could be salvaged, and they are reported with a CorruptedStoreError.
+func openStore(path string, options Options) (*bolt.DB, error) {
+	if options.Mode == ModeSharedReadOnly {
+		handle, err := openFile(path, 0o400, true, options)
+		if err != nil || !options.Verify {
+			return handle, err
+		}
+
+		if err := verify(handle); err != nil {
+			_ = handle.Close()
+			return nil, err
+		}
+
+		return handle, nil
+	}
+
+	handle, err := openFile(path, 0o600, false, options)
+	if !options.Verify && !options.Repair {
+		return handle, err
+	}
+
+	if err != nil {
+		if errors.Is(err, bolt.ErrInvalid) || errors.Is(err, bolt.ErrChecksum) {
+			return nil, NewError(CorruptedStoreError, "store "+path+" cannot be opened, nor repaired: "+err.Error())
+		}
+
+		return nil, err
+	}
+
+	verifyErr := verify(handle)
+	if verifyErr == nil {
+		return handle, nil
+	}
+
+	if closeErr := handle.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if !options.Repair {
+		return nil, verifyErr
+	}
+
+	if err := repair(path); err != nil {
+		return nil, err
+	}
+
+	return openFile(path, 0o600, false, options)
+}
+
+// verify checks the integrity of the store, by checking that its file holds
+// all of its pages, and by reading every entry of every bucket, and every
+// byte of their values, and returns a CorruptedStoreError if any of them
+// cannot be read.
+//
+// Bolt's own consistency check is not used, as it runs in a goroutine of its
+// own, where the panics corrupted pages cause cannot be recovered from.
+func verify(handle *bolt.DB) (err error) {
+	// Reading pages missing from a truncated file faults, rather than
+	// panicking, unless told otherwise.
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+
+	// Corrupted pages make bolt panic, rather than report them.
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewError(CorruptedStoreError, fmt.Sprintf("store %s is corrupted: %v", handle.Path(), r))
+		}
+	}()
+
+	return handle.View(func(tx *bolt.Tx) error {
+		info, err := os.Stat(handle.Path()) //nolint:forbidigo
+		if err != nil {
+			return err
+		}
+
+		// Pages past the end of a truncated file are not always reported
+		// when read, as the memory following the file's may be mapped.
+		if info.Size() < tx.Size() {
+			return NewError(CorruptedStoreError, fmt.Sprintf(
+				"store %s is truncated: its file holds %d bytes out of %d", handle.Path(), info.Size(), tx.Size(),
+			))
+		}
+
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			return walk(bucket)
+		})
+	})
+}
+
+// walk reads every entry of the bucket and of its nested buckets.
+func walk(bucket *bolt.Bucket) error {
+	return bucket.ForEach(func(k, v []byte) error {
+		if v != nil {
+			// Looking the entry up only reads the first page of its value,
+			// so read the rest of it, which may span overflow pages.
+			_ = crc32.ChecksumIEEE(v)
+
+			return nil
+		}
+
+		return walk(bucket.Bucket(k))
+	})
+}
+
+// repair replaces the store's file with a fresh one, holding the entries
+// which could be read from it. The corrupted file is kept alongside it,
+// suffixed with ".corrupted".
+//
+//nolint:forbidigo
+func repair(path string) error {
+	source, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return NewError(CorruptedStoreError, "store "+path+" cannot be repaired: "+err.Error())
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	repairedPath := path + ".repaired"
+	_ = os.Remove(repairedPath)
+
+	target, err := bolt.Open(repairedPath, 0o600, nil)
+	if err != nil {
+		return err
+	}
+
+	err = source.View(func(src *bolt.Tx) error {
+		return target.Update(func(dst *bolt.Tx) error {
+			return src.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+				copied, err := dst.CreateBucketIfNotExists(name)
+				if err != nil {
+					return err
+				}
+
+				salvage(bucket, copied)
+
+				return nil
+			})
+		})
+	})
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to repair store %s: %w", path, err)
+	}
+
+	if err := source.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(path, path+".corrupted"); err != nil {
+		return err
+	}
+
+	return os.Rename(repairedPath, path)
+}
+
+// salvage copies the sequence, entries and nested buckets of the source
+// bucket which can be read to the target bucket, skipping the corrupted ones.
+func salvage(source, target *bolt.Bucket) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+
+	// Stop copying the bucket once a corrupted page makes bolt panic.
+	defer func() {
+		_ = recover()
+	}()
+
+	// Keep the sequence of the bucket, so that queues and sequences carry
+	// on from where they were, rather than reusing their numbers.
+	_ = target.SetSequence(source.Sequence())
+
+	_ = source.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return target.Put(k, v)
+		}
+
+		nested, err := target.CreateBucketIfNotExists(k)
+		if err != nil {
+			return err
+		}
+
+		salvage(source.Bucket(k), nested)
+
+		return nil
+	})
+}
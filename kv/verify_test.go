@@ -0,0 +1,227 @@
+package kv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestOpenStore(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	// populate creates a store holding a thousand keys, and a nested bucket.
+	populate := func(t *testing.T, path string) {
+		t.Helper()
+
+		handle, err := bolt.Open(path, 0o600, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, handle.Update(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucket([]byte(DefaultKvBucket))
+			require.NoError(t, err)
+
+			for i := 0; i < 1000; i++ {
+				require.NoError(t, bucket.Put([]byte("key"+strconv.Itoa(i)), []byte(strconv.Itoa(i))))
+			}
+
+			nested, err := bucket.CreateBucket([]byte("nested"))
+			require.NoError(t, err)
+
+			return nested.Put([]byte("foo"), []byte(`"bar"`))
+		}))
+		require.NoError(t, handle.Close())
+	}
+
+	t.Run("healthy store is opened", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, "healthy.db")
+		populate(t, path)
+
+		handle, err := openStore(path, Options{Verify: true})
+		require.NoError(t, err)
+		assert.NoError(t, handle.Close())
+	})
+
+	t.Run("corrupted store is reported", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, "corrupted.db")
+		populate(t, path)
+		corrupt(t, path)
+
+		_, err := openStore(path, Options{Verify: true})
+
+		var kvErr *Error
+		require.True(t, errors.As(err, &kvErr))
+		assert.Equal(t, ErrorName(CorruptedStoreError), kvErr.Name)
+
+		handle, err := openStore(path, Options{Repair: true})
+		require.NoError(t, err)
+		assert.NoError(t, verify(handle))
+		assert.NoError(t, handle.Close())
+	})
+
+	t.Run("truncated values are reported", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, "truncated.db")
+
+		// Write a value spanning overflow pages last, once the pages freed
+		// by the previous writes can hold the pages written after it.
+		handle, err := bolt.Open(path, 0o600, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, handle.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucket([]byte(DefaultKvBucket))
+			return err
+		}))
+		for i := 0; i < 3; i++ {
+			require.NoError(t, handle.Update(func(tx *bolt.Tx) error {
+				return tx.Bucket([]byte(DefaultKvBucket)).Put([]byte("small"), []byte(strconv.Itoa(i)))
+			}))
+		}
+		require.NoError(t, handle.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte(DefaultKvBucket)).Put([]byte("large"), make([]byte, 64*handle.Info().PageSize))
+		}))
+
+		var leaf uint64
+		require.NoError(t, handle.View(func(tx *bolt.Tx) error {
+			leaf = uint64(tx.Bucket([]byte(DefaultKvBucket)).Root())
+			return nil
+		}))
+		pageSize := handle.Info().PageSize
+		require.NoError(t, handle.Close())
+
+		// Cut the file short after the first pages of the value, which its
+		// entry can still be read from.
+		require.NoError(t, os.Truncate(path, int64(leaf+2)*int64(pageSize))) //nolint:forbidigo
+
+		// Stores opened for writing grow their file back, filling the missing
+		// pages with zeroes.
+		_, err = openStore(path, Options{Verify: true, Mode: ModeSharedReadOnly})
+
+		var kvErr *Error
+		require.True(t, errors.As(err, &kvErr))
+		assert.Equal(t, ErrorName(CorruptedStoreError), kvErr.Name)
+	})
+
+	t.Run("stores which cannot be opened are not repaired", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, "unopenable.db")
+		populate(t, path)
+
+		// Overwrite both meta pages.
+		file, err := os.OpenFile(path, os.O_WRONLY, 0o600) //nolint:forbidigo
+		require.NoError(t, err)
+		_, err = file.WriteAt(make([]byte, 2*os.Getpagesize()), 0)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		_, err = openStore(path, Options{Repair: true})
+
+		var kvErr *Error
+		require.True(t, errors.As(err, &kvErr))
+		assert.Equal(t, ErrorName(CorruptedStoreError), kvErr.Name)
+		assert.NoFileExists(t, path+".corrupted")
+	})
+
+	t.Run("repair copies readable entries", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, "repaired.db")
+		populate(t, path)
+
+		require.NoError(t, repair(path))
+		assert.FileExists(t, path+".corrupted")
+
+		handle, err := openStore(path, Options{Verify: true})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, handle.Close())
+		})
+
+		assert.NoError(t, handle.View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(DefaultKvBucket))
+			require.NotNil(t, bucket)
+			assert.Equal(t, []byte("42"), bucket.Get([]byte("key42")))
+			assert.Equal(t, []byte(`"bar"`), bucket.Bucket([]byte("nested")).Get([]byte("foo")))
+
+			return nil
+		}))
+	})
+
+	t.Run("repair keeps the sequences of buckets", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(tmpDir, "sequences.db")
+		populate(t, path)
+
+		handle, err := bolt.Open(path, 0o600, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, handle.Update(func(tx *bolt.Tx) error {
+			for i := 0; i < 3; i++ {
+				if _, err := nextSequence(tx, []byte("orders")); err != nil {
+					return err
+				}
+
+				if _, err := pushQueue(tx, []byte("jobs"), []byte(strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}))
+		require.NoError(t, handle.Close())
+
+		require.NoError(t, repair(path))
+
+		handle, err = openStore(path, Options{Verify: true})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, handle.Close())
+		})
+
+		assert.NoError(t, handle.Update(func(tx *bolt.Tx) error {
+			next, err := nextSequence(tx, []byte("orders"))
+			require.NoError(t, err)
+			assert.Equal(t, uint64(4), next)
+
+			length, err := pushQueue(tx, []byte("jobs"), []byte("3"))
+			require.NoError(t, err)
+			assert.Equal(t, int64(4), length)
+
+			return nil
+		}))
+	})
+}
+
+// corrupt overwrites the pages following the store's meta pages with garbage.
+//
+//nolint:forbidigo
+func corrupt(t *testing.T, path string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+
+	pageSize := int64(os.Getpagesize())
+	garbage := make([]byte, 4*pageSize)
+	for i := range garbage {
+		garbage[i] = 0xff
+	}
+
+	_, err = file.WriteAt(garbage, 2*pageSize)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
@@ -0,0 +1,108 @@
+package kv
+
+import (
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// versionsBucket returns the name of the internal bucket holding the
+// versions of the keys of the given bucket, along with the times they
+// were created and last updated at.
+//
+// Versions are drawn from the bucket's sequence, so that every write of a
+// key of the bucket gives it a version greater than any before.
+func versionsBucket(bucket []byte) []byte {
+	return []byte(string(bucket) + "/versions")
+}
+
+// keyMetadata is the metadata kept for each key in the versions bucket.
+//
+// It is encoded as the 8-byte big-endian version, followed by the creation
+// and update times, as 8-byte big-endian Unix times in milliseconds.
+type keyMetadata struct {
+	version   uint64
+	createdAt int64
+	updatedAt int64
+}
+
+// encode returns the encoded form of the metadata.
+func (m keyMetadata) encode() []byte {
+	encoded := make([]byte, 24)
+	binary.BigEndian.PutUint64(encoded, m.version)
+	binary.BigEndian.PutUint64(encoded[8:], uint64(m.createdAt))
+	binary.BigEndian.PutUint64(encoded[16:], uint64(m.updatedAt))
+
+	return encoded
+}
+
+// decodeKeyMetadata decodes the metadata of a key. Entries written before
+// the times were tracked only hold the version.
+func decodeKeyMetadata(encoded []byte) keyMetadata {
+	var m keyMetadata
+
+	if len(encoded) >= 8 {
+		m.version = binary.BigEndian.Uint64(encoded)
+	}
+
+	if len(encoded) >= 24 {
+		m.createdAt = int64(binary.BigEndian.Uint64(encoded[8:]))
+		m.updatedAt = int64(binary.BigEndian.Uint64(encoded[16:]))
+	}
+
+	return m
+}
+
+// bumpVersion gives the key a new version, and records the time it was
+// updated at, as well as created at if it is new. It returns the version.
+func bumpVersion(tx *bolt.Tx, bucket []byte, key []byte) (uint64, error) {
+	versions, err := tx.CreateBucketIfNotExists(versionsBucket(bucket))
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := versions.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UnixMilli()
+
+	m := decodeKeyMetadata(versions.Get(key))
+	if m.createdAt == 0 {
+		m.createdAt = now
+	}
+
+	m.version = version
+	m.updatedAt = now
+
+	return version, versions.Put(key, m.encode())
+}
+
+// dropVersion removes the version of a deleted key.
+func dropVersion(tx *bolt.Tx, bucket []byte, key []byte) error {
+	versions := tx.Bucket(versionsBucket(bucket))
+	if versions == nil {
+		return nil
+	}
+
+	return versions.Delete(key)
+}
+
+// metadataOf returns the metadata of an existing key. Keys written before
+// versions were tracked have the version 0, and unknown times.
+func metadataOf(tx *bolt.Tx, bucket []byte, key []byte) keyMetadata {
+	versions := tx.Bucket(versionsBucket(bucket))
+	if versions == nil {
+		return keyMetadata{}
+	}
+
+	return decodeKeyMetadata(versions.Get(key))
+}
+
+// versionOf returns the version of an existing key. Keys written before
+// versions were tracked have the version 0.
+func versionOf(tx *bolt.Tx, bucket []byte, key []byte) uint64 {
+	return metadataOf(tx, bucket, key).version
+}
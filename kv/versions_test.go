@@ -0,0 +1,40 @@
+package kv
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKeyMetadata(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("a"), []byte(`1`)))
+		created := metadataOf(tx, kv.bucket, []byte("a"))
+		assert.NotZero(t, created.createdAt)
+		assert.Equal(t, created.createdAt, created.updatedAt)
+
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("a"), []byte(`2`)))
+		updated := metadataOf(tx, kv.bucket, []byte("a"))
+		assert.Greater(t, updated.version, created.version)
+		assert.Equal(t, created.createdAt, updated.createdAt)
+		assert.GreaterOrEqual(t, updated.updatedAt, created.updatedAt)
+
+		assert.Equal(t, keyMetadata{}, metadataOf(tx, kv.bucket, []byte("missing")))
+
+		return nil
+	}))
+
+	legacy := make([]byte, 8)
+	binary.BigEndian.PutUint64(legacy, 42)
+	assert.Equal(t, keyMetadata{version: 42}, decodeKeyMetadata(legacy))
+}
@@ -0,0 +1,67 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+)
+
+// pollInterval is the interval at which coordination primitives
+// check the store for a state change.
+const pollInterval = 10 * time.Millisecond
+
+// pollUntil calls cond every pollInterval until it reports true.
+//
+// It returns a TimeoutError if a non-zero timeout elapses first,
+// and the context's error if ctx is done first.
+func pollUntil(ctx context.Context, timeout time.Duration, cond func() (bool, error)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		done, err := cond()
+		if err != nil {
+			return err
+		}
+
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return NewError(TimeoutError, "timed out after "+timeout.String())
+			}
+
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// toDuration converts a JS value, either a number of milliseconds or a
+// duration string such as "30s", to a time.Duration.
+//
+// Nullish values convert to a zero duration.
+func toDuration(v sobek.Value) (time.Duration, error) {
+	if common.IsNullish(v) {
+		return 0, nil
+	}
+
+	return types.GetDurationValue(v.Export())
+}
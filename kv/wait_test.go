@@ -0,0 +1,64 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollUntil(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns once the condition is met", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		gotErr := pollUntil(context.Background(), time.Second, func() (bool, error) {
+			calls++
+			return calls == 3, nil
+		})
+
+		assert.NoError(t, gotErr)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("returns a TimeoutError when the timeout elapses", func(t *testing.T) {
+		t.Parallel()
+
+		gotErr := pollUntil(context.Background(), 3*pollInterval, func() (bool, error) {
+			return false, nil
+		})
+
+		var kvErr *Error
+		require.ErrorAs(t, gotErr, &kvErr)
+		assert.Equal(t, ErrorName(TimeoutError), kvErr.Name)
+	})
+
+	t.Run("returns the condition's error", func(t *testing.T) {
+		t.Parallel()
+
+		wantErr := errors.New("boom")
+		gotErr := pollUntil(context.Background(), 0, func() (bool, error) {
+			return false, wantErr
+		})
+
+		assert.ErrorIs(t, gotErr, wantErr)
+	})
+
+	t.Run("returns the context's error when it is canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		gotErr := pollUntil(ctx, 0, func() (bool, error) {
+			return false, nil
+		})
+
+		assert.ErrorIs(t, gotErr, context.Canceled)
+	})
+}
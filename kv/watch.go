@@ -0,0 +1,283 @@
+package kv
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/common"
+)
+
+// The types of the events reported to watchers.
+const (
+	// WatchEventSet reports that a key was set.
+	WatchEventSet = "set"
+
+	// WatchEventDelete reports that a key was deleted.
+	WatchEventDelete = "delete"
+
+	// WatchEventClear reports that all the keys of the bucket were deleted.
+	WatchEventClear = "clear"
+)
+
+// WatchEvent is a change of the store's keys, as reported to the callback of KV.Watch.
+type WatchEvent struct {
+	// Type is the type of the change: WatchEventSet, WatchEventDelete
+	// or WatchEventClear.
+	Type string `json:"type" js:"type"`
+
+	// Key is the key which changed, if any.
+	Key string `json:"key,omitempty" js:"key"`
+
+	// Value is the value the key was set to, if any.
+	Value any `json:"value,omitempty" js:"value"`
+
+	// jsonValue is the JSON-encoded value, decoded on the watching VU's event loop.
+	jsonValue []byte
+}
+
+// watcherRegistry holds the watchers of the keys of a store, which the
+// changes committed by any VU are reported to.
+type watcherRegistry struct {
+	lock     sync.RWMutex
+	watchers map[*watcher]struct{}
+
+	// count is the number of watchers, so that changes are not tracked
+	// when there are none.
+	count atomic.Int64
+}
+
+// watcher is a registration of KV.Watch.
+type watcher struct {
+	bucket string
+	prefix string
+
+	// queue holds the events not delivered yet, and signal is notified
+	// when events are queued.
+	lock   sync.Mutex
+	queue  []WatchEvent
+	signal chan struct{}
+
+	// done is closed when the watcher is stopped.
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// add registers a watcher.
+func (r *watcherRegistry) add(w *watcher) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.watchers == nil {
+		r.watchers = make(map[*watcher]struct{})
+	}
+
+	r.watchers[w] = struct{}{}
+	r.count.Add(1)
+}
+
+// remove unregisters a watcher.
+func (r *watcherRegistry) remove(w *watcher) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, found := r.watchers[w]; found {
+		delete(r.watchers, w)
+		r.count.Add(-1)
+	}
+}
+
+// publish queues an event for the watchers of the bucket it matches.
+func (r *watcherRegistry) publish(bucket string, event WatchEvent) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for w := range r.watchers {
+		if w.bucket != bucket || (event.Type != WatchEventClear && !strings.HasPrefix(event.Key, w.prefix)) {
+			continue
+		}
+
+		w.lock.Lock()
+		w.queue = append(w.queue, event)
+		w.lock.Unlock()
+
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// notifyChange reports a change of a key of the bucket to its watchers,
+// once the transaction is committed. A nil value reports a deletion.
+func (k *KV) notifyChange(tx *bolt.Tx, key, value []byte) {
+	if k.db.watchers.count.Load() == 0 {
+		return
+	}
+
+	event := WatchEvent{Type: WatchEventDelete, Key: string(key)}
+	if value != nil {
+		event.Type = WatchEventSet
+		event.jsonValue = bytes.Clone(value)
+	}
+
+	tx.OnCommit(func() {
+		k.db.watchers.publish(string(k.bucket), event)
+	})
+}
+
+// notifyClear reports the deletion of all the keys of the bucket to its
+// watchers, once the transaction is committed.
+func (k *KV) notifyClear(tx *bolt.Tx, bucket []byte) {
+	if k.db.watchers.count.Load() == 0 {
+		return
+	}
+
+	tx.OnCommit(func() {
+		k.db.watchers.publish(string(bucket), WatchEvent{Type: WatchEventClear})
+	})
+}
+
+// Watch calls the callback with a WatchEvent whenever any VU sets or
+// deletes a key starting with the given prefix, or clears the store. An
+// exact key can be watched by passing it as the prefix. Keys deleted
+// because their TTL elapsed are not reported.
+//
+// Events are delivered on the VU's event loop, in the order the changes were
+// committed. The returned Watcher must be stopped for the VU's iteration to
+// complete.
+func (k *KV) Watch(prefix sobek.Value, callback sobek.Value) *Watcher {
+	rt := k.vu.Runtime()
+
+	fn, isFunction := sobek.AssertFunction(callback)
+	if !isFunction {
+		common.Throw(rt, fmt.Errorf("watch expects a callback function, got %v", callback))
+		return nil
+	}
+
+	w := &watcher{bucket: string(k.bucket), signal: make(chan struct{}, 1), done: make(chan struct{})}
+	if !common.IsNullish(prefix) {
+		w.prefix = prefix.String()
+	}
+
+	k.db.watchers.add(w)
+	go k.deliverEvents(w, fn, k.vu.RegisterCallback())
+
+	return &Watcher{kv: k, watcher: w}
+}
+
+// deliverEvents delivers the events queued for the watcher to the callback,
+// on the VU's event loop, until the watcher is stopped.
+//
+// A callback is kept registered with the event loop while the watcher is
+// running, so that the VU waits for the events.
+func (k *KV) deliverEvents(w *watcher, fn sobek.Callable, callback func(func() error)) {
+	defer k.db.watchers.remove(w)
+
+	for {
+		select {
+		case <-w.signal:
+		case <-w.done:
+			callback(func() error { return nil })
+			return
+		case <-k.vu.Context().Done():
+			w.stop()
+			callback(func() error { return nil })
+			return
+		}
+
+		w.lock.Lock()
+		events := w.queue
+		w.queue = nil
+		w.lock.Unlock()
+
+		registered := make(chan func(func() error), 1)
+		callback(func() error {
+			defer close(registered)
+
+			select {
+			case <-w.done:
+				return nil
+			default:
+			}
+
+			if err := k.callWatcher(fn, events); err != nil {
+				w.stop()
+				return err
+			}
+
+			select {
+			case <-w.done:
+			default:
+				registered <- k.vu.RegisterCallback()
+			}
+
+			return nil
+		})
+
+		select {
+		case next, ok := <-registered:
+			if !ok {
+				return
+			}
+
+			callback = next
+		case <-k.vu.Context().Done():
+			w.stop()
+			return
+		}
+	}
+}
+
+// callWatcher calls the watcher's callback with each of the events.
+//
+// It must be called from the event loop.
+func (k *KV) callWatcher(fn sobek.Callable, events []WatchEvent) error {
+	rt := k.vu.Runtime()
+
+	for _, event := range events {
+		if event.jsonValue != nil {
+			var value any
+			if err := decodeValue(event.jsonValue, &value); err != nil {
+				return err
+			}
+
+			revived, err := k.revive([]byte(event.Key), value)
+			if err != nil {
+				return err
+			}
+
+			event.Value = revived
+		}
+
+		if _, err := fn(sobek.Undefined(), rt.ToValue(event)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stop stops delivering events to the watcher.
+func (w *watcher) stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+// Watcher is a handle on a watch started with KV.Watch.
+type Watcher struct {
+	kv      *KV
+	watcher *watcher
+}
+
+// Stop stops delivering events to the watcher's callback. Events not
+// delivered yet are dropped.
+func (w *Watcher) Stop() {
+	w.watcher.stop()
+	w.kv.db.watchers.remove(w.watcher)
+}
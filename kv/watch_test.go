@@ -0,0 +1,53 @@
+package kv
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestWatcherRegistry(t *testing.T) {
+	t.Parallel()
+
+	kv := openTestKV(t, Options{})
+	dbInstance := kv.db
+
+	sessions := &watcher{bucket: DefaultKvBucket, prefix: "session:", signal: make(chan struct{}, 1)}
+	others := &watcher{bucket: "others", signal: make(chan struct{}, 1)}
+	dbInstance.watchers.add(sessions)
+	dbInstance.watchers.add(others)
+
+	require.NoError(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(kv.bucket)
+
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("session:1"), []byte(`"a"`)))
+		require.NoError(t, kv.storeValue(tx, bucket, []byte("user:1"), []byte(`"b"`)))
+		require.NoError(t, kv.removeValue(tx, bucket, []byte("session:1")))
+
+		// Events are only published once the transaction is committed.
+		assert.Empty(t, sessions.queue)
+
+		return nil
+	}))
+
+	// Changes rolled back are not published.
+	rollback := errors.New("rollback")
+	require.ErrorIs(t, dbInstance.handle.Update(func(tx *bolt.Tx) error {
+		require.NoError(t, kv.storeValue(tx, tx.Bucket(kv.bucket), []byte("session:2"), []byte(`"c"`)))
+		return rollback
+	}), rollback)
+
+	assert.Equal(t, []WatchEvent{
+		{Type: WatchEventSet, Key: "session:1", jsonValue: []byte(`"a"`)},
+		{Type: WatchEventDelete, Key: "session:1"},
+	}, sessions.queue)
+	assert.Len(t, sessions.signal, 1)
+	assert.Empty(t, others.queue)
+
+	dbInstance.watchers.remove(sessions)
+	dbInstance.watchers.remove(others)
+	assert.Zero(t, dbInstance.watchers.count.Load())
+}
@@ -0,0 +1,100 @@
+package kv
+
+import "sync"
+
+// workerPool runs the operations of the KV instances on a fixed number of
+// long-lived goroutines, rather than on a new goroutine each.
+//
+// Operations are queued while all the workers are busy, so that the number
+// of goroutines running them is bounded, without ever blocking the callers.
+//
+// A nil workerPool runs each operation on a new goroutine.
+type workerPool struct {
+	lock sync.Mutex
+
+	// ready is signaled when an operation is queued, or the pool is stopped.
+	ready *sync.Cond
+
+	// queue holds the operations waiting for a worker, in order.
+	queue []func()
+
+	// stopped is true once the pool is stopped.
+	stopped bool
+}
+
+// newWorkerPool starts a workerPool of the given number of workers, or
+// returns nil if it is zero.
+func newWorkerPool(workers int64) *workerPool {
+	if workers <= 0 {
+		return nil
+	}
+
+	pool := &workerPool{}
+	pool.ready = sync.NewCond(&pool.lock)
+
+	for i := int64(0); i < workers; i++ {
+		go pool.work()
+	}
+
+	return pool
+}
+
+// work runs the queued operations, until the pool is stopped and its
+// queue is drained.
+func (p *workerPool) work() {
+	for {
+		p.lock.Lock()
+		for len(p.queue) == 0 && !p.stopped {
+			p.ready.Wait()
+		}
+
+		if len(p.queue) == 0 {
+			p.lock.Unlock()
+			return
+		}
+
+		task := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.lock.Unlock()
+
+		task()
+	}
+}
+
+// run queues fn, to be run by the next idle worker.
+//
+// Operations waiting on others, such as KV.Barrier, must not be run by the
+// pool, as they would hold a worker for as long as they wait.
+func (p *workerPool) run(fn func()) {
+	if p == nil {
+		go fn()
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Operations started once the pool is stopped still settle, rather
+	// than waiting for a worker forever.
+	if p.stopped {
+		go fn()
+		return
+	}
+
+	p.queue = append(p.queue, fn)
+	p.ready.Signal()
+}
+
+// stop stops the workers once they ran the queued operations.
+func (p *workerPool) stop() {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.stopped = true
+	p.ready.Broadcast()
+}
@@ -0,0 +1,62 @@
+package kv
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool(t *testing.T) {
+	t.Parallel()
+
+	for _, pool := range []*workerPool{nil, newWorkerPool(2)} {
+		const tasks = 100
+
+		var (
+			wg      sync.WaitGroup
+			ran     atomic.Int64
+			running atomic.Int64
+			peak    atomic.Int64
+		)
+
+		block := make(chan struct{})
+
+		wg.Add(tasks)
+		for i := 0; i < tasks; i++ {
+			// Tasks never wait for a worker, even while all of them are busy.
+			pool.run(func() {
+				defer wg.Done()
+
+				current := running.Add(1)
+				defer running.Add(-1)
+
+				for {
+					seen := peak.Load()
+					if current <= seen || peak.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+
+				ran.Add(1)
+				<-block
+			})
+		}
+		close(block)
+		wg.Wait()
+
+		assert.Equal(t, int64(tasks), ran.Load())
+		if pool != nil {
+			// Tasks queued while the workers are busy run on them.
+			assert.LessOrEqual(t, peak.Load(), int64(2))
+		}
+
+		pool.stop()
+
+		// Tasks run once the pool is stopped still complete.
+		done := make(chan struct{})
+		pool.run(func() { close(done) })
+		<-done
+	}
+}
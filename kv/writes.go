@@ -0,0 +1,228 @@
+package kv
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/sobek"
+	bolt "go.etcd.io/bbolt"
+	"go.k6.io/k6/js/promises"
+)
+
+// writeBuffer holds the writes of KV.Set and KV.Delete which are yet to be
+// applied to the store, when the store is opened with the FlushInterval
+// option. Buffered writes are applied together, within a single
+// transaction, when the buffer is flushed.
+type writeBuffer struct {
+	// mu protects pending, keys and failed.
+	mu      sync.Mutex
+	pending []func(tx *bolt.Tx) error
+	failed  []error
+
+	// keys are the keys of the pending writes, by bucket, so that reads
+	// only flush the buffer when they could see one of them.
+	keys map[string]map[string]bool
+
+	// buffered is the number of pending writes, so that flushing an empty
+	// buffer, as every operation does, takes no lock.
+	buffered atomic.Int64
+
+	// flushing serializes flushes, so that writes are applied
+	// in the order they were buffered in.
+	flushing sync.Mutex
+}
+
+// add buffers a write to the key of the bucket, to be applied at the next
+// flush.
+func (b *writeBuffer) add(bucket, key []byte, fn func(tx *bolt.Tx) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.keys == nil {
+		b.keys = make(map[string]map[string]bool)
+	}
+
+	if b.keys[string(bucket)] == nil {
+		b.keys[string(bucket)] = make(map[string]bool)
+	}
+
+	b.keys[string(bucket)][string(key)] = true
+	b.pending = append(b.pending, fn)
+	b.buffered.Add(1)
+}
+
+// holds reports whether one of the pending writes is to a key of the bucket
+// for which match returns true. A nil match matches every key.
+//
+// It is false on a nil buffer, when writes are not buffered.
+func (b *writeBuffer) holds(bucket []byte, match func(key string) bool) bool {
+	if b == nil || b.buffered.Load() == 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key := range b.keys[string(bucket)] {
+		if match == nil || match(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// flush applies the buffered writes to the store, within a single
+// transaction. If one of them fails, they are applied one by one instead,
+// and the errors of the failed ones are kept to be reported by KV.Flush.
+//
+// It is a no-op on a nil buffer, when writes are not buffered.
+func (b *writeBuffer) flush(handle *bolt.DB) {
+	if b == nil || b.buffered.Load() == 0 {
+		return
+	}
+
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.keys = nil
+	b.buffered.Add(-int64(len(pending)))
+	b.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	err := handle.Update(func(tx *bolt.Tx) error {
+		for _, fn := range pending {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err == nil {
+		return
+	}
+
+	var failed []error
+	for _, fn := range pending {
+		if err := handle.Update(fn); err != nil {
+			failed = append(failed, asError(err))
+		}
+	}
+
+	b.mu.Lock()
+	b.failed = append(b.failed, failed...)
+	b.mu.Unlock()
+}
+
+// errors returns the errors of the buffered writes which failed since the
+// last call, and forgets them.
+func (b *writeBuffer) errors() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := errors.Join(b.failed...)
+	b.failed = nil
+
+	return err
+}
+
+// run flushes the buffer every interval, until done is closed.
+func (b *writeBuffer) run(handle *bolt.DB, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			b.flush(handle)
+		}
+	}
+}
+
+// mutateLater runs fn like KV.mutate, unless the store buffers writes, in
+// which case fn is buffered, to be applied at the next flush, and nil is
+// returned right away.
+//
+// Writes in dry-run mode, and writes to undoable keys or keys with bound
+// metrics, are never buffered, as they need to be applied right away.
+func (k *KV) mutateLater(m mutation, fn func(tx *bolt.Tx) error) error {
+	if k.options.DryRun || k.undoable(m.key) || len(k.boundMetrics[string(m.key)]) > 0 {
+		return k.mutate(m, fn)
+	}
+
+	if k.db.readOnly {
+		return NewError(ReadOnlyError, "the store is opened in the "+ModeSharedReadOnly+" mode, and cannot be written to")
+	}
+
+	// Buffer the write under the store's lock, so that a closed store
+	// rejects it, rather than dropping it once closed.
+	buffered := false
+
+	err := k.limit(func() error {
+		if k.db.writes == nil {
+			return nil
+		}
+
+		k.db.writes.add(k.bucket, m.key, fn)
+		k.db.stats.writes.Add(1)
+		buffered = true
+
+		return nil
+	})
+	if err != nil || buffered {
+		return err
+	}
+
+	return k.mutate(m, fn)
+}
+
+// flushFor applies the buffered writes, if one of them is to a key of the
+// KV instance's bucket for which match returns true, so that reading those
+// keys sees them. A nil match matches every key.
+//
+// It must be called with the store's swap lock held, as KV.limit does.
+func (k *KV) flushFor(match func(key string) bool) {
+	if k.db.writes.holds(k.bucket, match) {
+		k.db.writes.flush(k.db.handle)
+	}
+}
+
+// Flush applies the writes buffered when the store is opened with the
+// flushInterval option, rather than waiting for the next periodic flush.
+//
+// It rejects with the errors of the buffered writes which failed since
+// the last call to Flush, if any.
+func (k *KV) Flush() *sobek.Promise {
+	promise, resolve, reject := promises.New(k.vu)
+
+	go func() {
+		err := k.limit(func() error {
+			k.db.writes.flush(k.db.handle)
+
+			return k.db.writes.errors()
+		})
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		resolve(true)
+	}()
+
+	return promise
+}
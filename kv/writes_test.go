@@ -0,0 +1,135 @@
+package kv
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestKVMutateLater(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	put := func(key string) func(tx *bolt.Tx) error {
+		return func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte(DefaultKvBucket)).Put([]byte(key), []byte(`"bar"`))
+		}
+	}
+
+	stored := func(t *testing.T, dbInstance *db, key string) bool {
+		t.Helper()
+
+		var found bool
+		require.NoError(t, dbInstance.handle.View(func(tx *bolt.Tx) error {
+			found = tx.Bucket([]byte(DefaultKvBucket)).Get([]byte(key)) != nil
+			return nil
+		}))
+
+		return found
+	}
+
+	t.Run("buffered writes are applied before reads", func(t *testing.T) {
+		t.Parallel()
+
+		kv := openTestKV(t, Options{FlushInterval: time.Hour})
+		dbInstance := kv.db
+		require.NoError(t, kv.mutateLater(mutation{op: "set", key: []byte("foo")}, put("foo")))
+		assert.False(t, stored(t, dbInstance, "foo"))
+		assert.Equal(t, int64(1), dbInstance.writes.buffered.Load())
+
+		require.NoError(t, kv.view(func(*bolt.Tx) error { return nil }))
+		assert.True(t, stored(t, dbInstance, "foo"))
+		assert.Zero(t, dbInstance.writes.buffered.Load())
+	})
+
+	t.Run("buffered writes are only applied before reads of their keys", func(t *testing.T) {
+		t.Parallel()
+
+		kv := openTestKV(t, Options{FlushInterval: time.Hour})
+		dbInstance := kv.db
+		require.NoError(t, kv.mutateLater(mutation{op: "set", key: []byte("foo")}, put("foo")))
+
+		_, err := kv.lookup([]byte("bar"))
+		require.NoError(t, err)
+		assert.False(t, stored(t, dbInstance, "foo"))
+
+		require.NoError(t, kv.viewKeys("b", func([]string) error { return nil }, func(*bolt.Tx) error { return nil }))
+		assert.False(t, stored(t, dbInstance, "foo"))
+
+		value, err := kv.lookup([]byte("foo"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte(`"bar"`), value)
+		assert.Zero(t, dbInstance.writes.buffered.Load())
+	})
+
+	t.Run("buffered writes are applied before other writes", func(t *testing.T) {
+		t.Parallel()
+
+		kv := openTestKV(t, Options{FlushInterval: time.Hour})
+		dbInstance := kv.db
+		require.NoError(t, kv.mutateLater(mutation{op: "set", key: []byte("foo")}, put("foo")))
+		require.NoError(t, kv.mutate(mutation{op: "set", key: []byte("bar")}, put("bar")))
+		assert.True(t, stored(t, dbInstance, "foo"))
+	})
+
+	t.Run("writes to a closed store are rejected rather than buffered", func(t *testing.T) {
+		t.Parallel()
+
+		dbInstance := newDB()
+		dbInstance.path = filepath.Join(tmpDir, "rejected.db")
+		require.NoError(t, dbInstance.open(Options{FlushInterval: time.Hour}))
+
+		kv := &KV{bucket: []byte(DefaultKvBucket), db: dbInstance}
+		require.NoError(t, dbInstance.close())
+
+		err := kv.mutateLater(mutation{op: "set", key: []byte("foo")}, put("foo"))
+
+		var kvErr *Error
+		require.ErrorAs(t, err, &kvErr)
+		assert.Equal(t, ErrorName(DatabaseNotOpenError), kvErr.Name)
+	})
+
+	t.Run("failed buffered writes are reported without discarding the others", func(t *testing.T) {
+		t.Parallel()
+
+		dbInstance := openTestDB(t, Options{FlushInterval: time.Hour})
+		failure := errors.New("boom")
+		kv := &KV{bucket: []byte(DefaultKvBucket), db: dbInstance}
+		require.NoError(t, kv.mutateLater(mutation{op: "set", key: []byte("foo")}, put("foo")))
+		require.NoError(t, kv.mutateLater(mutation{op: "set", key: []byte("bar")}, func(*bolt.Tx) error {
+			return failure
+		}))
+
+		err := kv.limit(func() error {
+			dbInstance.writes.flush(dbInstance.handle)
+			return dbInstance.writes.errors()
+		})
+		assert.ErrorIs(t, err, failure)
+		assert.True(t, stored(t, dbInstance, "foo"))
+		assert.NoError(t, dbInstance.writes.errors())
+	})
+
+	t.Run("buffered writes are applied when the store is closed", func(t *testing.T) {
+		t.Parallel()
+
+		dbInstance := newDB()
+		dbInstance.path = filepath.Join(tmpDir, "closed.db")
+		require.NoError(t, dbInstance.open(Options{FlushInterval: time.Hour}))
+
+		kv := &KV{bucket: []byte(DefaultKvBucket), db: dbInstance}
+		require.NoError(t, kv.mutateLater(mutation{op: "set", key: []byte("foo")}, put("foo")))
+		require.NoError(t, dbInstance.close())
+
+		require.NoError(t, dbInstance.open(Options{}))
+		t.Cleanup(func() {
+			require.NoError(t, dbInstance.close())
+		})
+		assert.True(t, stored(t, dbInstance, "foo"))
+	})
+}
@@ -6,6 +6,23 @@ import (
 	"go.k6.io/k6/js/modules"
 )
 
+// rootModule is the instance of the module registered with k6.
+var rootModule = kv.New() //nolint:gochecknoglobals
+
 func init() {
-	modules.Register("k6/x/kv", kv.New())
+	modules.Register("k6/x/kv", rootModule)
+}
+
+// OpenStore opens the store the scripts use, so that other extensions
+// compiled into the same k6 binary can read and write the same data.
+// The returned store must be closed once done. See [kv.RootModule.OpenStore].
+func OpenStore(options kv.Options) (*kv.Store, error) {
+	return rootModule.OpenStore(options)
+}
+
+// RegisterSerializer registers a serializer, so that scripts can select it
+// by name with the serialization option of openKv. It is meant to be called
+// from an init function. See [kv.RegisterSerializer].
+func RegisterSerializer(name string, s kv.Serializer) {
+	kv.RegisterSerializer(name, s)
 }